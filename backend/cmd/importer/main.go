@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/supchaser/wb_l0/internal/config"
+	"github.com/supchaser/wb_l0/internal/importer"
+	"github.com/supchaser/wb_l0/internal/kafka/consumer"
+	"github.com/supchaser/wb_l0/internal/kafka/producer"
+	"github.com/supchaser/wb_l0/internal/utils/db"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
+	"go.uber.org/zap"
+)
+
+const (
+	targetKafka    = "kafka"
+	targetPostgres = "postgres"
+
+	importSource = "importer"
+)
+
+type flags struct {
+	input            string
+	format           string
+	target           string
+	topic            string
+	rejects          string
+	batchSize        int
+	progressInterval time.Duration
+	dryRun           bool
+}
+
+func parseFlags() (*flags, error) {
+	f := &flags{}
+	flag.StringVar(&f.input, "input", "", "NDJSON file, JSON file or directory of model.json-style files to import")
+	flag.StringVar(&f.format, "format", string(importer.FormatAuto), "input format: auto, ndjson, json or dir")
+	flag.StringVar(&f.target, "target", targetKafka, "where to import orders: kafka or postgres")
+	flag.StringVar(&f.topic, "topic", "", "topic to publish to with --target=kafka (default: producer topic)")
+	flag.StringVar(&f.rejects, "rejects", "rejects.ndjson", "file to write rejected records to")
+	flag.IntVar(&f.batchSize, "batch-size", 500, "orders per Kafka batch or Postgres transaction")
+	flag.DurationVar(&f.progressInterval, "progress", 5*time.Second, "interval between progress reports")
+	flag.BoolVar(&f.dryRun, "dry-run", false, "validate the input without importing anything")
+	flag.Parse()
+
+	if f.input == "" {
+		return nil, fmt.Errorf("--input is required")
+	}
+	if f.target != targetKafka && f.target != targetPostgres {
+		return nil, fmt.Errorf("unsupported target %q, expected %s or %s", f.target, targetKafka, targetPostgres)
+	}
+
+	return f, nil
+}
+
+func main() {
+	f, err := parseFlags()
+	if err != nil {
+		fmt.Println(err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	format, err := importer.ParseFormat(f.format)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("error initializing config: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = logger.Init(cfg.LogMode)
+	if err != nil {
+		fmt.Printf("failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	if cfg.ValidationRulesFile != "" {
+		rules, err := validate.LoadRulesFile(cfg.ValidationRulesFile)
+		if err != nil {
+			logger.Fatal("failed to load validation rules", zap.Error(err))
+		}
+		validate.UseRules(rules)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	var sink importer.Sink
+	if !f.dryRun {
+		var closeSink func()
+		sink, closeSink, err = createSink(ctx, cfg, f)
+		if err != nil {
+			logger.Fatal("failed to create import target", zap.String("target", f.target), zap.Error(err))
+		}
+		defer closeSink()
+	}
+
+	var rejects io.Writer = io.Discard
+	if f.rejects != "" {
+		file, err := os.Create(f.rejects)
+		if err != nil {
+			logger.Fatal("failed to create rejects file", zap.Error(err))
+		}
+		defer file.Close()
+		rejects = file
+	}
+
+	logger.Info("starting import",
+		zap.String("input", f.input),
+		zap.String("format", string(format)),
+		zap.String("target", f.target),
+		zap.Bool("dry_run", f.dryRun))
+
+	im := importer.CreateImporter(sink, rejects, importer.Options{
+		BatchSize:        f.batchSize,
+		ProgressInterval: f.progressInterval,
+		DryRun:           f.dryRun,
+	})
+
+	stats, err := im.Run(ctx, f.input, format)
+	if err != nil {
+		logger.Error("import failed", zap.Error(err), zap.Any("stats", stats))
+		os.Exit(1)
+	}
+	if stats.Rejected > 0 {
+		logger.Warn("some records were rejected",
+			zap.Int("rejected", stats.Rejected),
+			zap.String("rejects", f.rejects))
+	}
+}
+
+func createSink(ctx context.Context, cfg *config.Config, f *flags) (importer.Sink, func(), error) {
+	if f.target == targetKafka {
+		p, err := producer.CreateProducer(cfg.ProducerConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := p.HealthCheck(ctx); err != nil {
+			p.Close()
+			return nil, nil, err
+		}
+
+		topic := f.topic
+		if topic == "" {
+			topic = cfg.ProducerConfig.Topic
+		}
+
+		return importer.ProducerSink(p, topic), p.Close, nil
+	}
+
+	dbpool, err := db.CreateConnectionPool(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	redisOpts, err := redis.ParseURL(cfg.RedisDSN)
+	if err != nil {
+		dbpool.Close()
+		return nil, nil, err
+	}
+	redisDB := redis.NewClient(redisOpts)
+
+	store, err := consumer.CreateStore(cfg.ConsumerConfig, dbpool, redisDB, importSource)
+	if err != nil {
+		redisDB.Close()
+		dbpool.Close()
+		return nil, nil, err
+	}
+
+	return store, func() {
+		redisDB.Close()
+		dbpool.Close()
+	}, nil
+}
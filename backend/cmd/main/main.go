@@ -12,14 +12,19 @@ import (
 	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/supchaser/wb_l0/internal/app/delivery"
 	"github.com/supchaser/wb_l0/internal/app/repository"
 	"github.com/supchaser/wb_l0/internal/app/usecase"
 	"github.com/supchaser/wb_l0/internal/config"
+	"github.com/supchaser/wb_l0/internal/health"
+	"github.com/supchaser/wb_l0/internal/invoice"
 	"github.com/supchaser/wb_l0/internal/kafka/consumer"
 	"github.com/supchaser/wb_l0/internal/middleware"
 	"github.com/supchaser/wb_l0/internal/utils/db"
 	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/money"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
 	"go.uber.org/zap"
 )
 
@@ -43,6 +48,16 @@ func main() {
 		zap.String("server_port", cfg.ServerPort),
 	)
 
+	if cfg.ValidationRulesFile != "" {
+		rules, err := validate.LoadRulesFile(cfg.ValidationRulesFile)
+		if err != nil {
+			logger.Fatal("failed to load validation rules", zap.Error(err))
+		}
+		validate.UseRules(rules)
+		logger.Info("validation rules loaded",
+			zap.String("path", cfg.ValidationRulesFile))
+	}
+
 	dbpool, err := db.CreateConnectionPool(cfg)
 	if err != nil {
 		logger.Fatal("failed to connect to DB", zap.Error(err))
@@ -62,31 +77,67 @@ func main() {
 		return
 	}
 
-	kafkaConsumer, err := consumer.CreateConsumer(cfg.ConsumerConfig, dbpool)
+	kafkaConsumer, err := consumer.CreateConsumer(cfg.ConsumerConfig, dbpool, redisDB)
 	if err != nil {
 		logger.Fatal("failed to create Kafka consumer", zap.Error(err))
 	}
 
+	appHealth := health.CreateHealth(time.Duration(cfg.ReadinessTimeoutMs) * time.Millisecond)
+	appHealth.Register("postgres", dbpool.Ping)
+	appHealth.Register("redis", func(ctx context.Context) error {
+		return redisDB.Ping(ctx).Err()
+	})
+	appHealth.Register("kafka", kafkaConsumer.HealthCheck)
+	appHealth.Register("consumer_lag", kafkaConsumer.LagCheck)
+
+	appRepo := repository.CreateAppRepository(dbpool, redisDB)
+
+	// The service reports ready once the consumer runs and the recent orders
+	// are cached.
 	go func() {
 		if err := kafkaConsumer.Start(); err != nil {
 			logger.Fatal("failed to start Kafka consumer", zap.Error(err))
 		}
+		warmCache(appRepo, cfg)
+		appHealth.SetServing()
 	}()
-
-	appRepo := repository.CreateAppRepository(dbpool, redisDB)
 	appUsecase := usecase.CreateAppUsecase(appRepo)
+	if cfg.ExchangeRatesFile != "" {
+		rates, err := money.LoadRatesFile(cfg.ExchangeRatesFile)
+		if err != nil {
+			logger.Fatal("failed to load exchange rates", zap.Error(err))
+		}
+		appUsecase.UseRates(rates)
+		logger.Info("exchange rates loaded",
+			zap.String("path", cfg.ExchangeRatesFile),
+			zap.String("base", string(rates.Base())))
+	}
 	appDelivery := delivery.CreateAppDelivery(appUsecase)
+	if cfg.InvoiceFontFile != "" {
+		font, err := invoice.LoadFont(cfg.InvoiceFontFile)
+		if err != nil {
+			logger.Fatal("failed to load invoice font", zap.Error(err))
+		}
+		appDelivery.UseInvoiceFont(font)
+		logger.Info("invoice font loaded", zap.String("path", cfg.InvoiceFontFile))
+	}
 
 	router := mux.NewRouter()
 
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	}).Methods("GET")
+	router.HandleFunc("/livez", appHealth.Livez).Methods("GET")
+	router.HandleFunc("/readyz", appHealth.Readyz).Methods("GET")
+	router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.HandleFunc("/consumer/status", kafkaConsumer.GetStatus).Methods("GET")
 
 	apiRouter := router.PathPrefix("/api/v1").Subrouter()
+	apiRouter.HandleFunc("/schema/order", appDelivery.GetOrderSchema).Methods("GET")
 	orderRouter := apiRouter.PathPrefix("/orders").Subrouter()
+	orderRouter.HandleFunc("/export", appDelivery.ExportOrders).Methods("GET")
 	orderRouter.HandleFunc("/{order_uid}", appDelivery.GetOrderByID).Methods("GET")
+	orderRouter.HandleFunc("/{order_uid}/timeline", appDelivery.GetOrderTimeline).Methods("GET")
+	orderRouter.HandleFunc("/{order_uid}/invoice", appDelivery.GetOrderInvoice).Methods("GET")
 
 	router.Use(middleware.LoggingMiddleware)
 	router.Use(middleware.PanicMiddleware)
@@ -133,6 +184,8 @@ func main() {
 			zap.String("signal", sig.String()),
 		)
 
+		appHealth.SetDraining()
+
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
@@ -148,3 +201,25 @@ func main() {
 		os.Exit(0)
 	}
 }
+
+func warmCache(appRepo *repository.AppRepository, cfg *config.Config) {
+	if cfg.CacheWarmupOrders <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.CacheWarmupTimeoutMs)*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	cached, err := appRepo.WarmCache(ctx, cfg.CacheWarmupOrders)
+	if err != nil {
+		logger.Warn("cache warm-up failed",
+			zap.Int("cached", cached),
+			zap.Error(err))
+		return
+	}
+
+	logger.Info("cache warmed up",
+		zap.Int("cached", cached),
+		zap.Duration("duration", time.Since(start)))
+}
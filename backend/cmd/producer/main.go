@@ -2,20 +2,49 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"math/rand"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/supchaser/wb_l0/internal/app/models"
 	"github.com/supchaser/wb_l0/internal/config"
 	"github.com/supchaser/wb_l0/internal/kafka/producer"
+	"github.com/supchaser/wb_l0/internal/loadgen"
 	"github.com/supchaser/wb_l0/internal/utils/logger"
 	"go.uber.org/zap"
 )
 
+// parseFlags lets every generator setting loaded from the environment be
+// overridden on the command line.
+func parseFlags(cfg *config.GeneratorConfig) {
+	flag.Float64Var(&cfg.Rate, "rate", cfg.Rate, "target orders per second, 0 for as fast as possible")
+	flag.IntVar(&cfg.Count, "count", cfg.Count, "number of orders to produce, 0 to run until stopped")
+	flag.IntVar(&cfg.Burst, "burst", cfg.Burst, "orders released at once, keeping the average rate")
+	flag.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "orders awaiting delivery at most")
+	flag.Int64Var(&cfg.Seed, "seed", cfg.Seed, "random seed, 0 to pick one from the clock")
+	flag.StringVar(&cfg.UIDPrefix, "uid-prefix", cfg.UIDPrefix, "order UID prefix, derived from the seed when empty")
+	flag.IntVar(&cfg.ItemsMin, "items-min", cfg.ItemsMin, "minimum items per order")
+	flag.IntVar(&cfg.ItemsMax, "items-max", cfg.ItemsMax, "maximum items per order")
+	flag.Float64Var(&cfg.FaultRate, "fault-rate", cfg.FaultRate, "fraction of orders replaced by a faulty message, between 0 and 1")
+	faults := flag.String("faults", strings.Join(cfg.Faults, ","), "comma-separated faults to inject, all when empty")
+	flag.Parse()
+
+	cfg.Faults = nil
+	for _, fault := range strings.Split(*faults, ",") {
+		if fault = strings.TrimSpace(fault); fault != "" {
+			cfg.Faults = append(cfg.Faults, fault)
+		}
+	}
+
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+}
+
 func main() {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -23,6 +52,8 @@ func main() {
 		os.Exit(1)
 	}
 
+	parseFlags(cfg.GeneratorConfig)
+
 	err = logger.Init(cfg.LogMode)
 	if err != nil {
 		fmt.Printf("failed to initialize logger: %v\n", err)
@@ -36,6 +67,12 @@ func main() {
 		zap.String("server_port", cfg.ServerPort),
 	)
 
+	generatorCfg := cfg.GeneratorConfig
+	generator, err := loadgen.CreateGenerator(generatorCfg.Seed, generatorCfg.UIDPrefix, generatorCfg.ItemsMin, generatorCfg.ItemsMax)
+	if err != nil {
+		logger.Fatal("failed to create order generator", zap.Error(err))
+	}
+
 	producer, err := producer.CreateProducer(cfg.ProducerConfig)
 	if err != nil {
 		logger.Fatal("failed to create producer", zap.Error(err))
@@ -46,171 +83,52 @@ func main() {
 		logger.Fatal("health check failed", zap.Error(err))
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	go generateOrders(ctx, producer)
-
-	<-sigChan
-	logger.Info("shutting down producer...")
-}
-
-func generateOrders(ctx context.Context, producer *producer.Producer) {
-	ticker := time.NewTicker(60 * time.Second)
-	defer ticker.Stop()
-
-	localRand := rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	for i := 0; ; i++ {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			order := generateTestOrder(i, localRand)
-			if err := producer.Produce(ctx, order, producer.Config.Topic); err != nil {
-				logger.Error("failed to produce order",
-					zap.Error(err),
-					zap.String("order_id", order.OrderUID))
-			} else {
-				logger.Info("order produced successfully",
-					zap.String("order_id", order.OrderUID),
-					zap.String("track_number", order.TrackNumber))
-			}
-		}
-	}
-}
-
-func generateTestOrder(seq int, r *rand.Rand) models.OrderRequest {
-	orderUID := fmt.Sprintf("b563feb7b2b84b6test%d", seq)
-	trackNumber := fmt.Sprintf("WBILMTESTTRACK%d", seq)
-	currentTime := time.Now()
-
-	itemsCount := r.Intn(3) + 1
-	items := make([]models.ItemRequest, itemsCount)
-
-	for i := range itemsCount {
-		items[i] = generateTestItem(trackNumber, i, r)
-	}
-
-	return models.OrderRequest{
-		OrderUID:          orderUID,
-		TrackNumber:       trackNumber,
-		Entry:             "WBIL",
-		Locale:            getRandomLocale(r),
-		InternalSignature: "",
-		CustomerID:        fmt.Sprintf("customer%d", r.Intn(1000)),
-		DeliveryService:   getRandomDeliveryService(r),
-		Shardkey:          fmt.Sprintf("%d", r.Intn(10)),
-		SmID:              r.Intn(100),
-		DateCreated:       currentTime,
-		OofShard:          fmt.Sprintf("%d", r.Intn(2)),
-		Delivery:          generateTestDelivery(r),
-		Payment:           generateTestPayment(orderUID, r),
-		Items:             items,
-	}
-}
-
-func generateTestDelivery(r *rand.Rand) models.DeliveryRequest {
-	firstNames := []string{"John", "Jane", "Alex", "Maria", "David", "Sarah", "Mike", "Anna"}
-	lastNames := []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis"}
-	cities := []string{"Moscow", "Saint Petersburg", "Novosibirsk", "Yekaterinburg", "Kazan"}
-	regions := []string{"Moscow Oblast", "Leningrad Oblast", "Sverdlovsk Oblast", "Republic of Tatarstan"}
-
-	return models.DeliveryRequest{
-		Name:    fmt.Sprintf("%s %s", firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))]),
-		Phone:   fmt.Sprintf("+7%d", 9000000000+r.Int63n(100000000)),
-		Zip:     fmt.Sprintf("%d", 100000+r.Intn(900000)),
-		City:    cities[r.Intn(len(cities))],
-		Address: fmt.Sprintf("%s st., %d", getRandomStreet(r), r.Intn(100)+1),
-		Region:  regions[r.Intn(len(regions))],
-		Email:   fmt.Sprintf("test%d@example.com", r.Intn(1000)),
-	}
-}
-
-func generateTestPayment(orderUID string, r *rand.Rand) models.PaymentRequest {
-	amount := r.Intn(10000) + 1000
-
-	return models.PaymentRequest{
-		Transaction:  orderUID,
-		RequestID:    "",
-		Currency:     getRandomCurrency(r),
-		Provider:     "wbpay",
-		Amount:       amount,
-		PaymentDt:    int(time.Now().Unix()),
-		Bank:         getRandomBank(r),
-		DeliveryCost: r.Intn(500) + 100,
-		GoodsTotal:   amount - 500,
-		CustomFee:    0,
+	produce := func(ctx context.Context, order models.OrderRequest) error {
+		return producer.Produce(ctx, order, producer.Config.Topic)
 	}
-}
 
-func generateTestItem(trackNumber string, index int, r *rand.Rand) models.ItemRequest {
-	products := []struct {
-		name  string
-		brand string
-		price int
-	}{
-		{"Smartphone", "Samsung", 25000},
-		{"Laptop", "Apple", 150000},
-		{"Headphones", "Sony", 5000},
-		{"Watch", "Casio", 3000},
-		{"Camera", "Canon", 45000},
-		{"Tablet", "Huawei", 20000},
-		{"Speaker", "JBL", 7000},
+	var injector *loadgen.FaultInjector
+	if generatorCfg.FaultRate > 0 {
+		faults, err := loadgen.ParseFaults(generatorCfg.Faults)
+		if err != nil {
+			logger.Fatal("failed to parse faults", zap.Error(err))
+		}
+		injector, err = loadgen.CreateFaultInjector(producer, producer.Config.Topic, generatorCfg.FaultRate, faults, generatorCfg.Seed)
+		if err != nil {
+			logger.Fatal("failed to create fault injector", zap.Error(err))
+		}
+		produce = injector.Produce
 	}
 
-	product := products[r.Intn(len(products))]
-
-	return models.ItemRequest{
-		ChrtID:      r.Intn(10000000),
-		TrackNumber: trackNumber,
-		Price:       product.price,
-		Rid:         fmt.Sprintf("ab4219087a764ae0btest%d", index),
-		Name:        product.name,
-		Sale:        r.Intn(30),
-		Size:        fmt.Sprintf("%d", r.Intn(5)),
-		TotalPrice:  product.price - (product.price * r.Intn(30) / 100),
-		NmID:        r.Intn(1000000),
-		Brand:       product.brand,
-		Status:      202,
-	}
-}
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
 
-func getRandomLocale(r *rand.Rand) models.LocaleEnum {
-	locales := []models.LocaleEnum{
-		models.LocaleEN,
-		models.LocaleRU,
-		models.LocaleES,
-		models.LocaleDE,
-		models.LocaleFR,
+	logger.Info("starting order generator",
+		zap.String("topic", producer.Config.Topic),
+		zap.Float64("rate", generatorCfg.Rate),
+		zap.Int("count", generatorCfg.Count),
+		zap.Int("burst", generatorCfg.Burst),
+		zap.Int("concurrency", generatorCfg.Concurrency),
+		zap.Int64("seed", generatorCfg.Seed),
+		zap.Int("items_min", generatorCfg.ItemsMin),
+		zap.Int("items_max", generatorCfg.ItemsMax),
+		zap.Float64("fault_rate", generatorCfg.FaultRate),
+		zap.Strings("faults", generatorCfg.Faults))
+
+	options := loadgen.Options{
+		Rate:        generatorCfg.Rate,
+		Count:       generatorCfg.Count,
+		Burst:       generatorCfg.Burst,
+		Concurrency: generatorCfg.Concurrency,
 	}
-	return locales[r.Intn(len(locales))]
-}
-
-func getRandomCurrency(r *rand.Rand) models.CurrencyEnum {
-	currencies := []models.CurrencyEnum{
-		models.CurrencyUSD,
-		models.CurrencyEUR,
-		models.CurrencyRUB,
-		models.CurrencyGBP,
+	summary := loadgen.Run(ctx, options, generator, produce)
+
+	logger.Info("order generator finished", summary.Fields()...)
+	if injector != nil {
+		faults := injector.Summary()
+		logger.Info("injected faults",
+			zap.Any("injected", faults.Injected),
+			zap.Any("expected_consumer_results", faults.Expected))
 	}
-	return currencies[r.Intn(len(currencies))]
-}
-
-func getRandomDeliveryService(r *rand.Rand) string {
-	services := []string{"meest", "russian-post", "dhl", "fedex", "ups", "cdek"}
-	return services[r.Intn(len(services))]
-}
-
-func getRandomBank(r *rand.Rand) string {
-	banks := []string{"sberbank", "alpha", "tinkoff", "vtb", "gazprombank", "raiffeisen"}
-	return banks[r.Intn(len(banks))]
-}
-
-func getRandomStreet(r *rand.Rand) string {
-	streets := []string{"Lenin", "Pushkin", "Gorky", "Peace", "Victory", "Central", "Green", "Sunset"}
-	return streets[r.Intn(len(streets))]
+	logger.Info("shutting down producer...")
 }
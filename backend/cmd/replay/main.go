@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/app/repository"
+	"github.com/supchaser/wb_l0/internal/config"
+	"github.com/supchaser/wb_l0/internal/kafka/producer"
+	"github.com/supchaser/wb_l0/internal/replay"
+	"github.com/supchaser/wb_l0/internal/utils/db"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
+	"go.uber.org/zap"
+)
+
+type flags struct {
+	input            string
+	from             string
+	to               string
+	uids             string
+	all              bool
+	limit            int
+	topic            string
+	replayID         string
+	rate             float64
+	checkpoint       string
+	checkpointEvery  int
+	progressInterval time.Duration
+}
+
+func parseFlags() (*flags, error) {
+	f := &flags{}
+	flag.StringVar(&f.input, "input", "", "NDJSON file of orders to replay instead of reading them from Postgres")
+	flag.StringVar(&f.from, "from", "", "replay orders created at or after this RFC 3339 timestamp or YYYY-MM-DD date")
+	flag.StringVar(&f.to, "to", "", "replay orders created before this RFC 3339 timestamp or YYYY-MM-DD date")
+	flag.StringVar(&f.uids, "uids", "", "comma-separated order UIDs to replay")
+	flag.BoolVar(&f.all, "all", false, "replay every stored order")
+	flag.IntVar(&f.limit, "limit", 0, "replay at most this many stored orders, 0 for no limit")
+	flag.StringVar(&f.topic, "topic", "", "topic to republish to (default: producer topic)")
+	flag.StringVar(&f.replayID, "replay-id", "", "value of the replay header (default: start time); must match a resumed replay's")
+	flag.Float64Var(&f.rate, "rate", 0, "orders per second, 0 for as fast as they are delivered")
+	flag.StringVar(&f.checkpoint, "checkpoint", "", "file recording progress, to resume an interrupted replay; removed once it completes")
+	flag.IntVar(&f.checkpointEvery, "checkpoint-every", 100, "orders replayed between checkpoint saves")
+	flag.DurationVar(&f.progressInterval, "progress", 5*time.Second, "interval between progress reports")
+	flag.Parse()
+
+	selects := f.from != "" || f.to != "" || f.uids != "" || f.all
+	if f.input != "" && (selects || f.limit != 0) {
+		return nil, fmt.Errorf("--input cannot be combined with --from, --to, --uids, --all or --limit")
+	}
+	if f.input == "" && !selects {
+		return nil, fmt.Errorf("select orders with --input, --from, --to or --uids, or pass --all")
+	}
+
+	return f, nil
+}
+
+func (f *flags) filter() (models.OrderFilter, error) {
+	filter := models.OrderFilter{Limit: f.limit}
+
+	var err error
+	if filter.CreatedFrom, err = parseTime("from", f.from); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseTime("to", f.to); err != nil {
+		return filter, err
+	}
+	for _, uid := range strings.Split(f.uids, ",") {
+		if uid = strings.TrimSpace(uid); uid != "" {
+			filter.OrderUIDs = append(filter.OrderUIDs, uid)
+		}
+	}
+
+	return filter, validate.ValidateOrderFilter(&filter)
+}
+
+// selection describes the orders the flags select, recorded in the checkpoint.
+func (f *flags) selection() string {
+	if f.input != "" {
+		return "input=" + f.input
+	}
+
+	return fmt.Sprintf("from=%s to=%s uids=%s all=%t limit=%d", f.from, f.to, f.uids, f.all, f.limit)
+}
+
+func parseTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("--%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", name)
+}
+
+func main() {
+	f, err := parseFlags()
+	if err != nil {
+		fmt.Println(err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("error initializing config: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = logger.Init(cfg.LogMode)
+	if err != nil {
+		fmt.Printf("failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	var source replay.Source
+	if f.input != "" {
+		source = replay.FileSource(f.input)
+	} else {
+		filter, err := f.filter()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+
+		dbpool, err := db.CreateConnectionPool(cfg)
+		if err != nil {
+			logger.Fatal("failed to connect to postgres", zap.Error(err))
+		}
+		defer dbpool.Close()
+
+		source = replay.PostgresSource(repository.CreateAppRepository(dbpool, nil), filter)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	p, err := producer.CreateProducer(cfg.ProducerConfig)
+	if err != nil {
+		logger.Fatal("failed to create producer", zap.Error(err))
+	}
+	defer p.Close()
+
+	if err := p.HealthCheck(ctx); err != nil {
+		logger.Fatal("health check failed", zap.Error(err))
+	}
+
+	topic := f.topic
+	if topic == "" {
+		topic = cfg.ProducerConfig.Topic
+	}
+
+	replayer := replay.CreateReplayer(p, replay.Options{
+		Topic:            topic,
+		ReplayID:         f.replayID,
+		Selection:        f.selection(),
+		Rate:             f.rate,
+		Checkpoint:       f.checkpoint,
+		CheckpointEvery:  f.checkpointEvery,
+		ProgressInterval: f.progressInterval,
+	})
+
+	stats, err := replayer.Run(ctx, source)
+	if err != nil {
+		logger.Error("replay failed",
+			zap.Error(err),
+			zap.Any("stats", stats),
+			zap.String("checkpoint", f.checkpoint))
+		os.Exit(1)
+	}
+}
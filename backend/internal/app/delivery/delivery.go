@@ -1,22 +1,32 @@
 package delivery
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/supchaser/wb_l0/internal/app"
 	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/export"
+	"github.com/supchaser/wb_l0/internal/i18n"
+	"github.com/supchaser/wb_l0/internal/invoice"
 	"github.com/supchaser/wb_l0/internal/utils/errs"
 	"github.com/supchaser/wb_l0/internal/utils/logger"
 	"github.com/supchaser/wb_l0/internal/utils/responses"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
 	"go.uber.org/zap"
 )
 
 type AppDelivery struct {
 	orderUsecase app.AppUsecase
+	invoiceFont  *invoice.Font
 }
 
 func CreateAppDelivery(orderUsecase app.AppUsecase) *AppDelivery {
@@ -51,6 +61,10 @@ func (d *AppDelivery) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 			responses.DoBadResponseAndLog(w, http.StatusNotFound, "order not found")
 			return
 		}
+		if errors.Is(err, errs.ErrValidation) {
+			responses.DoValidationErrorResponse(w, err, "invalid order_uid")
+			return
+		}
 
 		logger.Error("failed to get order",
 			zap.String("function", funcName),
@@ -62,6 +76,39 @@ func (d *AppDelivery) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 
 	orderResponse := d.convertToResponse(order)
 
+	if currency := r.URL.Query().Get("currency"); currency != "" {
+		totals, err := d.orderUsecase.ConvertOrderTotals(order, currency)
+		if err != nil {
+			if errors.Is(err, errs.ErrUnsupportedCurrency) {
+				responses.DoBadResponseAndLog(w, http.StatusBadRequest, "unsupported currency")
+				return
+			}
+
+			logger.Error("failed to convert order totals",
+				zap.String("function", funcName),
+				zap.String("order_uid", orderUID),
+				zap.String("currency", currency),
+				zap.Error(err))
+			responses.DoBadResponseAndLog(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if totals != nil {
+			orderResponse["converted"] = totals
+		}
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+	queryLocale := r.URL.Query().Get("locale")
+	acceptLanguage := r.Header.Get("Accept-Language")
+	if queryLocale != "" || acceptLanguage != "" {
+		locale, err := i18n.Negotiate(queryLocale, acceptLanguage, order.Locale)
+		if err != nil {
+			responses.DoBadResponseAndLog(w, http.StatusBadRequest, "unsupported locale")
+			return
+		}
+		orderResponse["presentation"] = d.convertToPresentation(order, i18n.For(locale))
+	}
+
 	responses.DoJSONResponse(w, orderResponse, http.StatusOK)
 
 	logger.Info("order retrieved successfully",
@@ -69,6 +116,281 @@ func (d *AppDelivery) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 		zap.String("order_uid", orderUID))
 }
 
+// UseInvoiceFont embeds font into PDF invoices instead of the built-in
+// Latin-only Helvetica.
+func (d *AppDelivery) UseInvoiceFont(font *invoice.Font) {
+	d.invoiceFont = font
+}
+
+func (d *AppDelivery) GetOrderInvoice(w http.ResponseWriter, r *http.Request) {
+	const funcName = "AppDelivery.GetOrderInvoice"
+
+	logger.Info("handling get order invoice request",
+		zap.String("function", funcName),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	vars := mux.Vars(r)
+	orderUID := vars["order_uid"]
+
+	if orderUID == "" {
+		responses.DoBadResponseAndLog(w, http.StatusBadRequest, "order_uid is required")
+		return
+	}
+
+	query := r.URL.Query()
+	kind, err := invoice.ParseKind(query.Get("type"))
+	if err != nil {
+		responses.DoBadResponseAndLog(w, http.StatusBadRequest, "unsupported document type")
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "application/pdf") {
+		format = "pdf"
+	}
+	if format != "" && format != "html" && format != "pdf" {
+		responses.DoBadResponseAndLog(w, http.StatusBadRequest, "unsupported format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	order, err := d.orderUsecase.GetOrderByID(ctx, orderUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+			responses.DoBadResponseAndLog(w, http.StatusNotFound, "order not found")
+		case errors.Is(err, errs.ErrValidation):
+			responses.DoValidationErrorResponse(w, err, "invalid order_uid")
+		default:
+			logger.Error("failed to get order",
+				zap.String("function", funcName),
+				zap.String("order_uid", orderUID),
+				zap.Error(err))
+			responses.DoBadResponseAndLog(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+
+	locale, err := i18n.Negotiate(query.Get("locale"), "", order.Locale)
+	if err != nil {
+		responses.DoBadResponseAndLog(w, http.StatusBadRequest, "unsupported locale")
+		return
+	}
+	doc := invoice.Build(order, kind, i18n.For(locale))
+
+	var body bytes.Buffer
+	contentType := "text/html; charset=utf-8"
+	if format == "pdf" {
+		contentType = "application/pdf"
+		err = invoice.RenderPDF(&body, doc, d.invoiceFont)
+	} else {
+		err = invoice.RenderHTML(&body, doc)
+	}
+	if errors.Is(err, errs.ErrUnsupportedScript) {
+		logger.Warn("invoice font cannot render order",
+			zap.String("function", funcName),
+			zap.String("order_uid", orderUID),
+			zap.String("locale", string(locale)),
+			zap.Error(err))
+		responses.DoBadResponseAndLog(w, http.StatusNotAcceptable, "invoice font cannot render this order as pdf, request format=html")
+		return
+	}
+	if err != nil {
+		logger.Error("failed to render invoice",
+			zap.String("function", funcName),
+			zap.String("order_uid", orderUID),
+			zap.String("format", format),
+			zap.Error(err))
+		responses.DoBadResponseAndLog(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if format == "pdf" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", fmt.Sprintf("%s-%s.pdf", kind, orderUID)))
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body.Bytes())
+
+	logger.Info("order invoice rendered",
+		zap.String("function", funcName),
+		zap.String("order_uid", orderUID),
+		zap.String("type", string(kind)),
+		zap.String("locale", string(locale)))
+}
+
+// ExportOrders streams the orders matching the listing filters as CSV (one
+// row per item), NDJSON or Parquet.
+func (d *AppDelivery) ExportOrders(w http.ResponseWriter, r *http.Request) {
+	const funcName = "AppDelivery.ExportOrders"
+
+	logger.Info("handling export orders request",
+		zap.String("function", funcName),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	query := r.URL.Query()
+	format, err := export.ParseFormat(query.Get("format"))
+	if err != nil {
+		responses.DoBadResponseAndLog(w, http.StatusBadRequest, "unsupported export format")
+		return
+	}
+
+	filter, err := parseOrderFilter(query)
+	if err != nil {
+		responses.DoValidationErrorResponse(w, err, "invalid filter")
+		return
+	}
+
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "orders."+string(format)))
+		w.WriteHeader(http.StatusOK)
+	}
+
+	writer := export.NewWriter(format, w)
+	err = d.orderUsecase.ExportOrders(r.Context(), filter, func(order *models.Order) error {
+		start()
+		return writer.Write(order)
+	})
+	if err == nil {
+		start()
+		err = writer.Close()
+	}
+
+	if err != nil {
+		if !started {
+			if errors.Is(err, errs.ErrValidation) {
+				responses.DoValidationErrorResponse(w, err, "invalid filter")
+				return
+			}
+			logger.Error("failed to export orders",
+				zap.String("function", funcName),
+				zap.Error(err))
+			responses.DoBadResponseAndLog(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		// The status line is gone; abort so the client sees a truncated
+		// transfer instead of a complete-looking file.
+		logger.Error("export interrupted",
+			zap.String("function", funcName),
+			zap.Error(err))
+		panic(http.ErrAbortHandler)
+	}
+
+	logger.Info("orders exported",
+		zap.String("function", funcName),
+		zap.String("format", string(format)))
+}
+
+func parseOrderFilter(query url.Values) (models.OrderFilter, error) {
+	var v validate.ValidationErrors
+	filter := models.OrderFilter{
+		CustomerID:      query.Get("customer_id"),
+		Locale:          models.LocaleEnum(query.Get("locale")),
+		DeliveryService: query.Get("delivery_service"),
+		Currency:        models.CurrencyEnum(strings.ToUpper(query.Get("currency"))),
+	}
+
+	parseTime := func(key string) time.Time {
+		value := query.Get(key)
+		if value == "" {
+			return time.Time{}
+		}
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t
+			}
+		}
+		v = append(v, validate.FieldError{
+			Path:    key,
+			Code:    validate.CodeFormat,
+			Message: fmt.Sprintf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", key),
+		})
+		return time.Time{}
+	}
+	filter.CreatedFrom = parseTime("created_from")
+	filter.CreatedTo = parseTime("created_to")
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			v = append(v, validate.FieldError{Path: "limit", Code: validate.CodeType, Message: "limit must be an integer"})
+		}
+		filter.Limit = limit
+	}
+
+	if len(v) > 0 {
+		return models.OrderFilter{}, v
+	}
+
+	return filter, nil
+}
+
+func (d *AppDelivery) GetOrderTimeline(w http.ResponseWriter, r *http.Request) {
+	const funcName = "AppDelivery.GetOrderTimeline"
+
+	logger.Info("handling get order timeline request",
+		zap.String("function", funcName),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	vars := mux.Vars(r)
+	orderUID := vars["order_uid"]
+
+	if orderUID == "" {
+		responses.DoBadResponseAndLog(w, http.StatusBadRequest, "order_uid is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	timeline, err := d.orderUsecase.GetOrderTimeline(ctx, orderUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+			responses.DoBadResponseAndLog(w, http.StatusNotFound, "order not found")
+		case errors.Is(err, errs.ErrValidation):
+			responses.DoValidationErrorResponse(w, err, "invalid order_uid")
+		default:
+			logger.Error("failed to get order timeline",
+				zap.String("function", funcName),
+				zap.String("order_uid", orderUID),
+				zap.Error(err))
+			responses.DoBadResponseAndLog(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+
+	responses.DoJSONResponse(w, map[string]any{
+		"order_uid": orderUID,
+		"timeline":  timeline,
+	}, http.StatusOK)
+}
+
+func (d *AppDelivery) GetOrderSchema(w http.ResponseWriter, r *http.Request) {
+	logger.Info("handling get order schema request",
+		zap.String("function", "AppDelivery.GetOrderSchema"),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	responses.DoJSONResponse(w, validate.OrderSchema(), http.StatusOK)
+}
+
 func (d *AppDelivery) convertToResponse(order *models.Order) map[string]any {
 	return map[string]any{
 		"order_uid":          order.OrderUID,
@@ -147,3 +469,44 @@ func (d *AppDelivery) convertItemsToResponse(items []models.Item) []map[string]a
 
 	return result
 }
+
+// convertToPresentation renders the human-facing fields of order for one
+// locale. It is added next to the raw fields, never instead of them.
+func (d *AppDelivery) convertToPresentation(order *models.Order, catalog *i18n.Catalog) map[string]any {
+	presentation := map[string]any{
+		"locale":           catalog.Locale,
+		"date_created":     catalog.FormatDate(order.DateCreated),
+		"delivery_service": catalog.DeliveryService(order.DeliveryService),
+	}
+
+	var currency models.CurrencyEnum
+	if order.Payment != nil {
+		currency = order.Payment.Currency
+	}
+	formatMoney := func(amount int) string {
+		return catalog.FormatAmount(int64(amount), currency)
+	}
+
+	if payment := order.Payment; payment != nil {
+		presentation["payment"] = map[string]any{
+			"amount":        formatMoney(payment.Amount),
+			"payment_dt":    catalog.FormatDate(time.Unix(int64(payment.PaymentDt), 0)),
+			"delivery_cost": formatMoney(payment.DeliveryCost),
+			"goods_total":   formatMoney(payment.GoodsTotal),
+			"custom_fee":    formatMoney(payment.CustomFee),
+		}
+	}
+
+	items := make([]map[string]any, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, map[string]any{
+			"chrt_id":     item.ChrtID,
+			"price":       formatMoney(item.Price),
+			"total_price": formatMoney(item.TotalPrice),
+			"status":      catalog.ItemStatus(item.Status),
+		})
+	}
+	presentation["items"] = items
+
+	return presentation
+}
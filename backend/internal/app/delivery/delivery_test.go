@@ -1,9 +1,12 @@
 package delivery
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,6 +17,7 @@ import (
 	"github.com/supchaser/wb_l0/internal/app/models"
 	"github.com/supchaser/wb_l0/internal/utils/errs"
 	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
 )
 
 func TestMain(m *testing.M) {
@@ -82,6 +86,8 @@ func TestAppDelivery_GetOrderByID(t *testing.T) {
 	tests := []struct {
 		name           string
 		orderUID       string
+		query          string
+		acceptLanguage string
 		mockSetup      func()
 		expectedStatus int
 		validateFunc   func(t *testing.T, body []byte)
@@ -102,6 +108,7 @@ func TestAppDelivery_GetOrderByID(t *testing.T) {
 				assert.Equal(t, "test123", response["order_uid"])
 				assert.Equal(t, "WBILMTESTTRACK", response["track_number"])
 				assert.Equal(t, testTime.Format(time.RFC3339), response["date_created"])
+				assert.NotContains(t, response, "presentation")
 
 				delivery := response["delivery"].(map[string]any)
 				assert.Equal(t, "Test Testov", delivery["name"])
@@ -135,6 +142,27 @@ func TestAppDelivery_GetOrderByID(t *testing.T) {
 				assert.Equal(t, "order not found", response["text"])
 			},
 		},
+		{
+			name:     "InvalidOrderUID",
+			orderUID: "bad@uid",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					GetOrderByID(gomock.Any(), "bad@uid").
+					Return(nil, validate.ValidateOrderUID("bad@uid"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			validateFunc: func(t *testing.T, body []byte) {
+				var response map[string]any
+				err := json.Unmarshal(body, &response)
+				assert.NoError(t, err)
+				assert.Equal(t, "invalid order_uid", response["text"])
+				assert.Equal(t, []any{map[string]any{
+					"path":    "order_uid",
+					"code":    validate.CodePattern,
+					"message": "order_uid contains invalid characters",
+				}}, response["errors"])
+			},
+		},
 		{
 			name:     "InternalServerError",
 			orderUID: "test123",
@@ -152,6 +180,115 @@ func TestAppDelivery_GetOrderByID(t *testing.T) {
 				assert.Equal(t, "internal server error", response["text"])
 			},
 		},
+		{
+			name:     "ConvertedCurrency",
+			orderUID: "test123",
+			query:    "?currency=eur",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					GetOrderByID(gomock.Any(), "test123").
+					Return(testOrder, nil)
+				mockUsecase.EXPECT().
+					ConvertOrderTotals(testOrder, "eur").
+					Return(&models.ConvertedTotals{
+						Currency: models.CurrencyEUR,
+						Rate:     "0.800000",
+						Amount:   1454,
+						Items:    []models.ConvertedItemTotals{{ChrtID: 9934930, Price: 362, TotalPrice: 254}},
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			validateFunc: func(t *testing.T, body []byte) {
+				var response map[string]any
+				err := json.Unmarshal(body, &response)
+				assert.NoError(t, err)
+				converted := response["converted"].(map[string]any)
+				assert.Equal(t, "EUR", converted["currency"])
+				assert.Equal(t, float64(1454), converted["amount"])
+				assert.Equal(t, "USD", response["payment"].(map[string]any)["currency"])
+			},
+		},
+		{
+			name:     "UnsupportedCurrency",
+			orderUID: "test123",
+			query:    "?currency=BTC",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					GetOrderByID(gomock.Any(), "test123").
+					Return(testOrder, nil)
+				mockUsecase.EXPECT().
+					ConvertOrderTotals(testOrder, "BTC").
+					Return(nil, errs.ErrUnsupportedCurrency)
+			},
+			expectedStatus: http.StatusBadRequest,
+			validateFunc: func(t *testing.T, body []byte) {
+				var response map[string]any
+				err := json.Unmarshal(body, &response)
+				assert.NoError(t, err)
+				assert.Equal(t, "unsupported currency", response["text"])
+			},
+		},
+		{
+			name:     "PresentationByQueryLocale",
+			orderUID: "test123",
+			query:    "?locale=ru",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					GetOrderByID(gomock.Any(), "test123").
+					Return(testOrder, nil)
+			},
+			expectedStatus: http.StatusOK,
+			validateFunc: func(t *testing.T, body []byte) {
+				var response map[string]any
+				err := json.Unmarshal(body, &response)
+				assert.NoError(t, err)
+				presentation := response["presentation"].(map[string]any)
+				assert.Equal(t, "ru", presentation["locale"])
+				assert.Equal(t, testTime.UTC().Format("02.01.2006 15:04"), presentation["date_created"])
+				assert.Equal(t, "18,17 $", presentation["payment"].(map[string]any)["amount"])
+				item := presentation["items"].([]any)[0].(map[string]any)
+				assert.Equal(t, "Принят", item["status"])
+				assert.Equal(t, float64(1817), response["payment"].(map[string]any)["amount"])
+				assert.Equal(t, float64(202), response["items"].([]any)[0].(map[string]any)["status"])
+			},
+		},
+		{
+			name:           "PresentationByAcceptLanguage",
+			orderUID:       "test123",
+			acceptLanguage: "pt-BR, de-CH;q=0.9, en;q=0.5",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					GetOrderByID(gomock.Any(), "test123").
+					Return(testOrder, nil)
+			},
+			expectedStatus: http.StatusOK,
+			validateFunc: func(t *testing.T, body []byte) {
+				var response map[string]any
+				err := json.Unmarshal(body, &response)
+				assert.NoError(t, err)
+				presentation := response["presentation"].(map[string]any)
+				assert.Equal(t, "de", presentation["locale"])
+				assert.Equal(t, "Meest", presentation["delivery_service"])
+				assert.Equal(t, "15,00 $", presentation["payment"].(map[string]any)["delivery_cost"])
+			},
+		},
+		{
+			name:     "UnsupportedLocale",
+			orderUID: "test123",
+			query:    "?locale=pt",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					GetOrderByID(gomock.Any(), "test123").
+					Return(testOrder, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			validateFunc: func(t *testing.T, body []byte) {
+				var response map[string]any
+				err := json.Unmarshal(body, &response)
+				assert.NoError(t, err)
+				assert.Equal(t, "unsupported locale", response["text"])
+			},
+		},
 		{
 			name:     "MissingOrderUID",
 			orderUID: "",
@@ -172,7 +309,10 @@ func TestAppDelivery_GetOrderByID(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockSetup()
 
-			req := httptest.NewRequest("GET", "/orders/"+tt.orderUID, nil)
+			req := httptest.NewRequest("GET", "/orders/"+tt.orderUID+tt.query, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
 			if tt.orderUID != "" {
 				req = mux.SetURLVars(req, map[string]string{"order_uid": tt.orderUID})
 			}
@@ -187,6 +327,437 @@ func TestAppDelivery_GetOrderByID(t *testing.T) {
 	}
 }
 
+func TestAppDelivery_GetOrderInvoice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsecase := mock_app.NewMockAppUsecase(ctrl)
+	appDelivery := CreateAppDelivery(mockUsecase)
+
+	testOrder := &models.Order{
+		OrderUID:        "test123",
+		TrackNumber:     "WBILMTESTTRACK",
+		Locale:          models.LocaleDE,
+		DeliveryService: "dhl",
+		DateCreated:     time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		Delivery:        &models.Delivery{Name: "Test Testov", City: "Kiryat Mozkin"},
+		Payment:         &models.Payment{Currency: "USD", Amount: 1817, GoodsTotal: 317, DeliveryCost: 1500},
+		Items:           []models.Item{{ChrtID: 9934930, Name: "Mascaras", Price: 453, Sale: 30, TotalPrice: 317, Status: 202}},
+	}
+
+	tests := []struct {
+		name                string
+		query               string
+		accept              string
+		mockSetup           func()
+		expectedStatus      int
+		expectedContentType string
+		contains            []string
+		notContain          []string
+	}{
+		{
+			name: "HTMLInOrderLocale",
+			mockSetup: func() {
+				mockUsecase.EXPECT().GetOrderByID(gomock.Any(), "test123").Return(testOrder, nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/html; charset=utf-8",
+			contains:            []string{"Rechnung", "18,17 $", "30%", "Angenommen"},
+		},
+		{
+			name:  "PackingSlipWithLocaleOverride",
+			query: "?type=packing-slip&locale=en",
+			mockSetup: func() {
+				mockUsecase.EXPECT().GetOrderByID(gomock.Any(), "test123").Return(testOrder, nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/html; charset=utf-8",
+			contains:            []string{"Packing slip", "Mascaras"},
+			notContain:          []string{"$"},
+		},
+		{
+			name:  "PDFByQuery",
+			query: "?format=pdf",
+			mockSetup: func() {
+				mockUsecase.EXPECT().GetOrderByID(gomock.Any(), "test123").Return(testOrder, nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/pdf",
+			contains:            []string{"%PDF-1.7", "(Rechnung)", "%%EOF"},
+		},
+		{
+			name:   "PDFByAccept",
+			accept: "application/pdf",
+			mockSetup: func() {
+				mockUsecase.EXPECT().GetOrderByID(gomock.Any(), "test123").Return(testOrder, nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/pdf",
+			contains:            []string{"%PDF-1.7"},
+		},
+		{
+			name:  "PDFInLocaleFontCannotRender",
+			query: "?format=pdf&locale=ru",
+			mockSetup: func() {
+				mockUsecase.EXPECT().GetOrderByID(gomock.Any(), "test123").Return(testOrder, nil)
+			},
+			expectedStatus: http.StatusNotAcceptable,
+			contains:       []string{"invoice font cannot render this order as pdf"},
+		},
+		{
+			name:  "HTMLInLocaleFontCannotRender",
+			query: "?locale=ru",
+			mockSetup: func() {
+				mockUsecase.EXPECT().GetOrderByID(gomock.Any(), "test123").Return(testOrder, nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/html; charset=utf-8",
+			contains:            []string{"Счёт"},
+		},
+		{
+			name:           "UnsupportedType",
+			query:          "?type=receipt",
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			contains:       []string{"unsupported document type"},
+		},
+		{
+			name:           "UnsupportedFormat",
+			query:          "?format=docx",
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			contains:       []string{"unsupported format"},
+		},
+		{
+			name:  "UnsupportedLocale",
+			query: "?locale=pt",
+			mockSetup: func() {
+				mockUsecase.EXPECT().GetOrderByID(gomock.Any(), "test123").Return(testOrder, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			contains:       []string{"unsupported locale"},
+		},
+		{
+			name: "NotFound",
+			mockSetup: func() {
+				mockUsecase.EXPECT().GetOrderByID(gomock.Any(), "test123").Return(nil, errs.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			contains:       []string{"order not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := httptest.NewRequest(http.MethodGet, "/orders/test123/invoice"+tt.query, nil)
+			req = mux.SetURLVars(req, map[string]string{"order_uid": "test123"})
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			w := httptest.NewRecorder()
+
+			appDelivery.GetOrderInvoice(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedContentType != "" {
+				assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			}
+			for _, s := range tt.contains {
+				assert.Contains(t, w.Body.String(), s)
+			}
+			for _, s := range tt.notContain {
+				assert.NotContains(t, w.Body.String(), s)
+			}
+		})
+	}
+}
+
+func TestAppDelivery_ExportOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsecase := mock_app.NewMockAppUsecase(ctrl)
+	appDelivery := CreateAppDelivery(mockUsecase)
+
+	orders := []*models.Order{
+		{OrderUID: "order1", Items: []models.Item{{ChrtID: 1}, {ChrtID: 2}}},
+		{OrderUID: "order2"},
+	}
+	streamOrders := func(_ context.Context, _ models.OrderFilter, fn func(*models.Order) error) error {
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name                string
+		query               string
+		mockSetup           func()
+		expectedStatus      int
+		expectedContentType string
+		validateFunc        func(t *testing.T, body []byte)
+	}{
+		{
+			name:  "CSVWithFilters",
+			query: "?created_from=2024-01-01&created_to=2024-02-01T00:00:00Z&customer_id=test&currency=usd&limit=5",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					ExportOrders(gomock.Any(), models.OrderFilter{
+						CreatedFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+						CreatedTo:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+						CustomerID:  "test",
+						Currency:    models.CurrencyUSD,
+						Limit:       5,
+					}, gomock.Any()).
+					DoAndReturn(streamOrders)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			validateFunc: func(t *testing.T, body []byte) {
+				lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+				assert.Len(t, lines, 4)
+				assert.True(t, strings.HasPrefix(lines[0], "order_uid,"))
+				assert.True(t, strings.HasPrefix(lines[3], "order2,"))
+			},
+		},
+		{
+			name:  "NDJSON",
+			query: "?format=ndjson",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					ExportOrders(gomock.Any(), models.OrderFilter{}, gomock.Any()).
+					DoAndReturn(streamOrders)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			validateFunc: func(t *testing.T, body []byte) {
+				lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+				assert.Len(t, lines, 2)
+				var order models.Order
+				assert.NoError(t, json.Unmarshal([]byte(lines[0]), &order))
+				assert.Equal(t, "order1", order.OrderUID)
+				assert.Len(t, order.Items, 2)
+			},
+		},
+		{
+			name:  "EmptyParquet",
+			query: "?format=parquet",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					ExportOrders(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/vnd.apache.parquet",
+			validateFunc: func(t *testing.T, body []byte) {
+				assert.True(t, strings.HasPrefix(string(body), "PAR1"))
+				assert.True(t, strings.HasSuffix(string(body), "PAR1"))
+			},
+		},
+		{
+			name:           "UnsupportedFormat",
+			query:          "?format=xlsx",
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			validateFunc: func(t *testing.T, body []byte) {
+				assert.Contains(t, string(body), "unsupported export format")
+			},
+		},
+		{
+			name:           "MalformedFilter",
+			query:          "?created_from=yesterday&limit=ten",
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			validateFunc: func(t *testing.T, body []byte) {
+				var response map[string]any
+				assert.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, "invalid filter", response["text"])
+				assert.Len(t, response["errors"], 2)
+			},
+		},
+		{
+			name:  "InvalidFilter",
+			query: "?locale=pt",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					ExportOrders(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(validate.ValidationErrors{{Path: "locale", Code: validate.CodeEnum, Message: "unsupported locale: pt"}})
+			},
+			expectedStatus: http.StatusBadRequest,
+			validateFunc: func(t *testing.T, body []byte) {
+				assert.Contains(t, string(body), "invalid filter")
+			},
+		},
+		{
+			name: "FailsBeforeFirstOrder",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					ExportOrders(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(errors.New("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			validateFunc: func(t *testing.T, body []byte) {
+				assert.Contains(t, string(body), "internal server error")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/export"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			appDelivery.ExportOrders(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedContentType != "" {
+				assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			}
+			tt.validateFunc(t, w.Body.Bytes())
+		})
+	}
+}
+
+func TestAppDelivery_GetOrderTimeline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsecase := mock_app.NewMockAppUsecase(ctrl)
+	appDelivery := CreateAppDelivery(mockUsecase)
+
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	chrtID, previousStatus, status := 9934930, 202, 300
+	timeline := []models.TimelineEntry{
+		{Type: models.EventOrderCreated, OccurredAt: created},
+		{
+			Type:           models.EventItemStatusChanged,
+			OccurredAt:     created.Add(time.Hour),
+			ChrtID:         &chrtID,
+			PreviousStatus: &previousStatus,
+			Status:         &status,
+		},
+	}
+
+	tests := []struct {
+		name           string
+		orderUID       string
+		mockSetup      func()
+		expectedStatus int
+		validateFunc   func(t *testing.T, body []byte)
+	}{
+		{
+			name:     "Success",
+			orderUID: "test123",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					GetOrderTimeline(gomock.Any(), "test123").
+					Return(timeline, nil)
+			},
+			expectedStatus: http.StatusOK,
+			validateFunc: func(t *testing.T, body []byte) {
+				var response struct {
+					OrderUID string                 `json:"order_uid"`
+					Timeline []models.TimelineEntry `json:"timeline"`
+				}
+				err := json.Unmarshal(body, &response)
+				assert.NoError(t, err)
+				assert.Equal(t, "test123", response.OrderUID)
+				assert.Equal(t, timeline, response.Timeline)
+			},
+		},
+		{
+			name:     "OrderNotFound",
+			orderUID: "nonexistent",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					GetOrderTimeline(gomock.Any(), "nonexistent").
+					Return(nil, errs.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			validateFunc: func(t *testing.T, body []byte) {
+				var response map[string]any
+				err := json.Unmarshal(body, &response)
+				assert.NoError(t, err)
+				assert.Equal(t, "order not found", response["text"])
+			},
+		},
+		{
+			name:     "InvalidOrderUID",
+			orderUID: "bad@uid",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					GetOrderTimeline(gomock.Any(), "bad@uid").
+					Return(nil, errs.ErrValidation)
+			},
+			expectedStatus: http.StatusBadRequest,
+			validateFunc: func(t *testing.T, body []byte) {
+				var response map[string]any
+				err := json.Unmarshal(body, &response)
+				assert.NoError(t, err)
+				assert.Equal(t, "invalid order_uid", response["text"])
+			},
+		},
+		{
+			name:     "InternalServerError",
+			orderUID: "test123",
+			mockSetup: func() {
+				mockUsecase.EXPECT().
+					GetOrderTimeline(gomock.Any(), "test123").
+					Return(nil, assert.AnError)
+			},
+			expectedStatus: http.StatusInternalServerError,
+			validateFunc: func(t *testing.T, body []byte) {
+				var response map[string]any
+				err := json.Unmarshal(body, &response)
+				assert.NoError(t, err)
+				assert.Equal(t, "internal server error", response["text"])
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := httptest.NewRequest("GET", "/orders/"+tt.orderUID+"/timeline", nil)
+			req = mux.SetURLVars(req, map[string]string{"order_uid": tt.orderUID})
+
+			w := httptest.NewRecorder()
+
+			appDelivery.GetOrderTimeline(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			tt.validateFunc(t, w.Body.Bytes())
+		})
+	}
+}
+
+func TestAppDelivery_GetOrderSchema(t *testing.T) {
+	appDelivery := CreateAppDelivery(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/schema/order", nil)
+	rr := httptest.NewRecorder()
+
+	appDelivery.GetOrderSchema(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var schema map[string]any
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &schema))
+	assert.Equal(t, validate.SchemaDraft, schema["$schema"])
+	assert.Equal(t, "object", schema["type"])
+	assert.Contains(t, schema["required"], "order_uid")
+	assert.Contains(t, schema["properties"], "items")
+}
+
 func TestAppDelivery_convertToResponse(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
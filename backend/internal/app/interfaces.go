@@ -10,8 +10,13 @@ import (
 
 type AppRepository interface {
 	GetOrderByID(ctx context.Context, orderUID string) (*models.Order, error)
+	GetOrderTimeline(ctx context.Context, orderUID string) ([]models.TimelineEntry, error)
+	ExportOrders(ctx context.Context, filter models.OrderFilter, fn func(*models.Order) error) error
 }
 
 type AppUsecase interface {
 	GetOrderByID(ctx context.Context, orderUID string) (*models.Order, error)
+	GetOrderTimeline(ctx context.Context, orderUID string) ([]models.TimelineEntry, error)
+	ConvertOrderTotals(order *models.Order, currency string) (*models.ConvertedTotals, error)
+	ExportOrders(ctx context.Context, filter models.OrderFilter, fn func(*models.Order) error) error
 }
@@ -35,6 +35,20 @@ func (m *MockAppRepository) EXPECT() *MockAppRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ExportOrders mocks base method.
+func (m *MockAppRepository) ExportOrders(ctx context.Context, filter models.OrderFilter, fn func(*models.Order) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportOrders", ctx, filter, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportOrders indicates an expected call of ExportOrders.
+func (mr *MockAppRepositoryMockRecorder) ExportOrders(ctx, filter, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportOrders", reflect.TypeOf((*MockAppRepository)(nil).ExportOrders), ctx, filter, fn)
+}
+
 // GetOrderByID mocks base method.
 func (m *MockAppRepository) GetOrderByID(ctx context.Context, orderUID string) (*models.Order, error) {
 	m.ctrl.T.Helper()
@@ -50,6 +64,21 @@ func (mr *MockAppRepositoryMockRecorder) GetOrderByID(ctx, orderUID interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderByID", reflect.TypeOf((*MockAppRepository)(nil).GetOrderByID), ctx, orderUID)
 }
 
+// GetOrderTimeline mocks base method.
+func (m *MockAppRepository) GetOrderTimeline(ctx context.Context, orderUID string) ([]models.TimelineEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderTimeline", ctx, orderUID)
+	ret0, _ := ret[0].([]models.TimelineEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderTimeline indicates an expected call of GetOrderTimeline.
+func (mr *MockAppRepositoryMockRecorder) GetOrderTimeline(ctx, orderUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderTimeline", reflect.TypeOf((*MockAppRepository)(nil).GetOrderTimeline), ctx, orderUID)
+}
+
 // MockAppUsecase is a mock of AppUsecase interface.
 type MockAppUsecase struct {
 	ctrl     *gomock.Controller
@@ -73,6 +102,35 @@ func (m *MockAppUsecase) EXPECT() *MockAppUsecaseMockRecorder {
 	return m.recorder
 }
 
+// ConvertOrderTotals mocks base method.
+func (m *MockAppUsecase) ConvertOrderTotals(order *models.Order, currency string) (*models.ConvertedTotals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConvertOrderTotals", order, currency)
+	ret0, _ := ret[0].(*models.ConvertedTotals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConvertOrderTotals indicates an expected call of ConvertOrderTotals.
+func (mr *MockAppUsecaseMockRecorder) ConvertOrderTotals(order, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConvertOrderTotals", reflect.TypeOf((*MockAppUsecase)(nil).ConvertOrderTotals), order, currency)
+}
+
+// ExportOrders mocks base method.
+func (m *MockAppUsecase) ExportOrders(ctx context.Context, filter models.OrderFilter, fn func(*models.Order) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportOrders", ctx, filter, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportOrders indicates an expected call of ExportOrders.
+func (mr *MockAppUsecaseMockRecorder) ExportOrders(ctx, filter, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportOrders", reflect.TypeOf((*MockAppUsecase)(nil).ExportOrders), ctx, filter, fn)
+}
+
 // GetOrderByID mocks base method.
 func (m *MockAppUsecase) GetOrderByID(ctx context.Context, orderUID string) (*models.Order, error) {
 	m.ctrl.T.Helper()
@@ -87,3 +145,18 @@ func (mr *MockAppUsecaseMockRecorder) GetOrderByID(ctx, orderUID interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderByID", reflect.TypeOf((*MockAppUsecase)(nil).GetOrderByID), ctx, orderUID)
 }
+
+// GetOrderTimeline mocks base method.
+func (m *MockAppUsecase) GetOrderTimeline(ctx context.Context, orderUID string) ([]models.TimelineEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderTimeline", ctx, orderUID)
+	ret0, _ := ret[0].([]models.TimelineEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderTimeline indicates an expected call of GetOrderTimeline.
+func (mr *MockAppUsecaseMockRecorder) GetOrderTimeline(ctx, orderUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderTimeline", reflect.TypeOf((*MockAppUsecase)(nil).GetOrderTimeline), ctx, orderUID)
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -33,6 +34,109 @@ const (
 	CurrencyCHF CurrencyEnum = "CHF"
 )
 
+type EventTypeEnum string
+
+const (
+	EventOrderCreated EventTypeEnum = "order.created"
+	EventOrderUpdated EventTypeEnum = "order.updated"
+	EventOrderDeleted EventTypeEnum = "order.deleted"
+
+	EventItemStatusChanged      EventTypeEnum = "item.status_changed"
+	EventPaymentCaptured        EventTypeEnum = "payment.captured"
+	EventDeliveryAddressChanged EventTypeEnum = "delivery.address_changed"
+)
+
+type EventEnvelope struct {
+	EventType  EventTypeEnum   `json:"event_type"`
+	EventID    string          `json:"event_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+type OrderDeletedEvent struct {
+	OrderUID string `json:"order_uid"`
+}
+
+// ItemStatusChangedEvent addresses the item by its rid, which is unique
+// within an order, unlike chrt_id.
+type ItemStatusChangedEvent struct {
+	OrderUID string `json:"order_uid"`
+	Rid      string `json:"rid"`
+	Status   int    `json:"status"`
+}
+
+type PaymentCapturedEvent struct {
+	OrderUID    string `json:"order_uid"`
+	Transaction string `json:"transaction"`
+	Amount      int    `json:"amount"`
+	PaymentDt   int    `json:"payment_dt"`
+	Bank        string `json:"bank"`
+}
+
+type DeliveryAddressChangedEvent struct {
+	OrderUID string `json:"order_uid"`
+	Zip      string `json:"zip"`
+	City     string `json:"city"`
+	Address  string `json:"address"`
+	Region   string `json:"region"`
+}
+
+type TimelineEntry struct {
+	Type            EventTypeEnum `json:"type"`
+	OccurredAt      time.Time     `json:"occurred_at"`
+	ChrtID          *int          `json:"chrt_id,omitempty"`
+	PreviousStatus  *int          `json:"previous_status,omitempty"`
+	Status          *int          `json:"status,omitempty"`
+	Transaction     string        `json:"transaction,omitempty"`
+	SourceTopic     string        `json:"source_topic,omitempty"`
+	SourcePartition *int32        `json:"source_partition,omitempty"`
+	SourceOffset    *int64        `json:"source_offset,omitempty"`
+}
+
+type ValidationWarning struct {
+	Rule    string `json:"rule"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ConvertedTotals are the money fields of an order converted into another
+// currency, in its minor units.
+type ConvertedTotals struct {
+	Currency     CurrencyEnum          `json:"currency"`
+	Rate         string                `json:"rate"`
+	Amount       int64                 `json:"amount"`
+	DeliveryCost int64                 `json:"delivery_cost"`
+	GoodsTotal   int64                 `json:"goods_total"`
+	CustomFee    int64                 `json:"custom_fee"`
+	Items        []ConvertedItemTotals `json:"items"`
+}
+
+type ConvertedItemTotals struct {
+	ChrtID     int   `json:"chrt_id"`
+	Price      int64 `json:"price"`
+	TotalPrice int64 `json:"total_price"`
+}
+
+// OrderFilter selects orders for listing and export. Zero fields match every
+// order; CreatedTo is exclusive and a zero Limit means no limit.
+type OrderFilter struct {
+	CreatedFrom     time.Time
+	CreatedTo       time.Time
+	CustomerID      string
+	Locale          LocaleEnum
+	DeliveryService string
+	Currency        CurrencyEnum
+	OrderUIDs       []string
+	Limit           int
+}
+
+// OrderPosition is the position of an order in date_created, id order, for
+// paging through orders by keyset.
+type OrderPosition struct {
+	DateCreated time.Time
+	ID          int64
+}
+
 type Order struct {
 	ID                int64      `json:"id" db:"id"`
 	OrderUID          string     `json:"order_uid" db:"order_uid"`
@@ -47,6 +151,10 @@ type Order struct {
 	OofShard          string     `json:"oof_shard" db:"oof_shard"`
 	DateCreated       time.Time  `json:"date_created" db:"date_created"`
 	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+	// Version and VersionSource are the version the order is stored at. They
+	// are read only by ExportOrders and PageOrders, for replays.
+	Version       int64 `json:"-" db:"version"`
+	VersionSource int16 `json:"-" db:"version_source"`
 
 	Delivery *Delivery `json:"delivery,omitempty" db:"-"`
 	Payment  *Payment  `json:"payment,omitempty" db:"-"`
@@ -114,6 +222,7 @@ type OrderRequest struct {
 	SmID              int             `json:"sm_id"`
 	DateCreated       time.Time       `json:"date_created"`
 	OofShard          string          `json:"oof_shard"`
+	Version           int64           `json:"version,omitempty"`
 	Delivery          DeliveryRequest `json:"delivery"`
 	Payment           PaymentRequest  `json:"payment"`
 	Items             []ItemRequest   `json:"items"`
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -28,6 +30,10 @@ func CreateAppRepository(postgresDB pgxiface.PgxIface, redisDB *redis.Client) *A
 	}
 }
 
+func OrderCacheKey(orderUID string) string {
+	return fmt.Sprintf("order:%s", orderUID)
+}
+
 func (ar *AppRepository) GetOrderByID(ctx context.Context, orderUID string) (*models.Order, error) {
 	const funcName = "GetOrderByID"
 
@@ -70,7 +76,7 @@ func (ar *AppRepository) GetOrderByID(ctx context.Context, orderUID string) (*mo
 func (ar *AppRepository) getOrderFromCache(ctx context.Context, orderUID string) (*models.Order, error) {
 	const funcName = "getOrderFromCache"
 
-	cacheKey := fmt.Sprintf("order:%s", orderUID)
+	cacheKey := OrderCacheKey(orderUID)
 
 	data, err := ar.redisDB.Get(ctx, cacheKey).Bytes()
 	if err != nil {
@@ -248,7 +254,7 @@ func (ar *AppRepository) getOrderFromDB(ctx context.Context, orderUID string) (*
 func (ar *AppRepository) saveOrderToCache(ctx context.Context, order *models.Order) error {
 	const funcName = "saveOrderToCache"
 
-	cacheKey := fmt.Sprintf("order:%s", order.OrderUID)
+	cacheKey := OrderCacheKey(order.OrderUID)
 
 	data, err := json.Marshal(order)
 	if err != nil {
@@ -266,3 +272,488 @@ func (ar *AppRepository) saveOrderToCache(ctx context.Context, order *models.Ord
 
 	return nil
 }
+
+func (ar *AppRepository) GetOrderTimeline(ctx context.Context, orderUID string) ([]models.TimelineEntry, error) {
+	const funcName = "GetOrderTimeline"
+
+	orderQuery := `
+		SELECT id, date_created
+		FROM "order"
+		WHERE order_uid = $1
+	`
+
+	var (
+		orderID     int64
+		dateCreated time.Time
+	)
+	err := ar.postgresDB.QueryRow(ctx, orderQuery, orderUID).Scan(&orderID, &dateCreated)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: failed to get order: %w", funcName, err)
+	}
+
+	timeline := []models.TimelineEntry{{
+		Type:       models.EventOrderCreated,
+		OccurredAt: dateCreated,
+	}}
+
+	paymentQuery := `
+		SELECT transaction, payment_dt
+		FROM payment
+		WHERE order_id = $1
+	`
+
+	var (
+		transaction string
+		paymentDt   int64
+	)
+	err = ar.postgresDB.QueryRow(ctx, paymentQuery, orderID).Scan(&transaction, &paymentDt)
+	switch {
+	case err == nil:
+		timeline = append(timeline, models.TimelineEntry{
+			Type:        models.EventPaymentCaptured,
+			OccurredAt:  time.Unix(paymentDt, 0).UTC(),
+			Transaction: transaction,
+		})
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("%s: failed to get payment: %w", funcName, err)
+	}
+
+	historyQuery := `
+		SELECT chrt_id, previous_status, status, source_topic,
+			   source_partition, source_offset, changed_at
+		FROM item_status_history
+		WHERE order_id = $1
+		ORDER BY changed_at, id
+	`
+
+	rows, err := ar.postgresDB.Query(ctx, historyQuery, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get item status history: %w", funcName, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry := models.TimelineEntry{Type: models.EventItemStatusChanged}
+		var chrtID, status int
+		err := rows.Scan(
+			&chrtID,
+			&entry.PreviousStatus,
+			&status,
+			&entry.SourceTopic,
+			&entry.SourcePartition,
+			&entry.SourceOffset,
+			&entry.OccurredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to scan item status history: %w", funcName, err)
+		}
+		entry.ChrtID = &chrtID
+		entry.Status = &status
+		timeline = append(timeline, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: rows error: %w", funcName, err)
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].OccurredAt.Before(timeline[j].OccurredAt)
+	})
+
+	return timeline, nil
+}
+
+// exportBatchSize is the number of orders fetched from the export cursor at a
+// time. Deliveries, payments and items are loaded once per batch.
+const exportBatchSize = 500
+
+// ExportOrders streams the orders matching filter to fn, oldest first. Orders
+// are read through a server-side cursor so memory use does not grow with the
+// size of the export.
+func (ar *AppRepository) ExportOrders(ctx context.Context, filter models.OrderFilter, fn func(*models.Order) error) error {
+	const funcName = "ExportOrders"
+
+	tx, err := ar.postgresDB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: failed to begin transaction: %w", funcName, err)
+	}
+	defer tx.Rollback(ctx)
+
+	where, args := orderFilterClause(filter)
+	cursorQuery := `
+		DECLARE order_export NO SCROLL CURSOR FOR
+		SELECT o.id, o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,
+			   o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.oof_shard,
+			   o.date_created, o.updated_at, o.version, o.version_source
+		FROM "order" o` + where + `
+		ORDER BY o.date_created, o.id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		cursorQuery += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	if _, err := tx.Exec(ctx, cursorQuery, args...); err != nil {
+		return fmt.Errorf("%s: failed to declare cursor: %w", funcName, err)
+	}
+
+	exported := 0
+	for {
+		orders, err := fetchExportBatch(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", funcName, err)
+		}
+		if len(orders) == 0 {
+			break
+		}
+
+		if err := loadOrderDetails(ctx, tx, orders); err != nil {
+			return fmt.Errorf("%s: %w", funcName, err)
+		}
+
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+		exported += len(orders)
+
+		if len(orders) < exportBatchSize {
+			break
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: failed to commit transaction: %w", funcName, err)
+	}
+
+	logger.Info("orders exported",
+		zap.String("function", funcName),
+		zap.Int("count", exported))
+
+	return nil
+}
+
+// PageOrders returns up to size orders matching filter that come after the
+// after position in date_created, id order, or the first ones for a nil after.
+// filter.Limit is ignored. Each page is read in its own short transaction, so
+// a caller can take its time between pages, as a rate-limited replay does.
+func (ar *AppRepository) PageOrders(ctx context.Context, filter models.OrderFilter, after *models.OrderPosition, size int) ([]*models.Order, error) {
+	const funcName = "PageOrders"
+
+	tx, err := ar.postgresDB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to begin transaction: %w", funcName, err)
+	}
+	defer tx.Rollback(ctx)
+
+	where, args := orderFilterClause(filter)
+	if after != nil {
+		args = append(args, after.DateCreated, after.ID)
+		condition := fmt.Sprintf("(o.date_created, o.id) > ($%d, $%d)", len(args)-1, len(args))
+		if where == "" {
+			where = "\n\t\tWHERE " + condition
+		} else {
+			where += " AND " + condition
+		}
+	}
+	args = append(args, size)
+
+	query := `
+		SELECT o.id, o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,
+			   o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.oof_shard,
+			   o.date_created, o.updated_at, o.version, o.version_source
+		FROM "order" o` + where + `
+		ORDER BY o.date_created, o.id` + fmt.Sprintf(" LIMIT $%d", len(args))
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get orders: %w", funcName, err)
+	}
+	orders, err := scanOrders(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", funcName, err)
+	}
+
+	if len(orders) > 0 {
+		if err := loadOrderDetails(ctx, tx, orders); err != nil {
+			return nil, fmt.Errorf("%s: %w", funcName, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit transaction: %w", funcName, err)
+	}
+
+	return orders, nil
+}
+
+// WarmCache caches the count most recently created orders, so the first
+// lookups after a start do not all miss the cache. It returns the number of
+// orders cached.
+func (ar *AppRepository) WarmCache(ctx context.Context, count int) (int, error) {
+	const funcName = "WarmCache"
+
+	tx, err := ar.postgresDB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to begin transaction: %w", funcName, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT o.id, o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,
+			   o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.oof_shard,
+			   o.date_created, o.updated_at, o.version, o.version_source
+		FROM "order" o
+		ORDER BY o.date_created DESC, o.id DESC
+		LIMIT $1
+	`
+
+	rows, err := tx.Query(ctx, query, count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get orders: %w", funcName, err)
+	}
+	orders, err := scanOrders(rows)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", funcName, err)
+	}
+
+	if len(orders) > 0 {
+		if err := loadOrderDetails(ctx, tx, orders); err != nil {
+			return 0, fmt.Errorf("%s: %w", funcName, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: failed to commit transaction: %w", funcName, err)
+	}
+
+	for i, order := range orders {
+		if err := ar.saveOrderToCache(ctx, order); err != nil {
+			return i, fmt.Errorf("%s: %w", funcName, err)
+		}
+	}
+
+	return len(orders), nil
+}
+
+func orderFilterClause(filter models.OrderFilter) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if !filter.CreatedFrom.IsZero() {
+		add("o.date_created >= $%d", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		add("o.date_created < $%d", filter.CreatedTo)
+	}
+	if filter.CustomerID != "" {
+		add("o.customer_id = $%d", filter.CustomerID)
+	}
+	if filter.Locale != "" {
+		add("o.locale = $%d", filter.Locale)
+	}
+	if filter.DeliveryService != "" {
+		add("o.delivery_service = $%d", filter.DeliveryService)
+	}
+	if filter.Currency != "" {
+		add("EXISTS (SELECT 1 FROM payment p WHERE p.order_id = o.id AND p.currency = $%d)", filter.Currency)
+	}
+	if len(filter.OrderUIDs) > 0 {
+		add("o.order_uid = ANY($%d)", filter.OrderUIDs)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return "\n\t\tWHERE " + strings.Join(conditions, " AND "), args
+}
+
+func fetchExportBatch(ctx context.Context, tx pgx.Tx) ([]*models.Order, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf("FETCH %d FROM order_export", exportBatchSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch orders: %w", err)
+	}
+
+	return scanOrders(rows)
+}
+
+func scanOrders(rows pgx.Rows) ([]*models.Order, error) {
+	defer rows.Close()
+
+	var orders []*models.Order
+	for rows.Next() {
+		order := &models.Order{}
+		err := rows.Scan(
+			&order.ID,
+			&order.OrderUID,
+			&order.TrackNumber,
+			&order.Entry,
+			&order.Locale,
+			&order.InternalSignature,
+			&order.CustomerID,
+			&order.DeliveryService,
+			&order.Shardkey,
+			&order.SmID,
+			&order.OofShard,
+			&order.DateCreated,
+			&order.UpdatedAt,
+			&order.Version,
+			&order.VersionSource,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return orders, nil
+}
+
+// loadOrderDetails fills in the delivery, payment and items of a batch of
+// orders with one query per table.
+func loadOrderDetails(ctx context.Context, tx pgx.Tx, orders []*models.Order) error {
+	byID := make(map[int64]*models.Order, len(orders))
+	ids := make([]int64, 0, len(orders))
+	for _, order := range orders {
+		byID[order.ID] = order
+		ids = append(ids, order.ID)
+	}
+
+	deliveryQuery := `
+		SELECT order_id, id, name, phone, zip, city, address, region, email,
+			   created_at, updated_at
+		FROM delivery
+		WHERE order_id = ANY($1)
+	`
+
+	rows, err := tx.Query(ctx, deliveryQuery, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get deliveries: %w", err)
+	}
+	for rows.Next() {
+		delivery := &models.Delivery{}
+		err := rows.Scan(
+			&delivery.OrderID,
+			&delivery.ID,
+			&delivery.Name,
+			&delivery.Phone,
+			&delivery.Zip,
+			&delivery.City,
+			&delivery.Address,
+			&delivery.Region,
+			&delivery.Email,
+			&delivery.CreatedAt,
+			&delivery.UpdatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		byID[delivery.OrderID].Delivery = delivery
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	paymentQuery := `
+		SELECT order_id, id, transaction, request_id, currency, provider, amount,
+			   payment_dt, bank, delivery_cost, goods_total, custom_fee,
+			   created_at, updated_at
+		FROM payment
+		WHERE order_id = ANY($1)
+	`
+
+	rows, err = tx.Query(ctx, paymentQuery, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get payments: %w", err)
+	}
+	for rows.Next() {
+		payment := &models.Payment{}
+		err := rows.Scan(
+			&payment.OrderID,
+			&payment.ID,
+			&payment.Transaction,
+			&payment.RequestID,
+			&payment.Currency,
+			&payment.Provider,
+			&payment.Amount,
+			&payment.PaymentDt,
+			&payment.Bank,
+			&payment.DeliveryCost,
+			&payment.GoodsTotal,
+			&payment.CustomFee,
+			&payment.CreatedAt,
+			&payment.UpdatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan payment: %w", err)
+		}
+		byID[payment.OrderID].Payment = payment
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	itemsQuery := `
+		SELECT order_id, id, chrt_id, track_number, price, rid, name, sale, size,
+			   total_price, nm_id, brand, status, created_at, updated_at
+		FROM item
+		WHERE order_id = ANY($1)
+		ORDER BY order_id, id
+	`
+
+	rows, err = tx.Query(ctx, itemsQuery, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.Item
+		err := rows.Scan(
+			&item.OrderID,
+			&item.ID,
+			&item.ChrtID,
+			&item.TrackNumber,
+			&item.Price,
+			&item.Rid,
+			&item.Name,
+			&item.Sale,
+			&item.Size,
+			&item.TotalPrice,
+			&item.NmID,
+			&item.Brand,
+			&item.Status,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan item: %w", err)
+		}
+		order := byID[item.OrderID]
+		order.Items = append(order.Items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	return nil
+}
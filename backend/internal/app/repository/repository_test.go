@@ -293,3 +293,323 @@ func TestGetOrderFromDB_DeliveryError(t *testing.T) {
 	assert.Nil(t, result)
 	assert.NoError(t, pgxMock.ExpectationsWereMet())
 }
+
+func TestGetOrderTimeline(t *testing.T) {
+	redisClient, _ := redismock.NewClientMock()
+
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("failed to create pgx mock: %v", err)
+	}
+	defer pgxMock.Close(context.Background())
+
+	repo := CreateAppRepository(pgxMock, redisClient)
+
+	orderUID := "test-order-123"
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	paid := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+	previousStatus := 202
+	partition, offset := int32(0), int64(42)
+
+	pgxMock.ExpectQuery(`SELECT id, date_created\s+FROM "order"`).
+		WithArgs(orderUID).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "date_created"}).AddRow(int64(1), created))
+	pgxMock.ExpectQuery(`SELECT transaction, payment_dt\s+FROM payment`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"transaction", "payment_dt"}).AddRow("txn-1", paid.Unix()))
+	pgxMock.ExpectQuery(`FROM item_status_history`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{
+			"chrt_id", "previous_status", "status", "source_topic",
+			"source_partition", "source_offset", "changed_at",
+		}).
+			AddRow(9934930, nil, 202, "import", nil, nil, created).
+			AddRow(9934930, &previousStatus, 300, "orders", &partition, &offset, created.Add(time.Hour)))
+
+	timeline, err := repo.GetOrderTimeline(context.Background(), orderUID)
+
+	assert.NoError(t, err)
+	assert.Len(t, timeline, 4)
+	assert.Equal(t, models.EventOrderCreated, timeline[0].Type)
+	assert.Equal(t, models.EventItemStatusChanged, timeline[1].Type)
+	assert.Nil(t, timeline[1].PreviousStatus)
+	assert.Nil(t, timeline[1].SourcePartition)
+	assert.Nil(t, timeline[1].SourceOffset)
+	assert.Equal(t, models.EventPaymentCaptured, timeline[2].Type)
+	assert.Equal(t, paid, timeline[2].OccurredAt)
+	assert.Equal(t, "txn-1", timeline[2].Transaction)
+	assert.Equal(t, models.EventItemStatusChanged, timeline[3].Type)
+	assert.Equal(t, 202, *timeline[3].PreviousStatus)
+	assert.Equal(t, 300, *timeline[3].Status)
+	assert.Equal(t, int64(42), *timeline[3].SourceOffset)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestGetOrderTimeline_NotFound(t *testing.T) {
+	redisClient, _ := redismock.NewClientMock()
+
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("failed to create pgx mock: %v", err)
+	}
+	defer pgxMock.Close(context.Background())
+
+	repo := CreateAppRepository(pgxMock, redisClient)
+
+	pgxMock.ExpectQuery(`SELECT id, date_created\s+FROM "order"`).
+		WithArgs("missing").
+		WillReturnError(pgx.ErrNoRows)
+
+	timeline, err := repo.GetOrderTimeline(context.Background(), "missing")
+
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+	assert.Nil(t, timeline)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestExportOrders(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("failed to create pgx mock: %v", err)
+	}
+	defer pgxMock.Close(context.Background())
+
+	repo := CreateAppRepository(pgxMock, nil)
+
+	now := time.Now()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter := models.OrderFilter{CreatedFrom: from, Currency: models.CurrencyUSD, Limit: 10}
+
+	pgxMock.ExpectBegin()
+	pgxMock.ExpectExec(`DECLARE order_export NO SCROLL CURSOR FOR .*`+
+		`WHERE o.date_created >= \$1 AND EXISTS \(SELECT 1 FROM payment p WHERE p.order_id = o.id AND p.currency = \$2\)\s+`+
+		`ORDER BY o.date_created, o.id LIMIT \$3`).
+		WithArgs(from, models.CurrencyUSD, 10).
+		WillReturnResult(pgxmock.NewResult("DECLARE CURSOR", 0))
+
+	orderRows := pgxmock.NewRows([]string{
+		"id", "order_uid", "track_number", "entry", "locale", "internal_signature",
+		"customer_id", "delivery_service", "shardkey", "sm_id", "oof_shard",
+		"date_created", "updated_at", "version", "version_source",
+	}).
+		AddRow(int64(1), "order1", "TRACK1", "WBIL", models.LocaleEN, "", "c1", "meest", "9", 99, "1", now, now, int64(3), int16(3)).
+		AddRow(int64(2), "order2", "TRACK2", "WBIL", models.LocaleRU, "", "c2", "dhl", "9", 99, "1", now, now, int64(3), int16(3))
+	pgxMock.ExpectQuery(`FETCH 500 FROM order_export`).WillReturnRows(orderRows)
+
+	deliveryRows := pgxmock.NewRows([]string{
+		"order_id", "id", "name", "phone", "zip", "city", "address", "region", "email",
+		"created_at", "updated_at",
+	}).AddRow(int64(2), int64(20), "Test Testov", "+9720000000", "2639809", "Kiryat Mozkin", "Ploshad Mira 15", "Kraiot", "test@gmail.com", now, now)
+	pgxMock.ExpectQuery(`FROM delivery\s+WHERE order_id = ANY\(\$1\)`).
+		WithArgs([]int64{1, 2}).
+		WillReturnRows(deliveryRows)
+
+	paymentRows := pgxmock.NewRows([]string{
+		"order_id", "id", "transaction", "request_id", "currency", "provider", "amount",
+		"payment_dt", "bank", "delivery_cost", "goods_total", "custom_fee",
+		"created_at", "updated_at",
+	}).AddRow(int64(1), int64(10), "order1", "", models.CurrencyUSD, "wbpay", 1817, 1637907727, "alpha", 1500, 317, 0, now, now)
+	pgxMock.ExpectQuery(`FROM payment\s+WHERE order_id = ANY\(\$1\)`).
+		WithArgs([]int64{1, 2}).
+		WillReturnRows(paymentRows)
+
+	itemRows := pgxmock.NewRows([]string{
+		"order_id", "id", "chrt_id", "track_number", "price", "rid", "name", "sale", "size",
+		"total_price", "nm_id", "brand", "status", "created_at", "updated_at",
+	}).
+		AddRow(int64(1), int64(100), 9934930, "TRACK1", 453, "rid1", "Mascaras", 30, "0", 317, 2389212, "Vivienne Sabo", 202, now, now).
+		AddRow(int64(1), int64(101), 9934931, "TRACK1", 200, "rid2", "Lipstick", 0, "0", 200, 2389213, "Vivienne Sabo", 202, now, now)
+	pgxMock.ExpectQuery(`FROM item\s+WHERE order_id = ANY\(\$1\)\s+ORDER BY order_id, id`).
+		WithArgs([]int64{1, 2}).
+		WillReturnRows(itemRows)
+
+	pgxMock.ExpectCommit()
+	pgxMock.ExpectRollback()
+
+	var exported []*models.Order
+	err = repo.ExportOrders(context.Background(), filter, func(order *models.Order) error {
+		exported = append(exported, order)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	if assert.Len(t, exported, 2) {
+		assert.Equal(t, "order1", exported[0].OrderUID)
+		assert.Len(t, exported[0].Items, 2)
+		assert.Equal(t, 1817, exported[0].Payment.Amount)
+		assert.Nil(t, exported[0].Delivery)
+
+		assert.Equal(t, "order2", exported[1].OrderUID)
+		assert.Equal(t, "Test Testov", exported[1].Delivery.Name)
+		assert.Nil(t, exported[1].Payment)
+		assert.Empty(t, exported[1].Items)
+	}
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestExportOrders_CallbackErrorStopsExport(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("failed to create pgx mock: %v", err)
+	}
+	defer pgxMock.Close(context.Background())
+
+	repo := CreateAppRepository(pgxMock, nil)
+	now := time.Now()
+
+	pgxMock.ExpectBegin()
+	pgxMock.ExpectExec(`DECLARE order_export NO SCROLL CURSOR FOR .*ORDER BY o.date_created, o.id$`).
+		WillReturnResult(pgxmock.NewResult("DECLARE CURSOR", 0))
+	pgxMock.ExpectQuery(`FETCH 500 FROM order_export`).WillReturnRows(pgxmock.NewRows([]string{
+		"id", "order_uid", "track_number", "entry", "locale", "internal_signature",
+		"customer_id", "delivery_service", "shardkey", "sm_id", "oof_shard",
+		"date_created", "updated_at", "version", "version_source",
+	}).AddRow(int64(1), "order1", "TRACK1", "WBIL", models.LocaleEN, "", "c1", "meest", "9", 99, "1", now, now, int64(3), int16(3)))
+	pgxMock.ExpectQuery(`FROM delivery`).WithArgs([]int64{1}).WillReturnRows(pgxmock.NewRows([]string{"order_id"}))
+	pgxMock.ExpectQuery(`FROM payment`).WithArgs([]int64{1}).WillReturnRows(pgxmock.NewRows([]string{"order_id"}))
+	pgxMock.ExpectQuery(`FROM item`).WithArgs([]int64{1}).WillReturnRows(pgxmock.NewRows([]string{"order_id"}))
+	pgxMock.ExpectRollback()
+
+	writeErr := errors.New("client went away")
+	err = repo.ExportOrders(context.Background(), models.OrderFilter{}, func(*models.Order) error {
+		return writeErr
+	})
+
+	assert.ErrorIs(t, err, writeErr)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestExportOrders_ByOrderUIDs(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("failed to create pgx mock: %v", err)
+	}
+	defer pgxMock.Close(context.Background())
+
+	repo := CreateAppRepository(pgxMock, nil)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	uids := []string{"order1", "order2"}
+
+	pgxMock.ExpectBegin()
+	pgxMock.ExpectExec(`WHERE o.date_created < \$1 AND o.order_uid = ANY\(\$2\)\s+ORDER BY o.date_created, o.id$`).
+		WithArgs(to, uids).
+		WillReturnResult(pgxmock.NewResult("DECLARE CURSOR", 0))
+	pgxMock.ExpectQuery(`FETCH 500 FROM order_export`).WillReturnRows(pgxmock.NewRows([]string{
+		"id", "order_uid", "track_number", "entry", "locale", "internal_signature",
+		"customer_id", "delivery_service", "shardkey", "sm_id", "oof_shard",
+		"date_created", "updated_at", "version", "version_source",
+	}))
+	pgxMock.ExpectCommit()
+	pgxMock.ExpectRollback()
+
+	err = repo.ExportOrders(context.Background(), models.OrderFilter{CreatedTo: to, OrderUIDs: uids}, func(*models.Order) error {
+		t.Error("no order should be exported")
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestPageOrders(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("failed to create pgx mock: %v", err)
+	}
+	defer pgxMock.Close(context.Background())
+
+	repo := CreateAppRepository(pgxMock, nil)
+	now := time.Now()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	after := &models.OrderPosition{DateCreated: from.Add(time.Hour), ID: 7}
+
+	pgxMock.ExpectBegin()
+	pgxMock.ExpectQuery(`FROM "order" o\s+WHERE o.date_created >= \$1 AND \(o.date_created, o.id\) > \(\$2, \$3\)\s+`+
+		`ORDER BY o.date_created, o.id LIMIT \$4`).
+		WithArgs(from, after.DateCreated, after.ID, 2).
+		WillReturnRows(pgxmock.NewRows([]string{
+			"id", "order_uid", "track_number", "entry", "locale", "internal_signature",
+			"customer_id", "delivery_service", "shardkey", "sm_id", "oof_shard",
+			"date_created", "updated_at", "version", "version_source",
+		}).AddRow(int64(8), "order8", "TRACK8", "WBIL", models.LocaleEN, "", "c8", "meest", "9", 99, "1", now, now, int64(1704110400000), int16(2)))
+	pgxMock.ExpectQuery(`FROM delivery`).WithArgs([]int64{8}).WillReturnRows(pgxmock.NewRows([]string{"order_id"}))
+	pgxMock.ExpectQuery(`FROM payment`).WithArgs([]int64{8}).WillReturnRows(pgxmock.NewRows([]string{"order_id"}))
+	pgxMock.ExpectQuery(`FROM item`).WithArgs([]int64{8}).WillReturnRows(pgxmock.NewRows([]string{"order_id"}))
+	pgxMock.ExpectCommit()
+	pgxMock.ExpectRollback()
+
+	orders, err := repo.PageOrders(context.Background(), models.OrderFilter{CreatedFrom: from, Limit: 100}, after, 2)
+
+	assert.NoError(t, err)
+	if assert.Len(t, orders, 1) {
+		assert.Equal(t, "order8", orders[0].OrderUID)
+		assert.Equal(t, int64(1704110400000), orders[0].Version)
+		assert.Equal(t, int16(2), orders[0].VersionSource)
+	}
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestPageOrders_FirstPage(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("failed to create pgx mock: %v", err)
+	}
+	defer pgxMock.Close(context.Background())
+
+	repo := CreateAppRepository(pgxMock, nil)
+
+	pgxMock.ExpectBegin()
+	pgxMock.ExpectQuery(`FROM "order" o\s+ORDER BY o.date_created, o.id LIMIT \$1`).
+		WithArgs(500).
+		WillReturnRows(pgxmock.NewRows([]string{
+			"id", "order_uid", "track_number", "entry", "locale", "internal_signature",
+			"customer_id", "delivery_service", "shardkey", "sm_id", "oof_shard",
+			"date_created", "updated_at", "version", "version_source",
+		}))
+	pgxMock.ExpectCommit()
+	pgxMock.ExpectRollback()
+
+	orders, err := repo.PageOrders(context.Background(), models.OrderFilter{}, nil, 500)
+
+	assert.NoError(t, err)
+	assert.Empty(t, orders)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestWarmCache(t *testing.T) {
+	redisClient, redisMock := redismock.NewClientMock()
+
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("failed to create pgx mock: %v", err)
+	}
+	defer pgxMock.Close(context.Background())
+
+	repo := CreateAppRepository(pgxMock, redisClient)
+	now := time.Now()
+
+	pgxMock.ExpectBegin()
+	pgxMock.ExpectQuery(`FROM "order" o\s+ORDER BY o.date_created DESC, o.id DESC\s+LIMIT \$1`).
+		WithArgs(2).
+		WillReturnRows(pgxmock.NewRows([]string{
+			"id", "order_uid", "track_number", "entry", "locale", "internal_signature",
+			"customer_id", "delivery_service", "shardkey", "sm_id", "oof_shard",
+			"date_created", "updated_at", "version", "version_source",
+		}).
+			AddRow(int64(2), "order2", "TRACK2", "WBIL", models.LocaleRU, "", "c2", "dhl", "9", 99, "1", now, now, int64(3), int16(3)).
+			AddRow(int64(1), "order1", "TRACK1", "WBIL", models.LocaleEN, "", "c1", "meest", "9", 99, "1", now, now, int64(3), int16(3)))
+	pgxMock.ExpectQuery(`FROM delivery`).WithArgs([]int64{2, 1}).WillReturnRows(pgxmock.NewRows([]string{"order_id"}))
+	pgxMock.ExpectQuery(`FROM payment`).WithArgs([]int64{2, 1}).WillReturnRows(pgxmock.NewRows([]string{"order_id"}))
+	pgxMock.ExpectQuery(`FROM item`).WithArgs([]int64{2, 1}).WillReturnRows(pgxmock.NewRows([]string{"order_id"}))
+	pgxMock.ExpectCommit()
+	pgxMock.ExpectRollback()
+	redisMock.Regexp().ExpectSet("order:order2", `.*`, 7*24*time.Hour).SetVal("OK")
+	redisMock.Regexp().ExpectSet("order:order1", `.*`, 7*24*time.Hour).SetVal("OK")
+
+	cached, err := repo.WarmCache(context.Background(), 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cached)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+	assert.NoError(t, redisMock.ExpectationsWereMet())
+}
@@ -9,12 +9,14 @@ import (
 	"github.com/supchaser/wb_l0/internal/app/models"
 	"github.com/supchaser/wb_l0/internal/utils/errs"
 	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/money"
 	"github.com/supchaser/wb_l0/internal/utils/validate"
 	"go.uber.org/zap"
 )
 
 type AppUsecase struct {
 	orderRepository app.AppRepository
+	rates           *money.Rates
 }
 
 func CreateAppUsecase(orderRepository app.AppRepository) *AppUsecase {
@@ -23,6 +25,11 @@ func CreateAppUsecase(orderRepository app.AppRepository) *AppUsecase {
 	}
 }
 
+// UseRates enables currency conversion with the given rate table.
+func (uc *AppUsecase) UseRates(rates *money.Rates) {
+	uc.rates = rates
+}
+
 func (uc *AppUsecase) GetOrderByID(ctx context.Context, orderUID string) (*models.Order, error) {
 	const funcName = "Usecase.GetOrderByID"
 
@@ -31,7 +38,7 @@ func (uc *AppUsecase) GetOrderByID(ctx context.Context, orderUID string) (*model
 			zap.String("function", funcName),
 			zap.String("order_uid", orderUID),
 			zap.Error(err))
-		return nil, fmt.Errorf("%w: %v", errs.ErrValidation, err)
+		return nil, err
 	}
 
 	order, err := uc.orderRepository.GetOrderByID(ctx, orderUID)
@@ -56,3 +63,115 @@ func (uc *AppUsecase) GetOrderByID(ctx context.Context, orderUID string) (*model
 
 	return order, nil
 }
+
+func (uc *AppUsecase) GetOrderTimeline(ctx context.Context, orderUID string) ([]models.TimelineEntry, error) {
+	const funcName = "Usecase.GetOrderTimeline"
+
+	if err := validate.ValidateOrderUID(orderUID); err != nil {
+		logger.Warn("invalid order UID",
+			zap.String("function", funcName),
+			zap.String("order_uid", orderUID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	timeline, err := uc.orderRepository.GetOrderTimeline(ctx, orderUID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			logger.Warn("order not found",
+				zap.String("function", funcName),
+				zap.String("order_uid", orderUID))
+			return nil, errs.ErrNotFound
+		}
+
+		logger.Error("failed to get order timeline",
+			zap.String("function", funcName),
+			zap.String("order_uid", orderUID),
+			zap.Error(err))
+		return nil, fmt.Errorf("%s: failed to get order timeline: %w", funcName, err)
+	}
+
+	return timeline, nil
+}
+
+func (uc *AppUsecase) ExportOrders(ctx context.Context, filter models.OrderFilter, fn func(*models.Order) error) error {
+	const funcName = "Usecase.ExportOrders"
+
+	if err := validate.ValidateOrderFilter(&filter); err != nil {
+		logger.Warn("invalid order filter",
+			zap.String("function", funcName),
+			zap.Error(err))
+		return err
+	}
+
+	if err := uc.orderRepository.ExportOrders(ctx, filter, fn); err != nil {
+		return fmt.Errorf("%s: failed to export orders: %w", funcName, err)
+	}
+
+	return nil
+}
+
+// ConvertOrderTotals converts the payment totals and item prices of order,
+// stored in minor units of the payment currency, into currency. Orders
+// without a payment have nothing to convert and yield nil.
+func (uc *AppUsecase) ConvertOrderTotals(order *models.Order, currency string) (*models.ConvertedTotals, error) {
+	if uc.rates == nil {
+		return nil, fmt.Errorf("%w: exchange rates are not configured", errs.ErrUnsupportedCurrency)
+	}
+
+	to, err := money.ParseCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	if order.Payment == nil {
+		return nil, nil
+	}
+
+	from := order.Payment.Currency
+	rate, err := uc.rates.Rate(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	convert := func(minor int) (int64, error) {
+		converted, err := uc.rates.Convert(money.Money{Minor: int64(minor), Currency: from}, to)
+		return converted.Minor, err
+	}
+
+	totals := &models.ConvertedTotals{
+		Currency: to,
+		Rate:     rate.FloatString(6),
+		Items:    make([]models.ConvertedItemTotals, 0, len(order.Items)),
+	}
+	for _, field := range []struct {
+		minor  int
+		target *int64
+	}{
+		{order.Payment.Amount, &totals.Amount},
+		{order.Payment.DeliveryCost, &totals.DeliveryCost},
+		{order.Payment.GoodsTotal, &totals.GoodsTotal},
+		{order.Payment.CustomFee, &totals.CustomFee},
+	} {
+		if *field.target, err = convert(field.minor); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, item := range order.Items {
+		price, err := convert(item.Price)
+		if err != nil {
+			return nil, err
+		}
+		totalPrice, err := convert(item.TotalPrice)
+		if err != nil {
+			return nil, err
+		}
+		totals.Items = append(totals.Items, models.ConvertedItemTotals{
+			ChrtID:     item.ChrtID,
+			Price:      price,
+			TotalPrice: totalPrice,
+		})
+	}
+
+	return totals, nil
+}
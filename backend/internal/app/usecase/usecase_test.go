@@ -11,6 +11,7 @@ import (
 	"github.com/supchaser/wb_l0/internal/app/models"
 	"github.com/supchaser/wb_l0/internal/utils/errs"
 	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/money"
 )
 
 func TestMain(m *testing.M) {
@@ -158,6 +159,33 @@ func TestAppUsecase_GetOrderByID_ValidationError(t *testing.T) {
 	}
 }
 
+func TestAppUsecase_ExportOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_app.NewMockAppRepository(ctrl)
+	uc := CreateAppUsecase(mockRepo)
+
+	filter := models.OrderFilter{CustomerID: "test", Limit: 10}
+	mockRepo.EXPECT().
+		ExportOrders(gomock.Any(), filter, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ models.OrderFilter, fn func(*models.Order) error) error {
+			return fn(&models.Order{OrderUID: "test123"})
+		})
+
+	var exported []string
+	err := uc.ExportOrders(context.Background(), filter, func(order *models.Order) error {
+		exported = append(exported, order.OrderUID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test123"}, exported)
+
+	mockRepo.EXPECT().ExportOrders(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	err = uc.ExportOrders(context.Background(), models.OrderFilter{Limit: -1}, func(*models.Order) error { return nil })
+	assert.ErrorIs(t, err, errs.ErrValidation)
+}
+
 func TestCreateAppUsecase(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -169,3 +197,76 @@ func TestCreateAppUsecase(t *testing.T) {
 	assert.NotNil(t, uc)
 	assert.IsType(t, &AppUsecase{}, uc)
 }
+
+func TestAppUsecase_ConvertOrderTotals(t *testing.T) {
+	rates, err := money.ParseRates([]byte("base: EUR\nrates:\n  USD: 1.25\n  JPY: 160\n"))
+	assert.NoError(t, err)
+
+	order := &models.Order{
+		OrderUID: "test123",
+		Payment: &models.Payment{
+			Currency:     models.CurrencyUSD,
+			Amount:       1817,
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []models.Item{{ChrtID: 9934930, Price: 453, TotalPrice: 317}},
+	}
+
+	tests := []struct {
+		name          string
+		rates         *money.Rates
+		order         *models.Order
+		currency      string
+		expected      *models.ConvertedTotals
+		expectedError error
+	}{
+		{
+			name:     "Success",
+			rates:    rates,
+			order:    order,
+			currency: "eur",
+			expected: &models.ConvertedTotals{
+				Currency:     models.CurrencyEUR,
+				Rate:         "0.800000",
+				Amount:       1454,
+				DeliveryCost: 1200,
+				GoodsTotal:   254,
+				Items:        []models.ConvertedItemTotals{{ChrtID: 9934930, Price: 362, TotalPrice: 254}},
+			},
+		},
+		{
+			name:     "ZeroExponentTarget",
+			rates:    rates,
+			order:    order,
+			currency: "JPY",
+			expected: &models.ConvertedTotals{
+				Currency:     models.CurrencyJPY,
+				Rate:         "128.000000",
+				Amount:       2326,
+				DeliveryCost: 1920,
+				GoodsTotal:   406,
+				Items:        []models.ConvertedItemTotals{{ChrtID: 9934930, Price: 580, TotalPrice: 406}},
+			},
+		},
+		{name: "NoPayment", rates: rates, order: &models.Order{OrderUID: "test123"}, currency: "EUR"},
+		{name: "UnknownCurrency", rates: rates, order: order, currency: "BTC", expectedError: errs.ErrUnsupportedCurrency},
+		{name: "MissingRate", rates: rates, order: order, currency: "GBP", expectedError: errs.ErrUnsupportedCurrency},
+		{name: "NotConfigured", order: order, currency: "EUR", expectedError: errs.ErrUnsupportedCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := CreateAppUsecase(nil)
+			uc.UseRates(tt.rates)
+
+			totals, err := uc.ConvertOrderTotals(tt.order, tt.currency)
+			if tt.expectedError != nil {
+				assert.True(t, errors.Is(err, tt.expectedError))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, totals)
+		})
+	}
+}
@@ -14,9 +14,16 @@ type Config struct {
 	PostgresDSN           string
 	RedisDSN              string
 	KafkaBootstrapServers string
-
-	ProducerConfig *ProducerConfig
-	ConsumerConfig *ConsumerConfig
+	ReadinessTimeoutMs    int
+	CacheWarmupOrders     int
+	CacheWarmupTimeoutMs  int
+	ValidationRulesFile   string
+	ExchangeRatesFile     string
+	InvoiceFontFile       string
+
+	ProducerConfig  *ProducerConfig
+	ConsumerConfig  *ConsumerConfig
+	GeneratorConfig *GeneratorConfig
 }
 
 type ProducerConfig struct {
@@ -29,14 +36,41 @@ type ProducerConfig struct {
 	BatchSize         int
 	LingerMs          int
 	EnableIdempotence bool
+	PayloadFormat     string
+	SchemaRegistryURL string
 }
 
 type ConsumerConfig struct {
-	Brokers          []string
-	GroupID          string
-	Topic            string
-	AutoOffsetReset  string
-	EnableAutoCommit bool
+	Brokers           []string
+	GroupID           string
+	Topic             string
+	AutoOffsetReset   string
+	EnableAutoCommit  bool
+	MaxReadyLag       int
+	LagIntervalMs     int
+	AcceptedFormats   []string
+	SchemaRegistryURL string
+	ValidationRules   string
+	DLQTopic          string
+	SchemaValidation  bool
+	StrictJSONTopics  []string
+}
+
+// GeneratorConfig drives the load generator in cmd/producer. A Rate of 0
+// produces as fast as Concurrency allows, a Count of 0 runs until stopped and
+// a Seed of 0 picks one from the clock. FaultRate is the fraction of orders
+// replaced by one of Faults, or by any fault when Faults is empty.
+type GeneratorConfig struct {
+	Rate        float64
+	Count       int
+	Burst       int
+	Concurrency int
+	Seed        int64
+	UIDPrefix   string
+	ItemsMin    int
+	ItemsMax    int
+	FaultRate   float64
+	Faults      []string
 }
 
 func checkEnv(envVars []string) error {
@@ -84,6 +118,24 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -93,6 +145,16 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func LoadConfig() (*Config, error) {
 	err := validateEnv()
 	if err != nil {
@@ -108,6 +170,12 @@ func LoadConfig() (*Config, error) {
 		PostgresDSN:           os.Getenv("POSTGRES_DSN"),
 		RedisDSN:              os.Getenv("REDIS_DSN"),
 		KafkaBootstrapServers: os.Getenv("KAFKA_BOOTSTRAP_SERVERS"),
+		ReadinessTimeoutMs:    getEnvInt("READINESS_TIMEOUT_MS", 2000),
+		CacheWarmupOrders:     getEnvInt("CACHE_WARMUP_ORDERS", 1000),
+		CacheWarmupTimeoutMs:  getEnvInt("CACHE_WARMUP_TIMEOUT_MS", 30000),
+		ValidationRulesFile:   os.Getenv("VALIDATION_RULES_FILE"),
+		ExchangeRatesFile:     os.Getenv("EXCHANGE_RATES_FILE"),
+		InvoiceFontFile:       os.Getenv("INVOICE_FONT_FILE"),
 
 		ProducerConfig: &ProducerConfig{
 			Brokers:           kafkaBrokers,
@@ -119,14 +187,37 @@ func LoadConfig() (*Config, error) {
 			LingerMs:          getEnvInt("KAFKA_LINGER_MS", 100),
 			EnableIdempotence: getEnvBool("KAFKA_ENABLE_IDEMPOTENCE", true),
 			Topic:             getEnv("TOPIC", "orders"),
+			PayloadFormat:     getEnv("KAFKA_PAYLOAD_FORMAT", "json"),
+			SchemaRegistryURL: os.Getenv("SCHEMA_REGISTRY_URL"),
 		},
 
 		ConsumerConfig: &ConsumerConfig{
-			Brokers:          kafkaBrokers,
-			GroupID:          getEnv("KAFKA_CONSUMER_GROUP_ID", "wb-l0-consumer-group"),
-			Topic:            getEnv("KAFKA_TOPIC", "orders"),
-			AutoOffsetReset:  getEnv("KAFKA_AUTO_OFFSET_RESET", "earliest"),
-			EnableAutoCommit: getEnvBool("KAFKA_ENABLE_AUTO_COMMIT", false),
+			Brokers:           kafkaBrokers,
+			GroupID:           getEnv("KAFKA_CONSUMER_GROUP_ID", "wb-l0-consumer-group"),
+			Topic:             getEnv("KAFKA_TOPIC", "orders"),
+			AutoOffsetReset:   getEnv("KAFKA_AUTO_OFFSET_RESET", "earliest"),
+			EnableAutoCommit:  getEnvBool("KAFKA_ENABLE_AUTO_COMMIT", false),
+			MaxReadyLag:       getEnvInt("KAFKA_MAX_READY_LAG", 10000),
+			LagIntervalMs:     getEnvInt("KAFKA_LAG_INTERVAL_MS", 5000),
+			AcceptedFormats:   strings.Split(getEnv("KAFKA_ACCEPTED_FORMATS", "json,protobuf,avro"), ","),
+			SchemaRegistryURL: os.Getenv("SCHEMA_REGISTRY_URL"),
+			ValidationRules:   os.Getenv("VALIDATION_RULES"),
+			DLQTopic:          os.Getenv("KAFKA_DLQ_TOPIC"),
+			SchemaValidation:  getEnvBool("KAFKA_SCHEMA_VALIDATION", false),
+			StrictJSONTopics:  getEnvList("KAFKA_STRICT_JSON_TOPICS"),
+		},
+
+		GeneratorConfig: &GeneratorConfig{
+			Rate:        getEnvFloat("GENERATOR_RATE", 1.0/60),
+			Count:       getEnvInt("GENERATOR_COUNT", 0),
+			Burst:       getEnvInt("GENERATOR_BURST", 1),
+			Concurrency: getEnvInt("GENERATOR_CONCURRENCY", 16),
+			Seed:        getEnvInt64("GENERATOR_SEED", 0),
+			UIDPrefix:   os.Getenv("GENERATOR_UID_PREFIX"),
+			ItemsMin:    getEnvInt("GENERATOR_ITEMS_MIN", 1),
+			ItemsMax:    getEnvInt("GENERATOR_ITEMS_MAX", 3),
+			FaultRate:   getEnvFloat("GENERATOR_FAULT_RATE", 0),
+			Faults:      getEnvList("GENERATOR_FAULTS"),
 		},
 	}, nil
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -255,6 +256,77 @@ func TestGetEnvBool(t *testing.T) {
 	}
 }
 
+func TestGetEnvFloat(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		defaultValue float64
+		want         float64
+	}{
+		{name: "valid float", value: "2.5", defaultValue: 1, want: 2.5},
+		{name: "invalid float", value: "fast", defaultValue: 1, want: 1},
+		{name: "empty", value: "", defaultValue: 0.5, want: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("FLOAT_VAR", tt.value)
+			defer os.Unsetenv("FLOAT_VAR")
+
+			if got := getEnvFloat("FLOAT_VAR", tt.defaultValue); got != tt.want {
+				t.Errorf("getEnvFloat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetEnvInt64(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		defaultValue int64
+		want         int64
+	}{
+		{name: "valid int64", value: "9007199254740993", defaultValue: 0, want: 9007199254740993},
+		{name: "invalid int64", value: "seed", defaultValue: 7, want: 7},
+		{name: "empty", value: "", defaultValue: 7, want: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("INT64_VAR", tt.value)
+			defer os.Unsetenv("INT64_VAR")
+
+			if got := getEnvInt64("INT64_VAR", tt.defaultValue); got != tt.want {
+				t.Errorf("getEnvInt64() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetEnvList(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "empty", value: "", want: nil},
+		{name: "single", value: "orders", want: []string{"orders"}},
+		{name: "trims and skips blanks", value: " orders, ,order-events ", want: []string{"orders", "order-events"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("LIST_VAR", tt.value)
+			defer os.Unsetenv("LIST_VAR")
+
+			if got := getEnvList("LIST_VAR"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getEnvList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestLoadConfig(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -368,6 +440,24 @@ func TestLoadConfig(t *testing.T) {
 				if cfg.ConsumerConfig.GroupID != "wb-l0-consumer-group" {
 					t.Errorf("ConsumerConfig.GroupID = %v, want %v", cfg.ConsumerConfig.GroupID, "wb-l0-consumer-group")
 				}
+				if cfg.ProducerConfig.PayloadFormat != "json" {
+					t.Errorf("ProducerConfig.PayloadFormat = %v, want %v", cfg.ProducerConfig.PayloadFormat, "json")
+				}
+				if len(cfg.ConsumerConfig.AcceptedFormats) != 3 {
+					t.Errorf("ConsumerConfig.AcceptedFormats = %v, want 3 formats", cfg.ConsumerConfig.AcceptedFormats)
+				}
+				if len(cfg.ConsumerConfig.StrictJSONTopics) != 0 {
+					t.Errorf("ConsumerConfig.StrictJSONTopics = %v, want none", cfg.ConsumerConfig.StrictJSONTopics)
+				}
+				if cfg.GeneratorConfig.Rate != 1.0/60 || cfg.GeneratorConfig.Count != 0 || cfg.GeneratorConfig.Burst != 1 {
+					t.Errorf("GeneratorConfig = %+v, want one order a minute until stopped", cfg.GeneratorConfig)
+				}
+				if cfg.GeneratorConfig.ItemsMin != 1 || cfg.GeneratorConfig.ItemsMax != 3 {
+					t.Errorf("GeneratorConfig items = %d-%d, want 1-3", cfg.GeneratorConfig.ItemsMin, cfg.GeneratorConfig.ItemsMax)
+				}
+				if cfg.GeneratorConfig.FaultRate != 0 || len(cfg.GeneratorConfig.Faults) != 0 {
+					t.Errorf("GeneratorConfig faults = %v %v, want none", cfg.GeneratorConfig.FaultRate, cfg.GeneratorConfig.Faults)
+				}
 			},
 		},
 	}
@@ -0,0 +1,125 @@
+package export
+
+import (
+	"time"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+)
+
+type columnType int
+
+const (
+	columnString columnType = iota
+	columnInt
+	columnTime
+)
+
+// column is one field of the flattened export, which has a row per item.
+// value returns nil when the order has no delivery, payment or item to read
+// it from.
+type column struct {
+	name  string
+	typ   columnType
+	value func(order *models.Order, item *models.Item) any
+}
+
+func orderColumn(name string, typ columnType, value func(*models.Order) any) column {
+	return column{name: name, typ: typ, value: func(order *models.Order, _ *models.Item) any {
+		return value(order)
+	}}
+}
+
+func deliveryColumn(name string, value func(*models.Delivery) string) column {
+	return column{name: "delivery_" + name, typ: columnString, value: func(order *models.Order, _ *models.Item) any {
+		if order.Delivery == nil {
+			return nil
+		}
+		return value(order.Delivery)
+	}}
+}
+
+func paymentColumn(name string, typ columnType, value func(*models.Payment) any) column {
+	return column{name: "payment_" + name, typ: typ, value: func(order *models.Order, _ *models.Item) any {
+		if order.Payment == nil {
+			return nil
+		}
+		return value(order.Payment)
+	}}
+}
+
+func itemColumn(name string, typ columnType, value func(*models.Item) any) column {
+	return column{name: "item_" + name, typ: typ, value: func(_ *models.Order, item *models.Item) any {
+		if item == nil {
+			return nil
+		}
+		return value(item)
+	}}
+}
+
+var columns = []column{
+	orderColumn("order_uid", columnString, func(o *models.Order) any { return o.OrderUID }),
+	orderColumn("track_number", columnString, func(o *models.Order) any { return o.TrackNumber }),
+	orderColumn("entry", columnString, func(o *models.Order) any { return o.Entry }),
+	orderColumn("locale", columnString, func(o *models.Order) any { return string(o.Locale) }),
+	orderColumn("internal_signature", columnString, func(o *models.Order) any { return o.InternalSignature }),
+	orderColumn("customer_id", columnString, func(o *models.Order) any { return o.CustomerID }),
+	orderColumn("delivery_service", columnString, func(o *models.Order) any { return o.DeliveryService }),
+	orderColumn("shardkey", columnString, func(o *models.Order) any { return o.Shardkey }),
+	orderColumn("sm_id", columnInt, func(o *models.Order) any { return int64(o.SmID) }),
+	orderColumn("oof_shard", columnString, func(o *models.Order) any { return o.OofShard }),
+	orderColumn("date_created", columnTime, func(o *models.Order) any { return o.DateCreated }),
+
+	deliveryColumn("name", func(d *models.Delivery) string { return d.Name }),
+	deliveryColumn("phone", func(d *models.Delivery) string { return d.Phone }),
+	deliveryColumn("zip", func(d *models.Delivery) string { return d.Zip }),
+	deliveryColumn("city", func(d *models.Delivery) string { return d.City }),
+	deliveryColumn("address", func(d *models.Delivery) string { return d.Address }),
+	deliveryColumn("region", func(d *models.Delivery) string { return d.Region }),
+	deliveryColumn("email", func(d *models.Delivery) string { return d.Email }),
+
+	paymentColumn("transaction", columnString, func(p *models.Payment) any { return p.Transaction }),
+	paymentColumn("request_id", columnString, func(p *models.Payment) any { return p.RequestID }),
+	paymentColumn("currency", columnString, func(p *models.Payment) any { return string(p.Currency) }),
+	paymentColumn("provider", columnString, func(p *models.Payment) any { return p.Provider }),
+	paymentColumn("amount", columnInt, func(p *models.Payment) any { return int64(p.Amount) }),
+	paymentColumn("payment_dt", columnTime, func(p *models.Payment) any { return time.Unix(int64(p.PaymentDt), 0) }),
+	paymentColumn("bank", columnString, func(p *models.Payment) any { return p.Bank }),
+	paymentColumn("delivery_cost", columnInt, func(p *models.Payment) any { return int64(p.DeliveryCost) }),
+	paymentColumn("goods_total", columnInt, func(p *models.Payment) any { return int64(p.GoodsTotal) }),
+	paymentColumn("custom_fee", columnInt, func(p *models.Payment) any { return int64(p.CustomFee) }),
+
+	itemColumn("chrt_id", columnInt, func(i *models.Item) any { return int64(i.ChrtID) }),
+	itemColumn("track_number", columnString, func(i *models.Item) any { return i.TrackNumber }),
+	itemColumn("price", columnInt, func(i *models.Item) any { return int64(i.Price) }),
+	itemColumn("rid", columnString, func(i *models.Item) any { return i.Rid }),
+	itemColumn("name", columnString, func(i *models.Item) any { return i.Name }),
+	itemColumn("sale", columnInt, func(i *models.Item) any { return int64(i.Sale) }),
+	itemColumn("size", columnString, func(i *models.Item) any { return i.Size }),
+	itemColumn("total_price", columnInt, func(i *models.Item) any { return int64(i.TotalPrice) }),
+	itemColumn("nm_id", columnInt, func(i *models.Item) any { return int64(i.NmID) }),
+	itemColumn("brand", columnString, func(i *models.Item) any { return i.Brand }),
+	itemColumn("status", columnInt, func(i *models.Item) any { return int64(i.Status) }),
+}
+
+// flatten calls fn with the values of each row of order: one per item, or a
+// single row with empty item columns when the order has no items.
+func flatten(order *models.Order, fn func(values []any) error) error {
+	values := make([]any, len(columns))
+	row := func(item *models.Item) error {
+		for i, c := range columns {
+			values[i] = c.value(order, item)
+		}
+		return fn(values)
+	}
+
+	if len(order.Items) == 0 {
+		return row(nil)
+	}
+	for i := range order.Items {
+		if err := row(&order.Items[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(format)) {
+	case "", FormatCSV:
+		return FormatCSV, nil
+	case FormatNDJSON:
+		return FormatNDJSON, nil
+	case FormatParquet:
+		return FormatParquet, nil
+	}
+
+	return "", fmt.Errorf("%w: export format %q", errs.ErrValidation, format)
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+// Writer encodes orders one at a time. Close must be called to flush
+// buffered rows and write any trailer; it does not close the underlying
+// writer.
+type Writer interface {
+	Write(order *models.Order) error
+	Close() error
+}
+
+func NewWriter(format Format, w io.Writer) Writer {
+	switch format {
+	case FormatNDJSON:
+		return newNDJSONWriter(w)
+	case FormatParquet:
+		return newParquetWriter(w)
+	}
+
+	return newCSVWriter(w)
+}
@@ -0,0 +1,344 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+func testOrders() []*models.Order {
+	created := time.Date(2021, time.November, 26, 6, 22, 19, 0, time.UTC)
+
+	return []*models.Order{
+		{
+			OrderUID:        "b563feb7b2b84b6test",
+			TrackNumber:     "WBILMTESTTRACK",
+			Entry:           "WBIL",
+			Locale:          models.LocaleEN,
+			CustomerID:      "test",
+			DeliveryService: "meest",
+			Shardkey:        "9",
+			SmID:            99,
+			OofShard:        "1",
+			DateCreated:     created,
+			Delivery:        &models.Delivery{Name: "Test, \"Testov\"", City: "Kiryat Mozkin"},
+			Payment:         &models.Payment{Transaction: "b563feb7b2b84b6test", Currency: models.CurrencyUSD, Amount: 1817, PaymentDt: 1637907727},
+			Items: []models.Item{
+				{ChrtID: 9934930, Name: "Mascaras", Price: 453, Sale: 30, TotalPrice: 317, Status: 202},
+				{ChrtID: 9934931, Name: "Lipstick", Price: 200, TotalPrice: 200, Status: 202},
+			},
+		},
+		{
+			OrderUID:    "c000000000000000test",
+			Locale:      models.LocaleRU,
+			DateCreated: created.Add(time.Hour),
+		},
+	}
+}
+
+func writeAll(t *testing.T, format Format, orders []*models.Order) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := NewWriter(format, &buf)
+	for _, order := range orders {
+		require.NoError(t, w.Write(order))
+	}
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{in: "", want: FormatCSV},
+		{in: "CSV", want: FormatCSV},
+		{in: "ndjson", want: FormatNDJSON},
+		{in: "parquet", want: FormatParquet},
+		{in: "xlsx", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFormat(tt.in)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, errs.ErrValidation))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(writeAll(t, FormatCSV, testOrders()))).ReadAll()
+	require.NoError(t, err)
+
+	require.Len(t, records, 4)
+	header := records[0]
+	assert.Equal(t, "order_uid", header[0])
+	assert.Equal(t, "item_status", header[len(header)-1])
+
+	row := func(record []string) map[string]string {
+		values := make(map[string]string, len(header))
+		for i, name := range header {
+			values[name] = record[i]
+		}
+		return values
+	}
+
+	first := row(records[1])
+	assert.Equal(t, "b563feb7b2b84b6test", first["order_uid"])
+	assert.Equal(t, "Test, \"Testov\"", first["delivery_name"])
+	assert.Equal(t, "2021-11-26T06:22:19Z", first["date_created"])
+	assert.Equal(t, "2021-11-26T06:22:07Z", first["payment_payment_dt"])
+	assert.Equal(t, "9934930", first["item_chrt_id"])
+	assert.Equal(t, "30", first["item_sale"])
+
+	assert.Equal(t, "9934931", row(records[2])["item_chrt_id"])
+
+	withoutItems := row(records[3])
+	assert.Equal(t, "c000000000000000test", withoutItems["order_uid"])
+	assert.Empty(t, withoutItems["item_chrt_id"])
+	assert.Empty(t, withoutItems["payment_amount"])
+}
+
+func TestCSVWriter_EmptyExportHasHeader(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(writeAll(t, FormatCSV, nil))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Len(t, records[0], len(columns))
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	scanner := bufio.NewScanner(bytes.NewReader(writeAll(t, FormatNDJSON, testOrders())))
+
+	var orders []models.Order
+	for scanner.Scan() {
+		var order models.Order
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &order))
+		orders = append(orders, order)
+	}
+
+	require.Len(t, orders, 2)
+	assert.Equal(t, "b563feb7b2b84b6test", orders[0].OrderUID)
+	assert.Len(t, orders[0].Items, 2)
+	assert.Equal(t, 1817, orders[0].Payment.Amount)
+	assert.Nil(t, orders[1].Payment)
+}
+
+func TestParquetWriter(t *testing.T) {
+	file := readParquet(t, writeAll(t, FormatParquet, testOrders()))
+
+	assert.Equal(t, int64(3), file.numRows)
+	assert.Len(t, file.rowGroups, 1)
+	assert.Equal(t, columnNames(), file.columnNames)
+
+	assert.Equal(t, []any{"b563feb7b2b84b6test", "b563feb7b2b84b6test", "c000000000000000test"}, file.column("order_uid"))
+	assert.Equal(t, []any{int64(9934930), int64(9934931), nil}, file.column("item_chrt_id"))
+	assert.Equal(t, []any{int64(1637907727000), int64(1637907727000), nil}, file.column("payment_payment_dt"))
+	assert.Equal(t, []any{"Test, \"Testov\"", "Test, \"Testov\"", nil}, file.column("delivery_name"))
+}
+
+func TestParquetWriter_RowGroups(t *testing.T) {
+	order := testOrders()[1]
+	orders := make([]*models.Order, parquetRowGroupRows+10)
+	for i := range orders {
+		orders[i] = order
+	}
+
+	file := readParquet(t, writeAll(t, FormatParquet, orders))
+
+	assert.Equal(t, int64(len(orders)), file.numRows)
+	assert.Len(t, file.rowGroups, 2)
+	assert.Len(t, file.column("order_uid"), len(orders))
+}
+
+func TestParquetWriter_Empty(t *testing.T) {
+	file := readParquet(t, writeAll(t, FormatParquet, nil))
+
+	assert.Equal(t, int64(0), file.numRows)
+	assert.Empty(t, file.rowGroups)
+}
+
+func columnNames() []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+type parquetFile struct {
+	data        []byte
+	numRows     int64
+	columnNames []string
+	rowGroups   []any
+}
+
+func readParquet(t *testing.T, data []byte) *parquetFile {
+	t.Helper()
+
+	require.True(t, bytes.HasPrefix(data, []byte(parquetMagic)))
+	require.True(t, bytes.HasSuffix(data, []byte(parquetMagic)))
+
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLen
+	r := &compactReader{data: data, pos: footerStart}
+	meta := r.readStruct()
+	require.Equal(t, len(data)-8, r.pos, "footer length")
+
+	file := &parquetFile{data: data, numRows: meta[3].(int64)}
+	schema := meta[2].([]any)
+	for _, element := range schema[1:] {
+		file.columnNames = append(file.columnNames, string(element.(map[int16]any)[4].([]byte)))
+	}
+	if groups, ok := meta[4].([]any); ok {
+		file.rowGroups = groups
+	}
+
+	return file
+}
+
+// column decodes every value of the named column across all row groups.
+func (f *parquetFile) column(name string) []any {
+	index := -1
+	for i, c := range f.columnNames {
+		if c == name {
+			index = i
+		}
+	}
+	typ := columns[index].typ
+
+	var values []any
+	for _, group := range f.rowGroups {
+		chunk := group.(map[int16]any)[1].([]any)[index].(map[int16]any)
+		meta := chunk[3].(map[int16]any)
+		r := &compactReader{data: f.data, pos: int(meta[9].(int64))}
+		header := r.readStruct()
+		dataHeader := header[5].(map[int16]any)
+		rows := int(dataHeader[1].(int32))
+
+		page := f.data[r.pos : r.pos+int(header[3].(int32))]
+		levelsLen := int(binary.LittleEndian.Uint32(page))
+		levels := decodeLevels(page[4:4+levelsLen], rows)
+		plain := page[4+levelsLen:]
+
+		for _, defined := range levels {
+			if !defined {
+				values = append(values, nil)
+				continue
+			}
+			switch typ {
+			case columnString:
+				n := int(binary.LittleEndian.Uint32(plain))
+				values = append(values, string(plain[4:4+n]))
+				plain = plain[4+n:]
+			default:
+				values = append(values, int64(binary.LittleEndian.Uint64(plain)))
+				plain = plain[8:]
+			}
+		}
+	}
+
+	return values
+}
+
+func decodeLevels(data []byte, rows int) []bool {
+	var levels []bool
+	for len(levels) < rows {
+		header, n := binary.Uvarint(data)
+		data = data[n:]
+		if header&1 != 0 {
+			panic("bit-packed runs are not written")
+		}
+		for i := 0; i < int(header>>1); i++ {
+			levels = append(levels, data[0] == 1)
+		}
+		data = data[1:]
+	}
+	return levels
+}
+
+// compactReader decodes just enough of the Thrift compact protocol to check
+// the metadata written by compactWriter.
+type compactReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *compactReader) varint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *compactReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *compactReader) readStruct() map[int16]any {
+	fields := make(map[int16]any)
+	var last int16
+	for {
+		b := r.data[r.pos]
+		r.pos++
+		if b == 0 {
+			return fields
+		}
+		typ := b & 0x0f
+		if delta := int16(b >> 4); delta != 0 {
+			last += delta
+		} else {
+			last = int16(r.zigzag())
+		}
+		fields[last] = r.readValue(typ)
+	}
+}
+
+func (r *compactReader) readValue(typ byte) any {
+	switch typ {
+	case thriftI32:
+		return int32(r.zigzag())
+	case thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := int(r.varint())
+		v := r.data[r.pos : r.pos+n]
+		r.pos += n
+		return v
+	case thriftList:
+		header := r.data[r.pos]
+		r.pos++
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.varint())
+		}
+		values := make([]any, size)
+		for i := range values {
+			values[i] = r.readValue(header & 0x0f)
+		}
+		return values
+	case thriftStruct:
+		return r.readStruct()
+	}
+
+	panic(fmt.Sprintf("unexpected thrift type %d", typ))
+}
@@ -0,0 +1,281 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+)
+
+const (
+	parquetMagic = "PAR1"
+
+	// parquetRowGroupRows bounds the rows buffered in memory before a row
+	// group is written out.
+	parquetRowGroupRows = 5000
+)
+
+// Parquet format enums (parquet.thrift).
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetOptional = 1
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMillis = 9
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+)
+
+// parquetWriter writes a flat Parquet file with one optional column per
+// export column, PLAIN encoded and uncompressed. Rows are buffered column by
+// column and flushed as a row group every parquetRowGroupRows rows.
+type parquetWriter struct {
+	w         *countingWriter
+	values    [][]any
+	rows      int
+	totalRows int64
+	rowGroups []parquetRowGroup
+	started   bool
+}
+
+type parquetRowGroup struct {
+	columns   []parquetColumnChunk
+	byteSize  int64
+	rowsCount int64
+}
+
+type parquetColumnChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{
+		w:      &countingWriter{w: w},
+		values: make([][]any, len(columns)),
+	}
+}
+
+func (w *parquetWriter) Write(order *models.Order) error {
+	return flatten(order, func(values []any) error {
+		for i, value := range values {
+			w.values[i] = append(w.values[i], value)
+		}
+		w.rows++
+
+		if w.rows >= parquetRowGroupRows {
+			return w.flush()
+		}
+		return nil
+	})
+}
+
+func (w *parquetWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	footer := w.footer()
+	if _, err := w.w.Write(footer); err != nil {
+		return err
+	}
+	if err := binary.Write(w.w, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w.w, parquetMagic)
+	return err
+}
+
+func (w *parquetWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+
+	_, err := io.WriteString(w.w, parquetMagic)
+	return err
+}
+
+func (w *parquetWriter) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	group := parquetRowGroup{rowsCount: int64(w.rows)}
+	for i, c := range columns {
+		page := encodePage(c, w.values[i])
+		header := pageHeader(w.rows, len(page))
+
+		chunk := parquetColumnChunk{
+			offset:    w.w.n,
+			size:      int64(len(header) + len(page)),
+			numValues: int64(w.rows),
+		}
+		if _, err := w.w.Write(header); err != nil {
+			return err
+		}
+		if _, err := w.w.Write(page); err != nil {
+			return err
+		}
+
+		group.columns = append(group.columns, chunk)
+		group.byteSize += chunk.size
+		w.values[i] = w.values[i][:0]
+	}
+
+	w.rowGroups = append(w.rowGroups, group)
+	w.totalRows += int64(w.rows)
+	w.rows = 0
+
+	return nil
+}
+
+// encodePage returns a v1 data page: RLE definition levels (bit width 1,
+// prefixed by their length) followed by the PLAIN encoded non-null values.
+func encodePage(c column, values []any) []byte {
+	var levels bytes.Buffer
+	for start := 0; start < len(values); {
+		defined := values[start] != nil
+		end := start + 1
+		for end < len(values) && (values[end] != nil) == defined {
+			end++
+		}
+
+		levels.Write(binary.AppendUvarint(nil, uint64(end-start)<<1))
+		if defined {
+			levels.WriteByte(1)
+		} else {
+			levels.WriteByte(0)
+		}
+		start = end
+	}
+
+	var page bytes.Buffer
+	_ = binary.Write(&page, binary.LittleEndian, uint32(levels.Len()))
+	page.Write(levels.Bytes())
+
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			_ = binary.Write(&page, binary.LittleEndian, uint32(len(v)))
+			page.WriteString(v)
+		case int64:
+			_ = binary.Write(&page, binary.LittleEndian, v)
+		case time.Time:
+			_ = binary.Write(&page, binary.LittleEndian, v.UnixMilli())
+		}
+	}
+
+	return page.Bytes()
+}
+
+func pageHeader(rows, size int) []byte {
+	var c compactWriter
+	c.beginStruct()
+	c.i32(1, parquetDataPage)
+	c.i32(2, int32(size))
+	c.i32(3, int32(size))
+	c.structField(5)
+	c.i32(1, int32(rows))
+	c.i32(2, parquetEncodingPlain)
+	c.i32(3, parquetEncodingRLE)
+	c.i32(4, parquetEncodingRLE)
+	c.endStruct()
+	c.endStruct()
+
+	return c.buf.Bytes()
+}
+
+func (w *parquetWriter) footer() []byte {
+	var c compactWriter
+	c.beginStruct()
+	c.i32(1, 1)
+
+	c.list(2, thriftStruct, len(columns)+1)
+	c.beginStruct()
+	c.binary(4, "order")
+	c.i32(5, int32(len(columns)))
+	c.endStruct()
+	for _, col := range columns {
+		physical, converted := parquetTypes(col.typ)
+		c.beginStruct()
+		c.i32(1, physical)
+		c.i32(3, parquetOptional)
+		c.binary(4, col.name)
+		if converted >= 0 {
+			c.i32(6, converted)
+		}
+		c.endStruct()
+	}
+
+	c.i64(3, w.totalRows)
+
+	c.list(4, thriftStruct, len(w.rowGroups))
+	for _, group := range w.rowGroups {
+		c.beginStruct()
+		c.list(1, thriftStruct, len(group.columns))
+		for i, chunk := range group.columns {
+			physical, _ := parquetTypes(columns[i].typ)
+			c.beginStruct()
+			c.i64(2, chunk.offset)
+			c.structField(3)
+			c.i32(1, physical)
+			c.listI32(2, parquetEncodingPlain, parquetEncodingRLE)
+			c.listBinary(3, columns[i].name)
+			c.i32(4, parquetUncompressed)
+			c.i64(5, chunk.numValues)
+			c.i64(6, chunk.size)
+			c.i64(7, chunk.size)
+			c.i64(9, chunk.offset)
+			c.endStruct()
+			c.endStruct()
+		}
+		c.i64(2, group.byteSize)
+		c.i64(3, group.rowsCount)
+		c.endStruct()
+	}
+
+	c.binary(6, "wb_l0 export")
+	c.endStruct()
+
+	return c.buf.Bytes()
+}
+
+// parquetTypes returns the physical and converted type of a column; -1 means
+// no converted type.
+func parquetTypes(typ columnType) (int32, int32) {
+	switch typ {
+	case columnInt:
+		return parquetInt64, -1
+	case columnTime:
+		return parquetInt64, parquetConvertedTimestampMillis
+	}
+
+	return parquetByteArray, parquetConvertedUTF8
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+)
+
+type csvWriter struct {
+	writer      *csv.Writer
+	wroteHeader bool
+	record      []string
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{writer: csv.NewWriter(w), record: make([]string, len(columns))}
+}
+
+func (w *csvWriter) header() error {
+	if w.wroteHeader {
+		return nil
+	}
+	w.wroteHeader = true
+
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+
+	return w.writer.Write(names)
+}
+
+func (w *csvWriter) Write(order *models.Order) error {
+	if err := w.header(); err != nil {
+		return err
+	}
+
+	err := flatten(order, func(values []any) error {
+		for i, value := range values {
+			w.record[i] = formatCSV(value)
+		}
+		return w.writer.Write(w.record)
+	})
+	if err != nil {
+		return err
+	}
+
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvWriter) Close() error {
+	if err := w.header(); err != nil {
+		return err
+	}
+
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func formatCSV(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	}
+
+	return ""
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	return &ndjsonWriter{encoder: json.NewEncoder(w)}
+}
+
+func (w *ndjsonWriter) Write(order *models.Order) error {
+	return w.encoder.Encode(order)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
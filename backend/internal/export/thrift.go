@@ -0,0 +1,97 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Thrift compact protocol type ids, as used by the Parquet metadata.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// compactWriter writes the Thrift compact protocol, which is all Parquet
+// needs for its page headers and footer. Structs nest by pushing the last
+// field id, since field headers are delta-encoded within a struct.
+type compactWriter struct {
+	buf  bytes.Buffer
+	last []int16
+}
+
+func (c *compactWriter) beginStruct() {
+	c.last = append(c.last, 0)
+}
+
+func (c *compactWriter) endStruct() {
+	c.buf.WriteByte(0)
+	c.last = c.last[:len(c.last)-1]
+}
+
+func (c *compactWriter) field(id int16, typ byte) {
+	last := &c.last[len(c.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		c.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		c.buf.WriteByte(typ)
+		c.varint(uint64(zigzag(int64(id))))
+	}
+	*last = id
+}
+
+func (c *compactWriter) structField(id int16) {
+	c.field(id, thriftStruct)
+	c.beginStruct()
+}
+
+func (c *compactWriter) i32(id int16, v int32) {
+	c.field(id, thriftI32)
+	c.varint(zigzag(int64(v)))
+}
+
+func (c *compactWriter) i64(id int16, v int64) {
+	c.field(id, thriftI64)
+	c.varint(zigzag(v))
+}
+
+func (c *compactWriter) binary(id int16, v string) {
+	c.field(id, thriftBinary)
+	c.varint(uint64(len(v)))
+	c.buf.WriteString(v)
+}
+
+func (c *compactWriter) list(id int16, elemType byte, size int) {
+	c.field(id, thriftList)
+	if size < 15 {
+		c.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	c.buf.WriteByte(0xf0 | elemType)
+	c.varint(uint64(size))
+}
+
+func (c *compactWriter) listI32(id int16, values ...int32) {
+	c.list(id, thriftI32, len(values))
+	for _, v := range values {
+		c.varint(zigzag(int64(v)))
+	}
+}
+
+func (c *compactWriter) listBinary(id int16, values ...string) {
+	c.list(id, thriftBinary, len(values))
+	for _, v := range values {
+		c.varint(uint64(len(v)))
+		c.buf.WriteString(v)
+	}
+}
+
+func (c *compactWriter) varint(v uint64) {
+	c.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
@@ -0,0 +1,179 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/responses"
+	"go.uber.org/zap"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+	StatusAlive    = "alive"
+
+	PhaseWarmingUp = "warming_up"
+	PhaseServing   = "serving"
+	PhaseDraining  = "draining"
+)
+
+type CheckFunc func(ctx context.Context) error
+
+type CheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Phase  string                 `json:"phase"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+type Health struct {
+	timeout time.Duration
+	phase   atomic.Value
+	mu      sync.RWMutex
+	checks  []namedCheck
+}
+
+func CreateHealth(timeout time.Duration) *Health {
+	h := &Health{
+		timeout: timeout,
+	}
+	h.phase.Store(PhaseWarmingUp)
+
+	return h
+}
+
+func (h *Health) Register(name string, check CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+func (h *Health) SetServing() {
+	h.phase.Store(PhaseServing)
+	logger.Info("readiness phase changed", zap.String("phase", PhaseServing))
+}
+
+func (h *Health) SetDraining() {
+	h.phase.Store(PhaseDraining)
+	logger.Info("readiness phase changed", zap.String("phase", PhaseDraining))
+}
+
+func (h *Health) Phase() string {
+	return h.phase.Load().(string)
+}
+
+func (h *Health) Check(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := make([]namedCheck, len(h.checks))
+	copy(checks, h.checks)
+	h.mu.RUnlock()
+
+	phase := h.Phase()
+	report := Report{
+		Status: StatusReady,
+		Phase:  phase,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+
+	for _, nc := range checks {
+		wg.Add(1)
+		go func(nc namedCheck) {
+			defer wg.Done()
+
+			result := runCheck(ctx, nc.check)
+
+			mu.Lock()
+			report.Checks[nc.name] = result
+			mu.Unlock()
+		}(nc)
+	}
+
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusNotReady
+			break
+		}
+	}
+
+	if phase != PhaseServing {
+		report.Status = StatusNotReady
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, check CheckFunc) CheckResult {
+	start := time.Now()
+	errChan := make(chan error, 1)
+
+	go func() {
+		errChan <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errChan:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Status:     StatusOK,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+func (h *Health) Livez(w http.ResponseWriter, r *http.Request) {
+	responses.DoJSONResponse(w, Report{
+		Status: StatusAlive,
+		Phase:  h.Phase(),
+	}, http.StatusOK)
+}
+
+func (h *Health) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.Check(r.Context())
+
+	statusCode := http.StatusOK
+	if report.Status != StatusReady {
+		statusCode = http.StatusServiceUnavailable
+		logger.Warn("readiness check failed",
+			zap.String("phase", report.Phase),
+			zap.Any("checks", report.Checks))
+	}
+
+	responses.DoJSONResponse(w, report, statusCode)
+}
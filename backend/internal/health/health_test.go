@@ -0,0 +1,135 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitTestLogger()
+	m.Run()
+}
+
+func TestHealth_Readyz(t *testing.T) {
+	okCheck := func(ctx context.Context) error { return nil }
+	failCheck := func(ctx context.Context) error { return errors.New("connection refused") }
+	slowCheck := func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}
+
+	tests := []struct {
+		name           string
+		setup          func(h *Health)
+		expectedStatus int
+		validateFunc   func(t *testing.T, report Report)
+	}{
+		{
+			name: "AllChecksPass",
+			setup: func(h *Health) {
+				h.Register("postgres", okCheck)
+				h.Register("redis", okCheck)
+				h.SetServing()
+			},
+			expectedStatus: http.StatusOK,
+			validateFunc: func(t *testing.T, report Report) {
+				assert.Equal(t, StatusReady, report.Status)
+				assert.Equal(t, PhaseServing, report.Phase)
+				assert.Equal(t, StatusOK, report.Checks["postgres"].Status)
+				assert.Equal(t, StatusOK, report.Checks["redis"].Status)
+			},
+		},
+		{
+			name: "DependencyDown",
+			setup: func(h *Health) {
+				h.Register("postgres", okCheck)
+				h.Register("redis", failCheck)
+				h.SetServing()
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			validateFunc: func(t *testing.T, report Report) {
+				assert.Equal(t, StatusNotReady, report.Status)
+				assert.Equal(t, StatusFail, report.Checks["redis"].Status)
+				assert.Equal(t, "connection refused", report.Checks["redis"].Error)
+			},
+		},
+		{
+			name: "CheckTimedOut",
+			setup: func(h *Health) {
+				h.Register("kafka", slowCheck)
+				h.SetServing()
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			validateFunc: func(t *testing.T, report Report) {
+				assert.Equal(t, StatusFail, report.Checks["kafka"].Status)
+				assert.Contains(t, report.Checks["kafka"].Error, "deadline exceeded")
+			},
+		},
+		{
+			name: "WarmingUp",
+			setup: func(h *Health) {
+				h.Register("postgres", okCheck)
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			validateFunc: func(t *testing.T, report Report) {
+				assert.Equal(t, StatusNotReady, report.Status)
+				assert.Equal(t, PhaseWarmingUp, report.Phase)
+			},
+		},
+		{
+			name: "Draining",
+			setup: func(h *Health) {
+				h.Register("postgres", okCheck)
+				h.SetServing()
+				h.SetDraining()
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			validateFunc: func(t *testing.T, report Report) {
+				assert.Equal(t, StatusNotReady, report.Status)
+				assert.Equal(t, PhaseDraining, report.Phase)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := CreateHealth(50 * time.Millisecond)
+			tt.setup(h)
+
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			rr := httptest.NewRecorder()
+			h.Readyz(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+
+			var report Report
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+			tt.validateFunc(t, report)
+		})
+	}
+}
+
+func TestHealth_Livez(t *testing.T) {
+	h := CreateHealth(time.Second)
+	h.Register("postgres", func(ctx context.Context) error { return errors.New("down") })
+	h.SetDraining()
+
+	req := httptest.NewRequest(http.MethodGet, "/livez", nil)
+	rr := httptest.NewRecorder()
+	h.Livez(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var report Report
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusAlive, report.Status)
+	assert.Empty(t, report.Checks)
+}
@@ -0,0 +1,239 @@
+package i18n
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"github.com/supchaser/wb_l0/internal/utils/money"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed catalogs/*.yaml
+var catalogFiles embed.FS
+
+// Locales lists the supported locales; the first one is the fallback.
+var Locales = []models.LocaleEnum{
+	models.LocaleEN,
+	models.LocaleRU,
+	models.LocaleES,
+	models.LocaleFR,
+	models.LocaleDE,
+	models.LocaleIT,
+	models.LocaleZH,
+	models.LocaleJA,
+	models.LocaleKO,
+	models.LocaleAR,
+}
+
+var currencySymbols = map[models.CurrencyEnum]string{
+	models.CurrencyUSD: "$",
+	models.CurrencyEUR: "€",
+	models.CurrencyRUB: "₽",
+	models.CurrencyGBP: "£",
+	models.CurrencyJPY: "¥",
+	models.CurrencyCNY: "CN¥",
+	models.CurrencyCAD: "CA$",
+	models.CurrencyAUD: "A$",
+	models.CurrencyCHF: "CHF",
+}
+
+var (
+	catalogs = mustLoadCatalogs()
+	matcher  = newMatcher()
+)
+
+// Catalog holds the formats and labels of one locale.
+type Catalog struct {
+	Locale           models.LocaleEnum `yaml:"-"`
+	DateFormat       string            `yaml:"date_format"`
+	DecimalSeparator string            `yaml:"decimal_separator"`
+	GroupSeparator   string            `yaml:"group_separator"`
+	CurrencyFormat   string            `yaml:"currency_format"`
+	ItemStatuses     map[int]string    `yaml:"item_statuses"`
+	DeliveryServices map[string]string `yaml:"delivery_services"`
+	Labels           map[string]string `yaml:"labels"`
+}
+
+func mustLoadCatalogs() map[models.LocaleEnum]*Catalog {
+	result := make(map[models.LocaleEnum]*Catalog, len(Locales))
+	for _, locale := range Locales {
+		catalog, err := loadCatalog(locale)
+		if err != nil {
+			panic(fmt.Sprintf("invalid embedded catalog %s: %v", locale, err))
+		}
+		result[locale] = catalog
+	}
+
+	return result
+}
+
+func loadCatalog(locale models.LocaleEnum) (*Catalog, error) {
+	data, err := catalogFiles.ReadFile(path.Join("catalogs", string(locale)+".yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{Locale: locale}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(catalog); err != nil {
+		return nil, err
+	}
+	if catalog.DateFormat == "" || catalog.DecimalSeparator == "" ||
+		!strings.Contains(catalog.CurrencyFormat, "{amount}") {
+		return nil, fmt.Errorf("date_format, decimal_separator and currency_format with {amount} are required")
+	}
+
+	return catalog, nil
+}
+
+func newMatcher() language.Matcher {
+	tags := make([]language.Tag, 0, len(Locales))
+	for _, locale := range Locales {
+		tags = append(tags, language.Make(string(locale)))
+	}
+
+	return language.NewMatcher(tags)
+}
+
+// For returns the catalog of locale, falling back to English.
+func For(locale models.LocaleEnum) *Catalog {
+	if catalog, ok := catalogs[locale]; ok {
+		return catalog
+	}
+
+	return catalogs[Locales[0]]
+}
+
+// Negotiate picks the presentation locale. An explicit ?locale= value wins and
+// must name a supported language ("order" selects the order's own locale);
+// otherwise the best Accept-Language match is used, and the order's locale
+// when nothing matches.
+func Negotiate(queryLocale, acceptLanguage string, orderLocale models.LocaleEnum) (models.LocaleEnum, error) {
+	if queryLocale != "" {
+		if strings.EqualFold(queryLocale, "order") {
+			return For(orderLocale).Locale, nil
+		}
+
+		tag, err := language.Parse(queryLocale)
+		if err != nil {
+			return "", fmt.Errorf("%w: locale %q", errs.ErrValidation, queryLocale)
+		}
+		base, _ := tag.Base()
+		if _, ok := catalogs[models.LocaleEnum(base.String())]; !ok {
+			return "", fmt.Errorf("%w: unsupported locale %q", errs.ErrValidation, queryLocale)
+		}
+
+		return models.LocaleEnum(base.String()), nil
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err == nil && len(tags) > 0 {
+		if _, index, confidence := matcher.Match(tags...); confidence != language.No {
+			return Locales[index], nil
+		}
+	}
+
+	return For(orderLocale).Locale, nil
+}
+
+func (c *Catalog) FormatDate(t time.Time) string {
+	return t.UTC().Format(c.DateFormat)
+}
+
+// FormatMoney renders m with the locale's separators and currency pattern,
+// e.g. "$1,817.00" for en and "1 817,00 $" for ru.
+func (c *Catalog) FormatMoney(m money.Money) string {
+	exponent, _ := money.Exponent(m.Currency)
+	major := m.Major().FloatString(exponent)
+
+	sign := ""
+	if strings.HasPrefix(major, "-") {
+		sign, major = "-", major[1:]
+	}
+	integer, fraction, _ := strings.Cut(major, ".")
+
+	amount := c.group(integer)
+	if fraction != "" {
+		amount += c.DecimalSeparator + fraction
+	}
+
+	symbol, ok := currencySymbols[m.Currency]
+	if !ok {
+		symbol = string(m.Currency)
+	}
+
+	return sign + strings.NewReplacer("{amount}", amount, "{symbol}", symbol).Replace(c.CurrencyFormat)
+}
+
+// FormatAmount formats an amount in the minor units of currency, or returns
+// an empty string when the currency is not supported.
+func (c *Catalog) FormatAmount(minor int64, currency models.CurrencyEnum) string {
+	m, err := money.New(minor, currency)
+	if err != nil {
+		return ""
+	}
+
+	return c.FormatMoney(m)
+}
+
+func (c *Catalog) group(digits string) string {
+	if c.GroupSeparator == "" || len(digits) <= 3 {
+		return digits
+	}
+
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(c.GroupSeparator)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+
+	return b.String()
+}
+
+// ItemStatus returns the label of an item status code, or the code itself
+// when the catalog has none.
+func (c *Catalog) ItemStatus(status int) string {
+	if label, ok := c.ItemStatuses[status]; ok {
+		return label
+	}
+
+	return strconv.Itoa(status)
+}
+
+// DeliveryService returns the label of a delivery service, or its name when
+// the catalog has none.
+func (c *Catalog) DeliveryService(service string) string {
+	if label, ok := c.DeliveryServices[strings.ToLower(service)]; ok {
+		return label
+	}
+
+	return service
+}
+
+// Label returns the document label for key, falling back to English and then
+// to the key itself.
+func (c *Catalog) Label(key string) string {
+	if label, ok := c.Labels[key]; ok {
+		return label
+	}
+	if label, ok := catalogs[Locales[0]].Labels[key]; ok {
+		return label
+	}
+
+	return key
+}
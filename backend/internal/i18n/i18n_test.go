@@ -0,0 +1,106 @@
+package i18n
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"github.com/supchaser/wb_l0/internal/utils/money"
+)
+
+func TestCatalogsAreComplete(t *testing.T) {
+	reference := For(models.LocaleEN)
+
+	for _, locale := range Locales {
+		t.Run(string(locale), func(t *testing.T) {
+			catalog := For(locale)
+			assert.Equal(t, locale, catalog.Locale)
+			for status := range reference.ItemStatuses {
+				assert.Contains(t, catalog.ItemStatuses, status)
+			}
+			for service := range reference.DeliveryServices {
+				assert.Contains(t, catalog.DeliveryServices, service)
+			}
+			for key := range reference.Labels {
+				assert.Contains(t, catalog.Labels, key)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		acceptLanguage string
+		orderLocale    models.LocaleEnum
+		want           models.LocaleEnum
+		wantErr        bool
+	}{
+		{name: "QueryWins", query: "ja", acceptLanguage: "fr", orderLocale: models.LocaleEN, want: models.LocaleJA},
+		{name: "QueryRegion", query: "ru-RU", orderLocale: models.LocaleEN, want: models.LocaleRU},
+		{name: "QueryOrder", query: "order", acceptLanguage: "fr", orderLocale: models.LocaleKO, want: models.LocaleKO},
+		{name: "QueryUnsupported", query: "pt", orderLocale: models.LocaleEN, wantErr: true},
+		{name: "QueryMalformed", query: "not a locale", orderLocale: models.LocaleEN, wantErr: true},
+		{name: "AcceptLanguageQuality", acceptLanguage: "en;q=0.4, it-IT;q=0.8", orderLocale: models.LocaleRU, want: models.LocaleIT},
+		{name: "AcceptLanguageSkipsUnsupported", acceptLanguage: "pt-BR, zh-CN;q=0.5", orderLocale: models.LocaleRU, want: models.LocaleZH},
+		{name: "AcceptLanguageNoMatch", acceptLanguage: "pt-BR", orderLocale: models.LocaleAR, want: models.LocaleAR},
+		{name: "AcceptLanguageMalformed", acceptLanguage: ";;;", orderLocale: models.LocaleES, want: models.LocaleES},
+		{name: "UnknownOrderLocale", acceptLanguage: "pt", orderLocale: "pt", want: models.LocaleEN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Negotiate(tt.query, tt.acceptLanguage, tt.orderLocale)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, errs.ErrValidation))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		locale models.LocaleEnum
+		money  money.Money
+		want   string
+	}{
+		{locale: models.LocaleEN, money: money.Money{Minor: 181700, Currency: models.CurrencyUSD}, want: "$1,817.00"},
+		{locale: models.LocaleEN, money: money.Money{Minor: -5, Currency: models.CurrencyUSD}, want: "-$0.05"},
+		{locale: models.LocaleRU, money: money.Money{Minor: 123456789, Currency: models.CurrencyRUB}, want: "1 234 567,89 ₽"},
+		{locale: models.LocaleDE, money: money.Money{Minor: 100000, Currency: models.CurrencyEUR}, want: "1.000,00 €"},
+		{locale: models.LocaleJA, money: money.Money{Minor: 1817, Currency: models.CurrencyJPY}, want: "¥1,817"},
+		{locale: models.LocaleFR, money: money.Money{Minor: 99, Currency: models.CurrencyCHF}, want: "0,99 CHF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.locale)+"_"+tt.money.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, For(tt.locale).FormatMoney(tt.money))
+		})
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2021, time.November, 26, 6, 22, 19, 0, time.FixedZone("MSK", 3*60*60))
+
+	assert.Equal(t, "11/26/2021 3:22 AM", For(models.LocaleEN).FormatDate(date))
+	assert.Equal(t, "26.11.2021 03:22", For(models.LocaleRU).FormatDate(date))
+	assert.Equal(t, "2021/11/26 03:22", For(models.LocaleZH).FormatDate(date))
+}
+
+func TestLabels(t *testing.T) {
+	ru := For(models.LocaleRU)
+
+	assert.Equal(t, "Принят", ru.ItemStatus(202))
+	assert.Equal(t, "317", ru.ItemStatus(317))
+	assert.Equal(t, "СДЭК", ru.DeliveryService("CDEK"))
+	assert.Equal(t, "boxberry", ru.DeliveryService("boxberry"))
+	assert.Equal(t, "Счёт", ru.Label("invoice"))
+	assert.Equal(t, "unknown_label", ru.Label("unknown_label"))
+}
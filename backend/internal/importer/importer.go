@@ -0,0 +1,210 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/kafka/producer"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBatchSize        = 500
+	defaultProgressInterval = 5 * time.Second
+)
+
+// Sink stores a batch of valid orders. It returns one entry per order, set
+// when that order was refused, or an error when the batch failed as a whole.
+// consumer.Store is a Sink.
+type Sink interface {
+	Save(ctx context.Context, orders []models.OrderRequest) ([]error, error)
+}
+
+type producerSink struct {
+	producer *producer.Producer
+	topic    string
+}
+
+// ProducerSink publishes orders to topic. The producer does not report
+// which orders of a failed batch were lost, so any failure fails the batch.
+func ProducerSink(p *producer.Producer, topic string) Sink {
+	return &producerSink{producer: p, topic: topic}
+}
+
+func (s *producerSink) Save(ctx context.Context, orders []models.OrderRequest) ([]error, error) {
+	return nil, s.producer.BatchProduce(ctx, orders, s.topic)
+}
+
+type Options struct {
+	BatchSize        int
+	ProgressInterval time.Duration
+	// DryRun validates every record without passing it to the sink.
+	DryRun bool
+}
+
+type Stats struct {
+	Read     int `json:"read"`
+	Valid    int `json:"valid"`
+	Imported int `json:"imported"`
+	Rejected int `json:"rejected"`
+}
+
+// Reject is written as a line of the rejects file for every record that
+// was not imported.
+type Reject struct {
+	Position   string                    `json:"position"`
+	OrderUID   string                    `json:"order_uid,omitempty"`
+	Error      string                    `json:"error"`
+	Violations validate.ValidationErrors `json:"violations,omitempty"`
+	Raw        any                       `json:"raw,omitempty"`
+}
+
+type Importer struct {
+	sink         Sink
+	rejects      *json.Encoder
+	options      Options
+	stats        Stats
+	batch        []Record
+	started      time.Time
+	lastProgress time.Time
+}
+
+func CreateImporter(sink Sink, rejects io.Writer, options Options) *Importer {
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultBatchSize
+	}
+	if options.ProgressInterval <= 0 {
+		options.ProgressInterval = defaultProgressInterval
+	}
+
+	return &Importer{
+		sink:    sink,
+		rejects: json.NewEncoder(rejects),
+		options: options,
+	}
+}
+
+// Run imports every record read from path. It stops at the first read or
+// sink error, and returns the stats gathered so far either way.
+func (im *Importer) Run(ctx context.Context, path string, format Format) (Stats, error) {
+	im.started = time.Now()
+	im.lastProgress = im.started
+
+	err := Read(path, format, func(record Record) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return im.add(ctx, record)
+	})
+	if err == nil {
+		err = im.flush(ctx)
+	}
+
+	im.progress("import finished")
+
+	return im.stats, err
+}
+
+func (im *Importer) add(ctx context.Context, record Record) error {
+	im.stats.Read++
+
+	if record.Err != nil {
+		return im.reject(record, record.Err)
+	}
+	if err := validate.ValidateOrderRequest(record.Order); err != nil {
+		return im.reject(record, err)
+	}
+	im.stats.Valid++
+
+	if !im.options.DryRun {
+		im.batch = append(im.batch, record)
+		if len(im.batch) >= im.options.BatchSize {
+			if err := im.flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if time.Since(im.lastProgress) >= im.options.ProgressInterval {
+		im.progress("import progress")
+	}
+
+	return nil
+}
+
+func (im *Importer) flush(ctx context.Context) error {
+	if len(im.batch) == 0 {
+		return nil
+	}
+
+	orders := make([]models.OrderRequest, len(im.batch))
+	for i, record := range im.batch {
+		orders[i] = *record.Order
+	}
+
+	refused, err := im.sink.Save(ctx, orders)
+	if err != nil {
+		return fmt.Errorf("failed to import batch ending at %s: %w", im.batch[len(im.batch)-1].Position, err)
+	}
+
+	for i, record := range im.batch {
+		if i < len(refused) && refused[i] != nil {
+			if err := im.reject(record, refused[i]); err != nil {
+				return err
+			}
+			continue
+		}
+		im.stats.Imported++
+	}
+	im.batch = im.batch[:0]
+
+	return nil
+}
+
+func (im *Importer) reject(record Record, cause error) error {
+	im.stats.Rejected++
+
+	reject := Reject{Position: record.Position, Error: cause.Error()}
+	if record.Order != nil {
+		reject.OrderUID = record.Order.OrderUID
+	}
+	if violations, ok := validate.AsValidationErrors(cause); ok {
+		reject.Violations = violations
+	}
+	if json.Valid(record.Raw) {
+		reject.Raw = json.RawMessage(record.Raw)
+	} else if len(record.Raw) > 0 {
+		reject.Raw = string(record.Raw)
+	}
+
+	logger.Debug("order rejected",
+		zap.String("position", record.Position),
+		zap.String("order_uid", reject.OrderUID),
+		zap.Error(cause))
+
+	if err := im.rejects.Encode(reject); err != nil {
+		return fmt.Errorf("failed to write reject: %w", err)
+	}
+
+	return nil
+}
+
+func (im *Importer) progress(message string) {
+	im.lastProgress = time.Now()
+	elapsed := im.lastProgress.Sub(im.started)
+
+	logger.Info(message,
+		zap.Int("read", im.stats.Read),
+		zap.Int("valid", im.stats.Valid),
+		zap.Int("imported", im.stats.Imported),
+		zap.Int("rejected", im.stats.Rejected),
+		zap.Bool("dry_run", im.options.DryRun),
+		zap.Duration("elapsed", elapsed),
+		zap.Float64("records_per_second", float64(im.stats.Read)/elapsed.Seconds()))
+}
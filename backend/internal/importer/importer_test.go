@@ -0,0 +1,363 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitTestLogger()
+	m.Run()
+}
+
+func validOrder(orderUID string) models.OrderRequest {
+	return models.OrderRequest{
+		OrderUID:        orderUID,
+		TrackNumber:     "WBILMTESTTRACK",
+		Entry:           "WBIL",
+		Locale:          models.LocaleEN,
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		Shardkey:        "9",
+		SmID:            99,
+		DateCreated:     time.Date(2021, time.November, 26, 6, 22, 19, 0, time.UTC),
+		OofShard:        "1",
+		Delivery: models.DeliveryRequest{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.PaymentRequest{
+			Transaction:  orderUID,
+			Currency:     models.CurrencyUSD,
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDt:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []models.ItemRequest{{
+			ChrtID:      9934930,
+			TrackNumber: "WBILMTESTTRACK",
+			Price:       453,
+			Rid:         "ab4219087a764ae0btest",
+			Name:        "Mascaras",
+			Sale:        30,
+			Size:        "0",
+			TotalPrice:  317,
+			NmID:        2389212,
+			Brand:       "Vivienne Sabo",
+			Status:      202,
+		}},
+	}
+}
+
+func marshal(t *testing.T, v any, indent bool) string {
+	t.Helper()
+
+	var (
+		data []byte
+		err  error
+	)
+	if indent {
+		data, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		data, err = json.Marshal(v)
+	}
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func writeFile(t *testing.T, path, content string) string {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	return path
+}
+
+func readAll(t *testing.T, path string, format Format) []Record {
+	t.Helper()
+
+	var records []Record
+	require.NoError(t, Read(path, format, func(record Record) error {
+		records = append(records, record)
+		return nil
+	}))
+
+	return records
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{in: "", want: FormatAuto},
+		{in: "NDJSON", want: FormatNDJSON},
+		{in: "json", want: FormatJSON},
+		{in: "dir", want: FormatDir},
+		{in: "csv", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFormat(tt.in)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, errs.ErrValidation))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	first, second := validOrder("first"), validOrder("second")
+
+	ndjson := writeFile(t, filepath.Join(dir, "orders.ndjson"),
+		marshal(t, first, false)+"\n\n{not json}\n"+marshal(t, second, false))
+	array := writeFile(t, filepath.Join(dir, "orders.json"),
+		marshal(t, []models.OrderRequest{first, second}, true))
+	single := writeFile(t, filepath.Join(dir, "model.json"), marshal(t, first, true))
+	stream := writeFile(t, filepath.Join(dir, "stream.txt"),
+		marshal(t, first, true)+"\n"+marshal(t, second, true))
+	empty := writeFile(t, filepath.Join(dir, "empty.json"), "  \n")
+
+	tree := filepath.Join(dir, "tree")
+	writeFile(t, filepath.Join(tree, "a", "model.json"), marshal(t, first, true))
+	writeFile(t, filepath.Join(tree, "b", "model.json"), marshal(t, second, true))
+	writeFile(t, filepath.Join(tree, "b", "notes.txt"), "not an order")
+
+	tests := []struct {
+		name      string
+		path      string
+		format    Format
+		positions []string
+		orders    []string
+	}{
+		{
+			name:      "ndjson keeps going after a bad line",
+			path:      ndjson,
+			format:    FormatAuto,
+			positions: []string{ndjson + ":1", ndjson + ":3", ndjson + ":4"},
+			orders:    []string{"first", "", "second"},
+		},
+		{
+			name:      "json array",
+			path:      array,
+			format:    FormatAuto,
+			positions: []string{array + "[0]", array + "[1]"},
+			orders:    []string{"first", "second"},
+		},
+		{
+			name:      "single object",
+			path:      single,
+			format:    FormatAuto,
+			positions: []string{single},
+			orders:    []string{"first"},
+		},
+		{
+			name:      "object stream",
+			path:      stream,
+			format:    FormatJSON,
+			positions: []string{stream + "[0]", stream + "[1]"},
+			orders:    []string{"first", "second"},
+		},
+		{
+			name:   "empty file",
+			path:   empty,
+			format: FormatAuto,
+		},
+		{
+			name:   "directory",
+			path:   tree,
+			format: FormatAuto,
+			positions: []string{
+				filepath.Join(tree, "a", "model.json"),
+				filepath.Join(tree, "b", "model.json"),
+			},
+			orders: []string{"first", "second"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := readAll(t, tt.path, tt.format)
+
+			var positions, orders []string
+			for _, record := range records {
+				positions = append(positions, record.Position)
+				if record.Err != nil {
+					orders = append(orders, "")
+					continue
+				}
+				orders = append(orders, record.Order.OrderUID)
+			}
+
+			assert.Equal(t, tt.positions, positions)
+			assert.Equal(t, tt.orders, orders)
+		})
+	}
+}
+
+func TestRead_SyntaxErrorStopsJSON(t *testing.T) {
+	path := writeFile(t, filepath.Join(t.TempDir(), "broken.json"),
+		"["+marshal(t, validOrder("first"), false)+", {oops}]")
+
+	var records []Record
+	err := Read(path, FormatAuto, func(record Record) error {
+		records = append(records, record)
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.Len(t, records, 1)
+}
+
+type fakeSink struct {
+	batches [][]string
+	refuse  map[string]error
+	err     error
+}
+
+func (s *fakeSink) Save(_ context.Context, orders []models.OrderRequest) ([]error, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	var (
+		uids    []string
+		refused = make([]error, len(orders))
+	)
+	for i, order := range orders {
+		uids = append(uids, order.OrderUID)
+		refused[i] = s.refuse[order.OrderUID]
+	}
+	s.batches = append(s.batches, uids)
+
+	return refused, nil
+}
+
+func writeOrders(t *testing.T, lines ...string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(line + "\n")
+	}
+
+	return writeFile(t, filepath.Join(t.TempDir(), "orders.ndjson"), buf.String())
+}
+
+func readRejects(t *testing.T, data []byte) []Reject {
+	t.Helper()
+
+	var rejects []Reject
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var reject Reject
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &reject))
+		rejects = append(rejects, reject)
+	}
+
+	return rejects
+}
+
+func TestImporter_Run(t *testing.T) {
+	invalid := validOrder("invalid")
+	invalid.Locale = "xx"
+
+	var lines []string
+	for i := range 5 {
+		lines = append(lines, marshal(t, validOrder(fmt.Sprintf("order-%d", i)), false))
+	}
+	lines = append(lines, marshal(t, invalid, false), "{broken")
+	path := writeOrders(t, lines...)
+
+	sink := &fakeSink{refuse: map[string]error{"order-3": errs.ErrStaleVersion}}
+	var rejects bytes.Buffer
+
+	stats, err := CreateImporter(sink, &rejects, Options{BatchSize: 2}).Run(context.Background(), path, FormatAuto)
+	require.NoError(t, err)
+
+	assert.Equal(t, Stats{Read: 7, Valid: 5, Imported: 4, Rejected: 3}, stats)
+	assert.Equal(t, [][]string{{"order-0", "order-1"}, {"order-2", "order-3"}, {"order-4"}}, sink.batches)
+
+	got := readRejects(t, rejects.Bytes())
+	require.Len(t, got, 3)
+
+	assert.Equal(t, path+":4", got[0].Position)
+	assert.Equal(t, "order-3", got[0].OrderUID)
+	assert.Equal(t, errs.ErrStaleVersion.Error(), got[0].Error)
+
+	assert.Equal(t, path+":6", got[1].Position)
+	assert.Equal(t, "invalid", got[1].OrderUID)
+	require.NotEmpty(t, got[1].Violations)
+	assert.Equal(t, validate.CodeEnum, got[1].Violations[0].Code)
+	assert.Equal(t, "invalid", got[1].Raw.(map[string]any)["order_uid"])
+
+	assert.Equal(t, path+":7", got[2].Position)
+	assert.Equal(t, "{broken", got[2].Raw)
+}
+
+func TestImporter_Run_DryRun(t *testing.T) {
+	path := writeOrders(t, marshal(t, validOrder("first"), false), "{broken")
+
+	sink := &fakeSink{}
+	var rejects bytes.Buffer
+
+	stats, err := CreateImporter(sink, &rejects, Options{DryRun: true}).Run(context.Background(), path, FormatAuto)
+	require.NoError(t, err)
+
+	assert.Equal(t, Stats{Read: 2, Valid: 1, Rejected: 1}, stats)
+	assert.Empty(t, sink.batches)
+	assert.Len(t, readRejects(t, rejects.Bytes()), 1)
+}
+
+func TestImporter_Run_SinkError(t *testing.T) {
+	path := writeOrders(t, marshal(t, validOrder("first"), false))
+
+	sink := &fakeSink{err: errors.New("broker unavailable")}
+	var rejects bytes.Buffer
+
+	stats, err := CreateImporter(sink, &rejects, Options{}).Run(context.Background(), path, FormatAuto)
+	assert.ErrorContains(t, err, "broker unavailable")
+	assert.Equal(t, Stats{Read: 1, Valid: 1}, stats)
+}
+
+func TestImporter_Run_Canceled(t *testing.T) {
+	path := writeOrders(t, marshal(t, validOrder("first"), false))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	stats, err := CreateImporter(&fakeSink{}, &bytes.Buffer{}, Options{}).Run(ctx, path, FormatAuto)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, Stats{}, stats)
+}
@@ -0,0 +1,208 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+type Format string
+
+const (
+	FormatAuto   Format = "auto"
+	FormatNDJSON Format = "ndjson"
+	FormatJSON   Format = "json"
+	FormatDir    Format = "dir"
+)
+
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(value)); format {
+	case "":
+		return FormatAuto, nil
+	case FormatAuto, FormatNDJSON, FormatJSON, FormatDir:
+		return format, nil
+	}
+
+	return "", fmt.Errorf("%w: unsupported import format %q", errs.ErrValidation, value)
+}
+
+// Record is one order read from an import source. Position locates it in
+// the input as file:line for NDJSON and file[index] for JSON arrays. Err is
+// set when the raw value could not be decoded into an order.
+type Record struct {
+	Position string
+	Raw      []byte
+	Order    *models.OrderRequest
+	Err      error
+}
+
+// Read calls fn with every record found at path. FormatAuto reads
+// directories as FormatDir, .ndjson and .jsonl files as NDJSON and any other
+// file as JSON. A directory is walked for .json files, each holding a single
+// order like model.json, or an array of them.
+func Read(path string, format Format, fn func(Record) error) error {
+	if format == FormatAuto {
+		detected, err := detectFormat(path)
+		if err != nil {
+			return err
+		}
+		format = detected
+	}
+
+	switch format {
+	case FormatNDJSON:
+		return readFile(path, readNDJSON, fn)
+	case FormatJSON:
+		return readFile(path, readJSON, fn)
+	case FormatDir:
+		return readDir(path, fn)
+	}
+
+	return fmt.Errorf("%w: unsupported import format %q", errs.ErrValidation, format)
+}
+
+func detectFormat(path string) (Format, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return FormatDir, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, nil
+	}
+
+	return FormatJSON, nil
+}
+
+func readFile(path string, read func(string, io.Reader, func(Record) error) error, fn func(Record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return read(path, file, fn)
+}
+
+func readDir(root string, fn func(Record) error) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(path), ".json") {
+			return nil
+		}
+
+		return readFile(path, readJSON, fn)
+	})
+}
+
+// readNDJSON reads one order per line. A line that does not decode is
+// reported as a record with Err, and reading goes on with the next line.
+func readNDJSON(path string, r io.Reader, fn func(Record) error) error {
+	reader := bufio.NewReader(r)
+
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read %s:%d: %w", path, line, err)
+		}
+
+		if raw := bytes.TrimSpace(data); len(raw) > 0 {
+			if err := fn(decodeRecord(fmt.Sprintf("%s:%d", path, line), raw)); err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+	}
+}
+
+// readJSON reads either an array of orders or a sequence of order objects.
+// Unlike NDJSON, a syntax error leaves nothing to resume from, so it ends
+// the read.
+func readJSON(path string, r io.Reader, fn func(Record) error) error {
+	reader := bufio.NewReader(r)
+	first, err := firstByte(reader)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	decoder := json.NewDecoder(reader)
+	if first == '[' {
+		if _, err := decoder.Token(); err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	for i := 0; decoder.More(); i++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return fmt.Errorf("failed to read %s[%d]: %w", path, i, err)
+		}
+
+		position := fmt.Sprintf("%s[%d]", path, i)
+		if first != '[' && i == 0 && !decoder.More() {
+			position = path
+		}
+		if err := fn(decodeRecord(position, raw)); err != nil {
+			return err
+		}
+	}
+
+	if first == '[' {
+		if _, err := decoder.Token(); err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func firstByte(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !isSpace(b) {
+			return b, r.UnreadByte()
+		}
+	}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+func decodeRecord(position string, raw []byte) Record {
+	record := Record{Position: position, Raw: raw}
+
+	var order models.OrderRequest
+	if err := json.Unmarshal(raw, &order); err != nil {
+		record.Err = fmt.Errorf("failed to decode order: %w", err)
+		return record
+	}
+	record.Order = &order
+
+	return record
+}
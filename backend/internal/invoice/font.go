@@ -0,0 +1,107 @@
+package invoice
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Font is a TrueType font embedded into PDFs for scripts the built-in
+// Helvetica cannot show, e.g. Cyrillic with DejaVu Sans or CJK with Noto Sans
+// CJK. Glyphs are placed one per rune: there is no shaping or bidi, so Arabic
+// comes out as isolated letters in logical order.
+type Font struct {
+	name       string
+	data       []byte
+	font       *sfnt.Font
+	unitsPerEm fixed.Int26_6
+	bbox       [4]int
+	ascent     int
+	descent    int
+}
+
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font: %w", err)
+	}
+
+	f, err := ParseFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font %s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+func ParseFont(data []byte) (*Font, error) {
+	parsed, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf sfnt.Buffer
+	name, err := parsed.Name(&buf, sfnt.NameIDPostScript)
+	if err != nil || name == "" {
+		name = "EmbeddedFont"
+	}
+
+	f := &Font{
+		name:       strings.Map(postScriptRune, name),
+		data:       data,
+		font:       parsed,
+		unitsPerEm: fixed.Int26_6(parsed.UnitsPerEm()),
+	}
+
+	bounds, err := parsed.Bounds(&buf, f.unitsPerEm, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+	f.bbox = [4]int{
+		f.scale(bounds.Min.X), f.scale(-bounds.Max.Y),
+		f.scale(bounds.Max.X), f.scale(-bounds.Min.Y),
+	}
+
+	metrics, err := parsed.Metrics(&buf, f.unitsPerEm, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+	f.ascent = f.scale(metrics.Ascent)
+	f.descent = -f.scale(metrics.Descent)
+
+	return f, nil
+}
+
+// glyph returns the glyph index of r and its advance in 1/1000 em. Missing
+// runes map to glyph 0, the font's .notdef box.
+func (f *Font) glyph(buf *sfnt.Buffer, r rune) (uint16, int) {
+	index, err := f.font.GlyphIndex(buf, r)
+	if err != nil {
+		index = 0
+	}
+
+	advance, err := f.font.GlyphAdvance(buf, index, f.unitsPerEm, font.HintingNone)
+	if err != nil {
+		return uint16(index), 0
+	}
+
+	return uint16(index), f.scale(advance)
+}
+
+// scale converts font units, read at a ppem equal to units per em, into
+// 1/1000 em.
+func (f *Font) scale(v fixed.Int26_6) int {
+	return int(int64(v) * 1000 / int64(f.unitsPerEm))
+}
+
+func postScriptRune(r rune) rune {
+	if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%#", r) {
+		return -1
+	}
+
+	return r
+}
@@ -0,0 +1,16 @@
+package invoice
+
+import (
+	"embed"
+	"html/template"
+	"io"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+var htmlTemplate = template.Must(template.ParseFS(templateFiles, "templates/document.html.tmpl"))
+
+func RenderHTML(w io.Writer, doc *Document) error {
+	return htmlTemplate.ExecuteTemplate(w, "document.html.tmpl", doc)
+}
@@ -0,0 +1,167 @@
+package invoice
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/i18n"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+type Kind string
+
+const (
+	KindInvoice     Kind = "invoice"
+	KindPackingSlip Kind = "packing-slip"
+)
+
+func ParseKind(kind string) (Kind, error) {
+	switch Kind(strings.ToLower(kind)) {
+	case "", KindInvoice:
+		return KindInvoice, nil
+	case KindPackingSlip:
+		return KindPackingSlip, nil
+	}
+
+	return "", fmt.Errorf("%w: document type %q", errs.ErrValidation, kind)
+}
+
+// Document is an order prepared for printing: every value is already
+// formatted for the document locale. A packing slip carries no prices.
+type Document struct {
+	Kind            Kind
+	Locale          models.LocaleEnum
+	RightToLeft     bool
+	Labels          map[string]string
+	Title           string
+	OrderUID        string
+	TrackNumber     string
+	DateCreated     string
+	DeliveryService string
+	ShipTo          []string
+	Payment         *Payment
+	Items           []Item
+}
+
+type Payment struct {
+	Transaction  string
+	Provider     string
+	Bank         string
+	PaidAt       string
+	GoodsTotal   string
+	DeliveryCost string
+	CustomFee    string
+	Amount       string
+}
+
+type Item struct {
+	Name   string
+	Brand  string
+	Size   string
+	Status string
+	Price  string
+	Sale   string
+	Total  string
+}
+
+func (d *Document) ShowPrices() bool {
+	return d.Kind == KindInvoice
+}
+
+// Build formats order for catalog's locale.
+func Build(order *models.Order, kind Kind, catalog *i18n.Catalog) *Document {
+	doc := &Document{
+		Kind:            kind,
+		Locale:          catalog.Locale,
+		RightToLeft:     catalog.Locale == models.LocaleAR,
+		Labels:          labels(catalog),
+		OrderUID:        order.OrderUID,
+		TrackNumber:     order.TrackNumber,
+		DateCreated:     catalog.FormatDate(order.DateCreated),
+		DeliveryService: catalog.DeliveryService(order.DeliveryService),
+	}
+	doc.Title = doc.Labels[labelKey(kind)]
+
+	if delivery := order.Delivery; delivery != nil {
+		doc.ShipTo = nonEmpty(
+			delivery.Name,
+			delivery.Address,
+			strings.TrimSpace(delivery.Zip+" "+delivery.City),
+			delivery.Region,
+			delivery.Phone,
+			delivery.Email,
+		)
+	}
+
+	var currency models.CurrencyEnum
+	if order.Payment != nil {
+		currency = order.Payment.Currency
+	}
+	amount := func(minor int) string {
+		return catalog.FormatAmount(int64(minor), currency)
+	}
+
+	if payment := order.Payment; payment != nil && kind == KindInvoice {
+		doc.Payment = &Payment{
+			Transaction:  payment.Transaction,
+			Provider:     payment.Provider,
+			Bank:         payment.Bank,
+			PaidAt:       catalog.FormatDate(time.Unix(int64(payment.PaymentDt), 0)),
+			GoodsTotal:   amount(payment.GoodsTotal),
+			DeliveryCost: amount(payment.DeliveryCost),
+			CustomFee:    amount(payment.CustomFee),
+			Amount:       amount(payment.Amount),
+		}
+	}
+
+	for _, item := range order.Items {
+		line := Item{
+			Name:   item.Name,
+			Brand:  item.Brand,
+			Size:   item.Size,
+			Status: catalog.ItemStatus(item.Status),
+		}
+		if kind == KindInvoice {
+			line.Price = amount(item.Price)
+			line.Total = amount(item.TotalPrice)
+			if item.Sale > 0 {
+				line.Sale = fmt.Sprintf("%d%%", item.Sale)
+			}
+		}
+		doc.Items = append(doc.Items, line)
+	}
+
+	return doc
+}
+
+func labelKey(kind Kind) string {
+	if kind == KindPackingSlip {
+		return "packing_slip"
+	}
+
+	return "invoice"
+}
+
+func labels(catalog *i18n.Catalog) map[string]string {
+	reference := i18n.For(models.LocaleEN).Labels
+
+	result := make(map[string]string, len(reference))
+	for key := range reference {
+		result[key] = catalog.Label(key)
+	}
+
+	return result
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
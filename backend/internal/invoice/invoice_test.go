@@ -0,0 +1,232 @@
+package invoice
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/i18n"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func testOrder() *models.Order {
+	return &models.Order{
+		OrderUID:        "b563feb7b2b84b6test",
+		TrackNumber:     "WBILMTESTTRACK",
+		Locale:          models.LocaleRU,
+		DeliveryService: "russian-post",
+		DateCreated:     time.Date(2021, time.November, 26, 6, 22, 19, 0, time.UTC),
+		Delivery: &models.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: &models.Payment{
+			Transaction:  "b563feb7b2b84b6test",
+			Currency:     models.CurrencyUSD,
+			Provider:     "wbpay",
+			Amount:       181700,
+			PaymentDt:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 150000,
+			GoodsTotal:   31700,
+		},
+		Items: []models.Item{
+			{ChrtID: 9934930, Price: 45300, Name: "Mascaras <b>", Sale: 30, Size: "0", TotalPrice: 31700, Brand: "Vivienne Sabo", Status: 202},
+		},
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	kind, err := ParseKind("")
+	assert.NoError(t, err)
+	assert.Equal(t, KindInvoice, kind)
+
+	kind, err = ParseKind("Packing-Slip")
+	assert.NoError(t, err)
+	assert.Equal(t, KindPackingSlip, kind)
+
+	_, err = ParseKind("receipt")
+	assert.True(t, errors.Is(err, errs.ErrValidation))
+}
+
+func TestBuild(t *testing.T) {
+	doc := Build(testOrder(), KindInvoice, i18n.For(models.LocaleRU))
+
+	assert.Equal(t, "Счёт", doc.Title)
+	assert.Equal(t, "26.11.2021 06:22", doc.DateCreated)
+	assert.Equal(t, "Почта России", doc.DeliveryService)
+	assert.Equal(t, []string{"Test Testov", "Ploshad Mira 15", "2639809 Kiryat Mozkin", "Kraiot", "+9720000000", "test@gmail.com"}, doc.ShipTo)
+	assert.Equal(t, "1 817,00 $", doc.Payment.Amount)
+	assert.Equal(t, "0,00 $", doc.Payment.CustomFee)
+	assert.Equal(t, Item{
+		Name:   "Mascaras <b>",
+		Brand:  "Vivienne Sabo",
+		Size:   "0",
+		Status: "Принят",
+		Price:  "453,00 $",
+		Sale:   "30%",
+		Total:  "317,00 $",
+	}, doc.Items[0])
+
+	slip := Build(testOrder(), KindPackingSlip, i18n.For(models.LocaleEN))
+	assert.Equal(t, "Packing slip", slip.Title)
+	assert.Nil(t, slip.Payment)
+	assert.Empty(t, slip.Items[0].Price)
+	assert.Empty(t, slip.Items[0].Sale)
+}
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       Kind
+		locale     models.LocaleEnum
+		contains   []string
+		notContain []string
+	}{
+		{
+			name:       "Invoice",
+			kind:       KindInvoice,
+			locale:     models.LocaleDE,
+			contains:   []string{`<html lang="de">`, "Rechnung", "Mascaras &lt;b&gt;", "1.817,00 $", "30%", "Angenommen"},
+			notContain: []string{"Mascaras <b>"},
+		},
+		{
+			name:       "PackingSlip",
+			kind:       KindPackingSlip,
+			locale:     models.LocaleEN,
+			contains:   []string{"Packing slip", "Ship to", "Kiryat Mozkin"},
+			notContain: []string{"$", "Amount paid"},
+		},
+		{
+			name:     "RightToLeft",
+			kind:     KindInvoice,
+			locale:   models.LocaleAR,
+			contains: []string{`<html lang="ar" dir="rtl">`, "فاتورة"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, RenderHTML(&buf, Build(testOrder(), tt.kind, i18n.For(tt.locale))))
+
+			for _, s := range tt.contains {
+				assert.Contains(t, buf.String(), s)
+			}
+			for _, s := range tt.notContain {
+				assert.NotContains(t, buf.String(), s)
+			}
+		})
+	}
+}
+
+func TestRenderPDF_Helvetica(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, RenderPDF(&buf, Build(testOrder(), KindInvoice, i18n.For(models.LocaleFR)), nil))
+
+	pdf := buf.Bytes()
+	assertValidPDF(t, pdf)
+	assert.Contains(t, string(pdf), "/BaseFont /Helvetica ")
+	assert.Contains(t, string(pdf), "(Facture)")
+	assert.Contains(t, string(pdf), "(1 817,00 $)")
+}
+
+func TestRenderPDF_UnsupportedScript(t *testing.T) {
+	font, err := ParseFont(goregular.TTF)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		locale models.LocaleEnum
+		font   *Font
+	}{
+		{name: "cyrillic in helvetica", locale: models.LocaleRU},
+		{name: "cjk in a latin and cyrillic font", locale: models.LocaleZH, font: font},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := RenderPDF(&buf, Build(testOrder(), KindInvoice, i18n.For(tt.locale)), tt.font)
+
+			assert.ErrorIs(t, err, errs.ErrUnsupportedScript)
+			assert.Zero(t, buf.Len())
+		})
+	}
+}
+
+func TestRenderPDF_ManyItemsSpanPages(t *testing.T) {
+	order := testOrder()
+	for i := 0; i < 120; i++ {
+		order.Items = append(order.Items, order.Items[0])
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderPDF(&buf, Build(order, KindInvoice, i18n.For(models.LocaleEN)), nil))
+
+	assertValidPDF(t, buf.Bytes())
+	count := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(buf.Bytes())
+	require.NotNil(t, count)
+	pages, _ := strconv.Atoi(string(count[1]))
+	assert.Greater(t, pages, 1)
+}
+
+func TestRenderPDF_EmbeddedFont(t *testing.T) {
+	font, err := ParseFont(goregular.TTF)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderPDF(&buf, Build(testOrder(), KindInvoice, i18n.For(models.LocaleRU)), font))
+
+	pdf := buf.Bytes()
+	assertValidPDF(t, pdf)
+	assert.Contains(t, string(pdf), "/Subtype /CIDFontType2")
+	assert.Contains(t, string(pdf), "/FontFile2")
+
+	// The ToUnicode map lets readers recover "Счёт" from the glyph indexes.
+	for _, r := range "Счёт" {
+		assert.Regexp(t, fmt.Sprintf(`<[0-9A-F]{4}> <%04X>`, r), string(pdf))
+	}
+
+	stream := regexp.MustCompile(`(?s)/Length1 (\d+) /Filter /FlateDecode >>\nstream\n(.*?)\nendstream`).FindSubmatch(pdf)
+	require.NotNil(t, stream)
+	reader, err := zlib.NewReader(bytes.NewReader(stream[2]))
+	require.NoError(t, err)
+	embedded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, goregular.TTF, embedded)
+}
+
+func assertValidPDF(t *testing.T, pdf []byte) {
+	t.Helper()
+
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.7\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	require.NotNil(t, startxref)
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	require.True(t, bytes.HasPrefix(pdf[xref:], []byte("xref\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		assert.True(t, bytes.HasPrefix(pdf[offset:], []byte(strconv.Itoa(i+1)+" 0 obj\n")), "object %d offset", i+1)
+	}
+}
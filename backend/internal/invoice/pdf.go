@@ -0,0 +1,507 @@
+package invoice
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 48
+
+	bodySize  = 10
+	tableSize = 9
+	lineGap   = 14
+)
+
+// RenderPDF writes doc as a PDF 1.7 file. Without a font the built-in
+// Helvetica is used, which covers Windows-1252 only; with a font the whole
+// file is embedded so any script it covers can be printed. A document with
+// text the font cannot print fails with errs.ErrUnsupportedScript rather than
+// printing it as placeholders.
+func RenderPDF(w io.Writer, doc *Document, font *Font) error {
+	l := &pdfLayout{text: newPDFText(font)}
+	l.newPage()
+
+	l.line(doc.Title, 0, 20, true)
+	l.y -= 6
+	l.field(doc.Labels["order"], doc.OrderUID)
+	l.field(doc.Labels["date"], doc.DateCreated)
+	l.field(doc.Labels["track_number"], doc.TrackNumber)
+	l.field(doc.Labels["delivery_service"], doc.DeliveryService)
+
+	if len(doc.ShipTo) > 0 {
+		l.heading(doc.Labels["ship_to"])
+		for _, line := range doc.ShipTo {
+			l.line(line, 0, bodySize, false)
+		}
+	}
+
+	l.heading(doc.Labels["item"])
+	l.items(doc)
+
+	if p := doc.Payment; p != nil {
+		l.heading(doc.Labels["payment"])
+		l.field(doc.Labels["transaction"], p.Transaction)
+		l.field(doc.Labels["provider"], p.Provider)
+		l.field(doc.Labels["bank"], p.Bank)
+		l.field(doc.Labels["paid_at"], p.PaidAt)
+		l.y -= 6
+		l.total(doc.Labels["goods_total"], p.GoodsTotal, false)
+		l.total(doc.Labels["delivery_cost"], p.DeliveryCost, false)
+		l.total(doc.Labels["custom_fee"], p.CustomFee, false)
+		l.rule(340, pageWidth-margin, l.y+lineGap-3)
+		l.total(doc.Labels["amount"], p.Amount, true)
+	}
+
+	if r := l.text.unsupported; r != 0 {
+		return fmt.Errorf("%w: %q is not covered by the invoice font", errs.ErrUnsupportedScript, r)
+	}
+
+	return l.write(w)
+}
+
+type column struct {
+	key   string
+	x     float64
+	width float64
+	right bool
+	value func(Item) string
+}
+
+func itemColumns(showPrices bool) []column {
+	name := func(i Item) string { return i.Name }
+	brand := func(i Item) string { return i.Brand }
+	size := func(i Item) string { return i.Size }
+	status := func(i Item) string { return i.Status }
+
+	if !showPrices {
+		return []column{
+			{key: "item", x: margin, width: 210, value: name},
+			{key: "brand", x: 264, width: 114, value: brand},
+			{key: "size", x: 384, width: 44, value: size},
+			{key: "status", x: 434, width: 113, value: status},
+		}
+	}
+
+	return []column{
+		{key: "item", x: margin, width: 140, value: name},
+		{key: "brand", x: 192, width: 70, value: brand},
+		{key: "size", x: 266, width: 26, value: size},
+		{key: "status", x: 296, width: 66, value: status},
+		{key: "price", x: 366, width: 74, right: true, value: func(i Item) string { return i.Price }},
+		{key: "sale", x: 444, width: 34, right: true, value: func(i Item) string { return i.Sale }},
+		{key: "total", x: 482, width: 65, right: true, value: func(i Item) string { return i.Total }},
+	}
+}
+
+type pdfLayout struct {
+	text  *pdfText
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func (l *pdfLayout) newPage() {
+	l.pages = append(l.pages, new(bytes.Buffer))
+	l.y = pageHeight - margin
+}
+
+func (l *pdfLayout) need(height float64) {
+	if l.y-height < margin {
+		l.newPage()
+	}
+}
+
+func (l *pdfLayout) show(x, y, size float64, bold bool, s string) {
+	if s == "" {
+		return
+	}
+
+	page := l.pages[len(l.pages)-1]
+	fontName := "F1"
+	if bold {
+		fontName = "F2"
+	}
+	fmt.Fprintf(page, "BT /%s %g Tf %s%.2f %.2f Td %s Tj ET\n",
+		fontName, size, l.text.boldOperators(bold), x, y, l.text.operand(s))
+}
+
+func (l *pdfLayout) rule(x1, x2, y float64) {
+	fmt.Fprintf(l.pages[len(l.pages)-1], "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y, x2, y)
+}
+
+func (l *pdfLayout) line(s string, indent, size float64, bold bool) {
+	l.need(size + 4)
+	l.y -= size + 4
+	l.show(margin+indent, l.y, size, bold, l.text.fit(s, pageWidth-2*margin-indent, size))
+}
+
+func (l *pdfLayout) heading(s string) {
+	l.need(lineGap*3 + 8)
+	l.y -= 10
+	l.line(s, 0, 13, true)
+	l.y -= 2
+}
+
+func (l *pdfLayout) field(label, value string) {
+	l.need(lineGap)
+	l.y -= lineGap
+	l.show(margin, l.y, bodySize, true, l.text.fit(label, 136, bodySize))
+	l.show(margin+140, l.y, bodySize, false, l.text.fit(value, pageWidth-2*margin-140, bodySize))
+}
+
+func (l *pdfLayout) total(label, amount string, bold bool) {
+	l.need(lineGap)
+	l.y -= lineGap
+	l.show(340, l.y, bodySize, bold, l.text.fit(label, 120, bodySize))
+	l.show(pageWidth-margin-l.text.width(amount, bodySize), l.y, bodySize, bold, amount)
+}
+
+func (l *pdfLayout) items(doc *Document) {
+	columns := itemColumns(doc.ShowPrices())
+
+	header := func() {
+		l.y -= lineGap
+		for _, c := range columns {
+			l.cell(c, doc.Labels[c.key], true)
+		}
+		l.rule(margin, pageWidth-margin, l.y-4)
+		l.y -= 4
+	}
+
+	l.need(lineGap * 2)
+	header()
+	for _, item := range doc.Items {
+		if l.y-lineGap < margin {
+			l.newPage()
+			header()
+		}
+		l.y -= lineGap
+		for _, c := range columns {
+			l.cell(c, c.value(item), false)
+		}
+	}
+}
+
+func (l *pdfLayout) cell(c column, s string, bold bool) {
+	s = l.text.fit(s, c.width, tableSize)
+	x := c.x
+	if c.right {
+		x = c.x + c.width - l.text.width(s, tableSize)
+	}
+	l.show(x, l.y, tableSize, bold, s)
+}
+
+func (l *pdfLayout) write(w io.Writer) error {
+	var objects pdfObjects
+	catalog := objects.reserve()
+	pages := objects.reserve()
+	regular, bold := l.text.fontObjects(&objects)
+
+	var kids []string
+	for _, content := range l.pages {
+		contentID := objects.add(streamObject(content.Bytes(), ""))
+		kids = append(kids, fmt.Sprintf("%d 0 R", objects.add([]byte(fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] "+
+				"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+			pages, pageWidth, pageHeight, regular, bold, contentID)))))
+	}
+
+	objects.set(catalog, []byte(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages)))
+	objects.set(pages, []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>",
+		strings.Join(kids, " "), len(kids))))
+
+	return objects.write(w, catalog)
+}
+
+type pdfObjects struct {
+	bodies [][]byte
+}
+
+func (o *pdfObjects) reserve() int {
+	o.bodies = append(o.bodies, nil)
+	return len(o.bodies)
+}
+
+func (o *pdfObjects) add(body []byte) int {
+	o.bodies = append(o.bodies, body)
+	return len(o.bodies)
+}
+
+func (o *pdfObjects) set(id int, body []byte) {
+	o.bodies[id-1] = body
+}
+
+func (o *pdfObjects) write(w io.Writer, root int) error {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(o.bodies))
+	for i, body := range o.bodies {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(body)
+		buf.WriteString("\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(o.bodies)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(o.bodies)+1, root, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func streamObject(data []byte, extra string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<< /Length %d%s >>\nstream\n", len(data), extra)
+	buf.Write(data)
+	buf.WriteString("\nendstream")
+
+	return buf.Bytes()
+}
+
+// pdfText encodes strings for either Helvetica with WinAnsiEncoding or an
+// embedded TrueType font addressed by glyph index (Identity-H), and records
+// the glyphs used so the font's widths and ToUnicode map can be written.
+// unsupported is the first rune shown that the font has no glyph for.
+type pdfText struct {
+	font        *Font
+	buf         sfnt.Buffer
+	glyphs      map[uint16]glyphInfo
+	unsupported rune
+}
+
+type glyphInfo struct {
+	r     rune
+	width int
+}
+
+func newPDFText(font *Font) *pdfText {
+	return &pdfText{font: font, glyphs: make(map[uint16]glyphInfo)}
+}
+
+func (t *pdfText) glyph(r rune) (uint16, int) {
+	index, width := t.font.glyph(&t.buf, r)
+	if _, ok := t.glyphs[index]; !ok {
+		t.glyphs[index] = glyphInfo{r: r, width: width}
+	}
+
+	return index, width
+}
+
+func (t *pdfText) width(s string, size float64) float64 {
+	total := 0
+	if t.font != nil {
+		for _, r := range s {
+			_, width := t.glyph(r)
+			total += width
+		}
+	} else {
+		for _, b := range winAnsi(s) {
+			total += helveticaWidth(b)
+		}
+	}
+
+	return float64(total) * size / 1000
+}
+
+// fit cuts s with an ellipsis so it is at most width points wide.
+func (t *pdfText) fit(s string, width, size float64) string {
+	if t.width(s, size) <= width {
+		return s
+	}
+
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := strings.TrimSpace(string(runes)) + "…"
+		if t.width(candidate, size) <= width {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+func (t *pdfText) operand(s string) string {
+	var b strings.Builder
+	if t.font != nil {
+		b.WriteByte('<')
+		for _, r := range s {
+			index, _ := t.glyph(r)
+			if index == 0 {
+				t.markUnsupported(r)
+			}
+			fmt.Fprintf(&b, "%04X", index)
+		}
+		b.WriteByte('>')
+		return b.String()
+	}
+
+	for _, r := range s {
+		if _, ok := charmap.Windows1252.EncodeRune(r); !ok {
+			t.markUnsupported(r)
+		}
+	}
+
+	b.WriteByte('(')
+	for _, c := range winAnsi(s) {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+
+	return b.String()
+}
+
+func (t *pdfText) markUnsupported(r rune) {
+	if t.unsupported == 0 {
+		t.unsupported = r
+	}
+}
+
+// boldOperators fakes a bold face for an embedded font, which has only one
+// weight, by stroking the glyph outlines.
+func (t *pdfText) boldOperators(bold bool) string {
+	if t.font == nil {
+		return ""
+	}
+	if bold {
+		return "2 Tr 0.3 w "
+	}
+
+	return "0 Tr "
+}
+
+// fontObjects adds the font resources and returns the ids of the regular and
+// bold fonts.
+func (t *pdfText) fontObjects(objects *pdfObjects) (int, int) {
+	if t.font == nil {
+		regular := objects.add([]byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"))
+		bold := objects.add([]byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>"))
+		return regular, bold
+	}
+
+	f := t.font
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write(f.data)
+	_ = zw.Close()
+
+	fontFile := objects.add(streamObject(compressed.Bytes(),
+		fmt.Sprintf(" /Length1 %d /Filter /FlateDecode", len(f.data))))
+	descriptor := objects.add([]byte(fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] "+
+			"/ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		f.name, f.bbox[0], f.bbox[1], f.bbox[2], f.bbox[3], f.ascent, f.descent, f.ascent, fontFile)))
+
+	indexes := make([]int, 0, len(t.glyphs))
+	for index := range t.glyphs {
+		indexes = append(indexes, int(index))
+	}
+	sort.Ints(indexes)
+
+	var widths strings.Builder
+	for _, index := range indexes {
+		fmt.Fprintf(&widths, "%d [%d] ", index, t.glyphs[uint16(index)].width)
+	}
+
+	cidFont := objects.add([]byte(fmt.Sprintf(
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+			"/FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+		f.name, descriptor, strings.TrimSpace(widths.String()))))
+	toUnicode := objects.add(streamObject(t.toUnicode(indexes), ""))
+
+	font := objects.add([]byte(fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H "+
+			"/DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		f.name, cidFont, toUnicode)))
+
+	return font, font
+}
+
+// toUnicode maps glyph indexes back to text so the PDF can be searched and
+// copied from.
+func (t *pdfText) toUnicode(indexes []int) []byte {
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	const chunk = 100
+	for start := 0; start < len(indexes); start += chunk {
+		end := min(start+chunk, len(indexes))
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, index := range indexes[start:end] {
+			fmt.Fprintf(&b, "<%04X> <", index)
+			for _, unit := range utf16.Encode([]rune{t.glyphs[uint16(index)].r}) {
+				fmt.Fprintf(&b, "%04X", unit)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+
+	return b.Bytes()
+}
+
+func winAnsi(s string) []byte {
+	encoded := make([]byte, 0, len(s))
+	for _, r := range s {
+		c, ok := charmap.Windows1252.EncodeRune(r)
+		if !ok {
+			c = '?'
+		}
+		encoded = append(encoded, c)
+	}
+
+	return encoded
+}
+
+// helveticaASCIIWidths are the Helvetica advance widths of ' ' through '~'
+// in 1/1000 em, from the standard AFM metrics.
+var helveticaASCIIWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+func helveticaWidth(c byte) int {
+	if c >= ' ' && c <= '~' {
+		return helveticaASCIIWidths[c-' ']
+	}
+	if c == 0x85 {
+		return 1000
+	}
+
+	return 556
+}
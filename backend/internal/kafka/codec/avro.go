@@ -0,0 +1,67 @@
+package codec
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/hamba/avro/v2"
+	"github.com/supchaser/wb_l0/internal/app/models"
+)
+
+//go:embed order.avsc
+var orderAvroSchema string
+
+var (
+	avroSchema = avro.MustParse(orderAvroSchema)
+	avroAPI    = avro.Config{TagKey: "json"}.Freeze()
+)
+
+type avroCodec struct{}
+
+func (avroCodec) SchemaType() string {
+	return SchemaTypeAvro
+}
+
+func (avroCodec) SchemaDefinition() string {
+	return orderAvroSchema
+}
+
+func (avroCodec) ContentType() string {
+	return ContentTypeAvro
+}
+
+func (avroCodec) Marshal(order *models.OrderRequest) ([]byte, error) {
+	return avroAPI.Marshal(avroSchema, order)
+}
+
+func (avroCodec) Unmarshal(data []byte) (*models.OrderRequest, error) {
+	return unmarshalAvro(avroSchema, data)
+}
+
+func UnmarshalAvroWithWriterSchema(data []byte, writerDefinition string) (*models.OrderRequest, error) {
+	writer, err := avro.Parse(writerDefinition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse writer schema: %w", err)
+	}
+
+	resolved, err := avro.NewSchemaCompatibility().Resolve(avroSchema, writer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve writer schema: %w", err)
+	}
+
+	return unmarshalAvro(resolved, data)
+}
+
+func unmarshalAvro(schema avro.Schema, data []byte) (*models.OrderRequest, error) {
+	var order models.OrderRequest
+
+	// Unmarshal treats io.EOF as success, which lets truncated payloads
+	// decode into partial orders; read through a Reader to surface it.
+	reader := avro.NewReader(nil, 0, avro.WithReaderConfig(avroAPI)).Reset(data)
+	reader.ReadVal(schema, &order)
+	if reader.Error != nil {
+		return nil, fmt.Errorf("failed to decode avro payload: %w", reader.Error)
+	}
+
+	return &order, nil
+}
@@ -0,0 +1,108 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+const (
+	HeaderVersion     = "version"
+	HeaderContentType = "content-type"
+	HeaderEventType   = "event-type"
+	PayloadVersion    = "1.0"
+
+	HeaderOrderVersion = "order-version"
+	// HeaderOrderVersionSource ranks the order-version header of a replayed
+	// order like the version_source column, so the order is rewritten at the
+	// version it is stored at.
+	HeaderOrderVersionSource = "order-version-source"
+	// HeaderFault names the fault a load generator deliberately injected
+	// into a message.
+	HeaderFault = "injected-fault"
+	// HeaderReplay marks a message republished by cmd/replay and holds the
+	// ID of the replay.
+	HeaderReplay = "replay"
+
+	HeaderDLQError         = "dlq-error"
+	HeaderDLQTopic         = "dlq-source-topic"
+	HeaderDLQPartition     = "dlq-source-partition"
+	HeaderDLQOffset        = "dlq-source-offset"
+	HeaderValidationErrors = "validation-errors"
+
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeAvro     = "application/avro"
+	ContentTypeEvent    = "application/vnd.wb-l0.event+json"
+
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+	FormatAvro     = "avro"
+)
+
+type Codec interface {
+	ContentType() string
+	Marshal(order *models.OrderRequest) ([]byte, error)
+	Unmarshal(data []byte) (*models.OrderRequest, error)
+}
+
+type SchemaProvider interface {
+	SchemaType() string
+	SchemaDefinition() string
+}
+
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeProtobuf = "PROTOBUF"
+)
+
+func Formats() []string {
+	return []string{FormatJSON, FormatProtobuf, FormatAvro}
+}
+
+var codecs = map[string]Codec{
+	FormatJSON:     jsonCodec{},
+	FormatProtobuf: protobufCodec{},
+	FormatAvro:     avroCodec{},
+}
+
+func ForFormat(format string) (Codec, error) {
+	c, ok := codecs[strings.ToLower(strings.TrimSpace(format))]
+	if !ok {
+		return nil, fmt.Errorf("%w: payload format %q", errs.ErrUnsupportedVersion, format)
+	}
+
+	return c, nil
+}
+
+func ForContentType(contentType string) (Codec, error) {
+	for _, c := range codecs {
+		if c.ContentType() == contentType {
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: content type %q", errs.ErrUnsupportedVersion, contentType)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (jsonCodec) Marshal(order *models.OrderRequest) ([]byte, error) {
+	return json.Marshal(order)
+}
+
+func (jsonCodec) Unmarshal(data []byte) (*models.OrderRequest, error) {
+	var order models.OrderRequest
+	if err := json.Unmarshal(data, &order); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
@@ -0,0 +1,222 @@
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
+)
+
+func createTestOrder() *models.OrderRequest {
+	return &models.OrderRequest{
+		OrderUID:          "b563feb7b2b84b6test",
+		TrackNumber:       "WBILMTESTTRACK",
+		Entry:             "WBIL",
+		Locale:            models.LocaleEN,
+		InternalSignature: "",
+		CustomerID:        "test",
+		DeliveryService:   "meest",
+		Shardkey:          "9",
+		SmID:              99,
+		DateCreated:       time.Date(2021, 11, 26, 6, 22, 19, 123456000, time.UTC),
+		OofShard:          "1",
+		Version:           1637907739,
+		Delivery: models.DeliveryRequest{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.PaymentRequest{
+			Transaction:  "b563feb7b2b84b6test",
+			RequestID:    "",
+			Currency:     models.CurrencyUSD,
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDt:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+			CustomFee:    0,
+		},
+		Items: []models.ItemRequest{
+			{
+				ChrtID:      9934930,
+				TrackNumber: "WBILMTESTTRACK",
+				Price:       453,
+				Rid:         "ab4219087a764ae0btest",
+				Name:        "Mascaras",
+				Sale:        30,
+				Size:        "0",
+				TotalPrice:  317,
+				NmID:        2389212,
+				Brand:       "Vivienne Sabo",
+				Status:      202,
+			},
+			{
+				ChrtID:      -1,
+				TrackNumber: "WBILMTESTTRACK",
+				Price:       1,
+				Rid:         "ab4219087a764ae0btest2",
+				Name:        "Негатив",
+				Size:        "XL",
+				TotalPrice:  1,
+				NmID:        1,
+				Brand:       "Brand",
+			},
+		},
+	}
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	tests := []struct {
+		format      string
+		contentType string
+	}{
+		{format: FormatJSON, contentType: ContentTypeJSON},
+		{format: FormatProtobuf, contentType: ContentTypeProtobuf},
+		{format: FormatAvro, contentType: ContentTypeAvro},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			c, err := ForFormat(tt.format)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.contentType, c.ContentType())
+
+			byContentType, err := ForContentType(tt.contentType)
+			assert.NoError(t, err)
+			assert.Equal(t, c, byContentType)
+
+			order := createTestOrder()
+			data, err := c.Marshal(order)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, data)
+
+			decoded, err := c.Unmarshal(data)
+			assert.NoError(t, err)
+			assert.True(t, order.DateCreated.Equal(decoded.DateCreated))
+
+			decoded.DateCreated = order.DateCreated
+			assert.Equal(t, order, decoded)
+		})
+	}
+}
+
+func TestCodec_Lookup(t *testing.T) {
+	c, err := ForFormat(" Protobuf ")
+	assert.NoError(t, err)
+	assert.Equal(t, ContentTypeProtobuf, c.ContentType())
+
+	_, err = ForFormat("xml")
+	assert.True(t, errors.Is(err, errs.ErrUnsupportedVersion))
+
+	_, err = ForContentType("text/plain")
+	assert.True(t, errors.Is(err, errs.ErrUnsupportedVersion))
+}
+
+func TestCodec_UnmarshalInvalid(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatProtobuf, FormatAvro} {
+		t.Run(format, func(t *testing.T) {
+			c, err := ForFormat(format)
+			assert.NoError(t, err)
+
+			data, err := c.Marshal(createTestOrder())
+			assert.NoError(t, err)
+
+			_, err = c.Unmarshal(data[:len(data)/2])
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCheckStrict(t *testing.T) {
+	valid, err := json.Marshal(createTestOrder())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		data       string
+		violations validate.ValidationErrors
+		wantErr    bool
+	}{
+		{name: "Valid", data: string(valid)},
+		{
+			name: "UnknownTopLevelField",
+			data: `{"order_uid": "a", "deliver": {"name": "x"}}`,
+			violations: validate.ValidationErrors{
+				{Path: "deliver", Code: validate.CodeUnknownField, Message: `unknown field "deliver"`},
+			},
+		},
+		{
+			name: "CaseMismatch",
+			data: `{"Order_UID": "a"}`,
+			violations: validate.ValidationErrors{
+				{Path: "Order_UID", Code: validate.CodeUnknownField, Message: `unknown field "Order_UID"`},
+			},
+		},
+		{
+			name: "NestedUnknownField",
+			data: `{"delivery": {"name": "x", "mail": "x@y.z"}, "items": [{"price": 1}, {"prise": 2}]}`,
+			violations: validate.ValidationErrors{
+				{Path: "delivery.mail", Code: validate.CodeUnknownField, Message: `unknown field "delivery.mail"`},
+				{Path: "items[1].prise", Code: validate.CodeUnknownField, Message: `unknown field "items[1].prise"`},
+			},
+		},
+		{
+			name: "DuplicateKey",
+			data: `{"order_uid": "a", "payment": {"amount": 1, "amount": 2}, "order_uid": "b"}`,
+			violations: validate.ValidationErrors{
+				{Path: "payment.amount", Code: validate.CodeDuplicateField, Message: `duplicate field "payment.amount"`},
+				{Path: "order_uid", Code: validate.CodeDuplicateField, Message: `duplicate field "order_uid"`},
+			},
+		},
+		{
+			name: "TrailingData",
+			data: `{"order_uid": "a"} {"order_uid": "b"}`,
+			violations: validate.ValidationErrors{
+				{Path: "", Code: validate.CodeTrailingData, Message: "unexpected data after the top-level value"},
+			},
+		},
+		{name: "TrailingWhitespace", data: "{\"order_uid\": \"a\"}\n"},
+		{name: "Malformed", data: `{"order_uid": `, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckStrict([]byte(tt.data), &models.OrderRequest{})
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.False(t, errors.Is(err, errs.ErrValidation))
+				return
+			}
+			if tt.violations == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.True(t, errors.Is(err, errs.ErrValidation))
+			violations, ok := validate.AsValidationErrors(err)
+			assert.True(t, ok)
+			assert.Equal(t, tt.violations, violations)
+		})
+	}
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	var envelope models.EventEnvelope
+	err := UnmarshalStrict([]byte(`{"event_type": "order.deleted", "payload": {"anything": 1}}`), &envelope)
+	assert.NoError(t, err)
+	assert.Equal(t, models.EventOrderDeleted, envelope.EventType)
+
+	err = UnmarshalStrict([]byte(`{"event_type": "order.deleted", "event": "x"}`), &envelope)
+	assert.True(t, errors.Is(err, errs.ErrValidation))
+}
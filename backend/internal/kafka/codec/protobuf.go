@@ -0,0 +1,308 @@
+package codec
+
+import (
+	_ "embed"
+	"fmt"
+	"time"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+//go:embed order.proto
+var orderProtoSchema string
+
+// protobufCodec is written by hand against order.proto so the build does not
+// depend on protoc; field numbers here must follow the .proto definition.
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (protobufCodec) SchemaType() string {
+	return SchemaTypeProtobuf
+}
+
+func (protobufCodec) SchemaDefinition() string {
+	return orderProtoSchema
+}
+
+func (protobufCodec) Marshal(order *models.OrderRequest) ([]byte, error) {
+	var b []byte
+	b = appendString(b, 1, order.OrderUID)
+	b = appendString(b, 2, order.TrackNumber)
+	b = appendString(b, 3, order.Entry)
+	b = appendString(b, 4, string(order.Locale))
+	b = appendString(b, 5, order.InternalSignature)
+	b = appendString(b, 6, order.CustomerID)
+	b = appendString(b, 7, order.DeliveryService)
+	b = appendString(b, 8, order.Shardkey)
+	b = appendInt(b, 9, int64(order.SmID))
+	if !order.DateCreated.IsZero() {
+		b = appendMessage(b, 10, marshalTimestamp(order.DateCreated))
+	}
+	b = appendString(b, 11, order.OofShard)
+	b = appendMessage(b, 12, marshalDelivery(&order.Delivery))
+	b = appendMessage(b, 13, marshalPayment(&order.Payment))
+	for i := range order.Items {
+		b = appendMessage(b, 14, marshalItem(&order.Items[i]))
+	}
+	b = appendInt(b, 15, order.Version)
+
+	return b, nil
+}
+
+func (protobufCodec) Unmarshal(data []byte) (*models.OrderRequest, error) {
+	order := &models.OrderRequest{}
+
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error {
+		var err error
+		switch num {
+		case 1:
+			order.OrderUID = string(value)
+		case 2:
+			order.TrackNumber = string(value)
+		case 3:
+			order.Entry = string(value)
+		case 4:
+			order.Locale = models.LocaleEnum(value)
+		case 5:
+			order.InternalSignature = string(value)
+		case 6:
+			order.CustomerID = string(value)
+		case 7:
+			order.DeliveryService = string(value)
+		case 8:
+			order.Shardkey = string(value)
+		case 9:
+			order.SmID = int(n)
+		case 10:
+			order.DateCreated, err = unmarshalTimestamp(value)
+		case 11:
+			order.OofShard = string(value)
+		case 12:
+			err = unmarshalDelivery(value, &order.Delivery)
+		case 13:
+			err = unmarshalPayment(value, &order.Payment)
+		case 14:
+			var item models.ItemRequest
+			if err = unmarshalItem(value, &item); err == nil {
+				order.Items = append(order.Items, item)
+			}
+		case 15:
+			order.Version = int64(n)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func marshalTimestamp(t time.Time) []byte {
+	var b []byte
+	b = appendInt(b, 1, t.Unix())
+	b = appendInt(b, 2, int64(t.Nanosecond()))
+	return b
+}
+
+func unmarshalTimestamp(data []byte) (time.Time, error) {
+	var seconds, nanos int64
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error {
+		switch num {
+		case 1:
+			seconds = int64(n)
+		case 2:
+			nanos = int64(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(seconds, nanos).UTC(), nil
+}
+
+func marshalDelivery(d *models.DeliveryRequest) []byte {
+	var b []byte
+	b = appendString(b, 1, d.Name)
+	b = appendString(b, 2, d.Phone)
+	b = appendString(b, 3, d.Zip)
+	b = appendString(b, 4, d.City)
+	b = appendString(b, 5, d.Address)
+	b = appendString(b, 6, d.Region)
+	b = appendString(b, 7, d.Email)
+	return b
+}
+
+func unmarshalDelivery(data []byte, d *models.DeliveryRequest) error {
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error {
+		switch num {
+		case 1:
+			d.Name = string(value)
+		case 2:
+			d.Phone = string(value)
+		case 3:
+			d.Zip = string(value)
+		case 4:
+			d.City = string(value)
+		case 5:
+			d.Address = string(value)
+		case 6:
+			d.Region = string(value)
+		case 7:
+			d.Email = string(value)
+		}
+		return nil
+	})
+}
+
+func marshalPayment(p *models.PaymentRequest) []byte {
+	var b []byte
+	b = appendString(b, 1, p.Transaction)
+	b = appendString(b, 2, p.RequestID)
+	b = appendString(b, 3, string(p.Currency))
+	b = appendString(b, 4, p.Provider)
+	b = appendInt(b, 5, int64(p.Amount))
+	b = appendInt(b, 6, int64(p.PaymentDt))
+	b = appendString(b, 7, p.Bank)
+	b = appendInt(b, 8, int64(p.DeliveryCost))
+	b = appendInt(b, 9, int64(p.GoodsTotal))
+	b = appendInt(b, 10, int64(p.CustomFee))
+	return b
+}
+
+func unmarshalPayment(data []byte, p *models.PaymentRequest) error {
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error {
+		switch num {
+		case 1:
+			p.Transaction = string(value)
+		case 2:
+			p.RequestID = string(value)
+		case 3:
+			p.Currency = models.CurrencyEnum(value)
+		case 4:
+			p.Provider = string(value)
+		case 5:
+			p.Amount = int(n)
+		case 6:
+			p.PaymentDt = int(n)
+		case 7:
+			p.Bank = string(value)
+		case 8:
+			p.DeliveryCost = int(n)
+		case 9:
+			p.GoodsTotal = int(n)
+		case 10:
+			p.CustomFee = int(n)
+		}
+		return nil
+	})
+}
+
+func marshalItem(item *models.ItemRequest) []byte {
+	var b []byte
+	b = appendInt(b, 1, int64(item.ChrtID))
+	b = appendString(b, 2, item.TrackNumber)
+	b = appendInt(b, 3, int64(item.Price))
+	b = appendString(b, 4, item.Rid)
+	b = appendString(b, 5, item.Name)
+	b = appendInt(b, 6, int64(item.Sale))
+	b = appendString(b, 7, item.Size)
+	b = appendInt(b, 8, int64(item.TotalPrice))
+	b = appendInt(b, 9, int64(item.NmID))
+	b = appendString(b, 10, item.Brand)
+	b = appendInt(b, 11, int64(item.Status))
+	return b
+}
+
+func unmarshalItem(data []byte, item *models.ItemRequest) error {
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error {
+		switch num {
+		case 1:
+			item.ChrtID = int(n)
+		case 2:
+			item.TrackNumber = string(value)
+		case 3:
+			item.Price = int(n)
+		case 4:
+			item.Rid = string(value)
+		case 5:
+			item.Name = string(value)
+		case 6:
+			item.Sale = int(n)
+		case 7:
+			item.Size = string(value)
+		case 8:
+			item.TotalPrice = int(n)
+		case 9:
+			item.NmID = int(n)
+		case 10:
+			item.Brand = string(value)
+		case 11:
+			item.Status = int(n)
+		}
+		return nil
+	})
+}
+
+func appendString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendInt(b []byte, num protowire.Number, value int64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(value))
+}
+
+func appendMessage(b []byte, num protowire.Number, value []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+type fieldFunc func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error
+
+func consumeFields(data []byte, fn fieldFunc) error {
+	for len(data) > 0 {
+		num, typ, tagLen := protowire.ConsumeTag(data)
+		if tagLen < 0 {
+			return fmt.Errorf("invalid protobuf tag: %w", protowire.ParseError(tagLen))
+		}
+		data = data[tagLen:]
+
+		var (
+			value    []byte
+			n        uint64
+			valueLen int
+		)
+		switch typ {
+		case protowire.VarintType:
+			n, valueLen = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			value, valueLen = protowire.ConsumeBytes(data)
+		default:
+			valueLen = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if valueLen < 0 {
+			return fmt.Errorf("invalid protobuf field %d: %w", num, protowire.ParseError(valueLen))
+		}
+		data = data[valueLen:]
+
+		if err := fn(num, typ, value, n); err != nil {
+			return fmt.Errorf("field %d: %w", num, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/supchaser/wb_l0/internal/utils/validate"
+)
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// UnmarshalStrict decodes data into v like json.Unmarshal, but rejects
+// unknown fields, duplicate keys and trailing data. Keys must match the json
+// tags exactly.
+func UnmarshalStrict(data []byte, v any) error {
+	if err := CheckStrict(data, v); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// CheckStrict reports every unknown field, duplicate key and trailing value
+// in data as validate.ValidationErrors with the offending JSON path. Malformed
+// JSON is returned as a plain error; type mismatches are left to
+// json.Unmarshal.
+func CheckStrict(data []byte, v any) error {
+	checker := strictChecker{decoder: json.NewDecoder(bytes.NewReader(data))}
+	checker.decoder.UseNumber()
+
+	if err := checker.value(reflect.TypeOf(v), ""); err != nil {
+		return err
+	}
+
+	if _, err := checker.decoder.Token(); !errors.Is(err, io.EOF) {
+		checker.violations = append(checker.violations, validate.FieldError{
+			Path:    "",
+			Code:    validate.CodeTrailingData,
+			Message: "unexpected data after the top-level value",
+		})
+	}
+
+	if len(checker.violations) == 0 {
+		return nil
+	}
+
+	return checker.violations
+}
+
+type strictChecker struct {
+	decoder    *json.Decoder
+	violations validate.ValidationErrors
+}
+
+func (c *strictChecker) value(t reflect.Type, path string) error {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && (t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(unmarshalerType)) {
+		t = nil
+	}
+
+	token, err := c.decoder.Token()
+	if err != nil {
+		return fmt.Errorf("invalid json at %s: %w", pathOrRoot(path), err)
+	}
+
+	switch token {
+	case json.Delim('{'):
+		return c.object(t, path)
+	case json.Delim('['):
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for i := 0; c.decoder.More(); i++ {
+			if err := c.value(elem, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		_, err := c.decoder.Token()
+		return err
+	}
+
+	return nil
+}
+
+func (c *strictChecker) object(t reflect.Type, path string) error {
+	var fields map[string]reflect.Type
+	var elem reflect.Type
+	switch {
+	case t == nil:
+	case t.Kind() == reflect.Struct:
+		fields = structFields(t)
+	case t.Kind() == reflect.Map:
+		elem = t.Elem()
+	}
+
+	seen := make(map[string]bool)
+	for c.decoder.More() {
+		token, err := c.decoder.Token()
+		if err != nil {
+			return fmt.Errorf("invalid json at %s: %w", pathOrRoot(path), err)
+		}
+		key := token.(string)
+		keyPath := joinPath(path, key)
+
+		if seen[key] {
+			c.violations = append(c.violations, validate.FieldError{
+				Path:    keyPath,
+				Code:    validate.CodeDuplicateField,
+				Message: fmt.Sprintf("duplicate field %q", keyPath),
+			})
+		}
+		seen[key] = true
+
+		fieldType := elem
+		if fields != nil {
+			var ok bool
+			fieldType, ok = fields[key]
+			if !ok {
+				c.violations = append(c.violations, validate.FieldError{
+					Path:    keyPath,
+					Code:    validate.CodeUnknownField,
+					Message: fmt.Sprintf("unknown field %q", keyPath),
+				})
+			}
+		}
+
+		if err := c.value(fieldType, keyPath); err != nil {
+			return err
+		}
+	}
+
+	_, err := c.decoder.Token()
+	return err
+}
+
+// structFields maps json names to field types. Fields of embedded structs
+// are promoted unless the outer struct declares the same name.
+func structFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.Anonymous || field.Tag.Get("json") != "" {
+			continue
+		}
+		embedded := field.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+		if embedded.Kind() == reflect.Struct {
+			for name, fieldType := range structFields(embedded) {
+				fields[name] = fieldType
+			}
+		}
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" || (field.Anonymous && tag == "") {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+
+	return fields
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "root"
+	}
+
+	return path
+}
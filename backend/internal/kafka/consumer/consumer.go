@@ -3,16 +3,24 @@ package consumer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/go-redis/redis/v8"
 	"github.com/jackc/pgx/v5"
 	"github.com/supchaser/wb_l0/internal/app/models"
 	"github.com/supchaser/wb_l0/internal/config"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/kafka/registry"
+	"github.com/supchaser/wb_l0/internal/metrics"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
 	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/normalize"
 	"github.com/supchaser/wb_l0/internal/utils/pgxiface"
 	"github.com/supchaser/wb_l0/internal/utils/validate"
 	"go.uber.org/zap"
@@ -23,6 +31,9 @@ const (
 	autoCommitInterval = 1000
 	pollTimeout        = 100
 	batchSize          = 1000
+	lagQueryTimeout    = 1000
+	lagInterval        = 5 * time.Second
+	metadataTimeout    = 5000
 )
 
 type Consumer struct {
@@ -32,9 +43,29 @@ type Consumer struct {
 	wg        sync.WaitGroup
 	stopChan  chan struct{}
 	batchChan chan *kafka.Message
+	flushChan chan flushRequest
+	holdChan  chan chan struct{}
+	decoders  *DecoderRegistry
+	cache     *redis.Client
+	rules     *validate.RuleSet
+	schema    bool
+	strict    map[string]bool
+	dlq       *deadLetterQueue
+	state     consumerState
 }
 
-func CreateConsumer(cfg *config.ConsumerConfig, db pgxiface.PgxIface) (*Consumer, error) {
+type failedMessage struct {
+	msg *kafka.Message
+	err error
+}
+
+type flushRequest struct {
+	partitions []kafka.TopicPartition
+	lost       bool
+	done       chan struct{}
+}
+
+func CreateConsumer(cfg *config.ConsumerConfig, db pgxiface.PgxIface, cache *redis.Client) (*Consumer, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("consumer config is required")
 	}
@@ -52,8 +83,31 @@ func CreateConsumer(cfg *config.ConsumerConfig, db pgxiface.PgxIface) (*Consumer
 		"fetch.message.max.bytes":   10485760,
 	}
 
+	decoders, err := CreateDecoderRegistry(cfg.AcceptedFormats...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create decoder registry: %w", err)
+	}
+
+	schemaRegistry, err := registry.CreateClient(cfg.SchemaRegistryURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema registry client: %w", err)
+	}
+
+	decoders.UseSchemaRegistry(schemaRegistry)
+
+	rules, err := createRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dlq, err := createDeadLetterQueue(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	c, err := kafka.NewConsumer(kafkaConfig)
 	if err != nil {
+		dlq.close()
 		return nil, fmt.Errorf("failed to create consumer: %w", err)
 	}
 
@@ -63,14 +117,36 @@ func CreateConsumer(cfg *config.ConsumerConfig, db pgxiface.PgxIface) (*Consumer
 		db:        db,
 		stopChan:  make(chan struct{}),
 		batchChan: make(chan *kafka.Message, batchSize),
+		flushChan: make(chan flushRequest),
+		holdChan:  make(chan chan struct{}),
+		decoders:  decoders,
+		cache:     cache,
+		rules:     rules,
+		schema:    cfg.SchemaValidation,
+		strict:    strictTopics(cfg.StrictJSONTopics),
+		dlq:       dlq,
 	}
 
 	return consumer, nil
 }
 
+func createRules(cfg *config.ConsumerConfig) (*validate.RuleSet, error) {
+	overrides, err := validate.ParseSeverities(cfg.ValidationRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse validation rules: %w", err)
+	}
+
+	rules, err := validate.CreateRuleSet(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create validation rules: %w", err)
+	}
+
+	return rules, nil
+}
+
 func (c *Consumer) Start() error {
 	topics := []string{c.config.Topic}
-	if err := c.consumer.SubscribeTopics(topics, nil); err != nil {
+	if err := c.consumer.SubscribeTopics(topics, c.rebalanceCallback); err != nil {
 		return fmt.Errorf("failed to subscribe to topics: %w", err)
 	}
 
@@ -85,6 +161,9 @@ func (c *Consumer) Start() error {
 	c.wg.Add(1)
 	go c.messageLoop()
 
+	c.wg.Add(1)
+	go c.lagTracker()
+
 	logger.Info("Kafka consumer started successfully")
 	return nil
 }
@@ -98,6 +177,9 @@ func (c *Consumer) messageLoop() {
 			logger.Info("stopping message loop")
 			return
 
+		case release := <-c.holdChan:
+			<-release
+
 		default:
 			msg, err := c.consumer.ReadMessage(pollTimeout)
 			if err != nil {
@@ -111,8 +193,10 @@ func (c *Consumer) messageLoop() {
 			select {
 			case c.batchChan <- msg:
 			default:
-				logger.Warn("batch channel full, message might be processed slowly")
+				logger.Warn("batch channel full, pausing partitions until it drains")
+				c.pause()
 				c.batchChan <- msg
+				c.resume()
 			}
 		}
 	}
@@ -125,18 +209,26 @@ func (c *Consumer) batchProcessor() {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
-	processBatch := func() {
+	// revoked is only set when flushing from the rebalance callback, which
+	// runs on the message loop and so already holds it.
+	processBatch := func(revoked []kafka.TopicPartition) {
 		if len(batch) == 0 {
 			return
 		}
 
+		startTime := time.Now()
+
 		if err := c.processMessageBatch(batch); err != nil {
-			logger.Error("failed to process batch", zap.Error(err))
+			logger.Error("failed to process batch, rewinding to retry it", zap.Error(err))
+			c.rewind(batch, revoked)
+		} else {
+			if err := c.commitOffsets(batch); err != nil {
+				logger.Error("failed to commit offsets", zap.Error(err))
+			}
+			c.recordBatch(batch)
 		}
 
-		if err := c.commitOffsets(batch); err != nil {
-			logger.Error("failed to commit offsets", zap.Error(err))
-		}
+		metrics.ConsumerBatchDuration.Observe(time.Since(startTime).Seconds())
 
 		batch = batch[:0]
 	}
@@ -144,22 +236,37 @@ func (c *Consumer) batchProcessor() {
 	for {
 		select {
 		case <-c.stopChan:
-			processBatch()
+			processBatch(nil)
 			logger.Info("batch processor stopped")
 			return
 
 		case msg := <-c.batchChan:
 			batch = append(batch, msg)
 			if len(batch) >= batchSize {
-				processBatch()
+				processBatch(nil)
 			}
 
+		case req := <-c.flushChan:
+			batch = c.drainQueued(batch)
+			if req.lost {
+				batch = dropPartitions(batch, req.partitions)
+			}
+			processBatch(req.partitions)
+			close(req.done)
+
 		case <-ticker.C:
-			processBatch()
+			processBatch(nil)
 		}
 	}
 }
 
+// errBatchAborted marks failures of the batch transaction itself, as opposed
+// to failures of a single message.
+var errBatchAborted = errors.New("batch transaction aborted")
+
+// processMessageBatch applies every message in its own savepoint of a single
+// transaction. A failing message is rolled back alone and sent to the DLQ;
+// metrics and the DLQ are only touched once the transaction is committed.
 func (c *Consumer) processMessageBatch(messages []*kafka.Message) error {
 	ctx := context.Background()
 	tx, err := c.db.Begin(ctx)
@@ -168,66 +275,296 @@ func (c *Consumer) processMessageBatch(messages []*kafka.Message) error {
 	}
 	defer tx.Rollback(ctx)
 
+	var (
+		evict     []string
+		failed    []failedMessage
+		processed int
+		stale     int
+	)
 	for _, msg := range messages {
 		if msg == nil {
 			continue
 		}
-		if err := c.processSingleMessage(ctx, tx, msg); err != nil {
+		orderUID, err := c.processInSavepoint(ctx, tx, msg)
+		if err != nil {
+			if errors.Is(err, errBatchAborted) {
+				return err
+			}
+			if errors.Is(err, errs.ErrStaleVersion) {
+				stale++
+				continue
+			}
+
 			var topic string
 			if msg.TopicPartition.Topic != nil {
 				topic = *msg.TopicPartition.Topic
 			}
-			logger.Error("failed to process message",
+			fields := []zap.Field{
 				zap.String("topic", topic),
 				zap.Int32("partition", msg.TopicPartition.Partition),
 				zap.Int64("offset", int64(msg.TopicPartition.Offset)),
-				zap.Error(err))
+				zap.Error(err),
+			}
+			if violations, ok := validate.AsValidationErrors(err); ok {
+				fields = append(fields, zap.Any("violations", violations))
+			}
+			logger.Error("failed to process message", fields...)
+			failed = append(failed, failedMessage{msg: msg, err: err})
+			continue
+		}
+		if orderUID != "" {
+			evict = append(evict, orderUID)
 		}
+		processed++
 	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	metrics.ConsumerMessagesTotal.WithLabelValues(metrics.ResultProcessed).Add(float64(processed))
+	metrics.ConsumerMessagesTotal.WithLabelValues(metrics.ResultStale).Add(float64(stale))
+	metrics.ConsumerMessagesTotal.WithLabelValues(metrics.ResultFailed).Add(float64(len(failed)))
+
+	c.evictFromCache(ctx, evict)
+
+	for _, f := range failed {
+		c.dlq.send(f.msg, f.err)
+	}
+
 	logger.Info("successfully processed message batch",
 		zap.Int("message_count", len(messages)))
 
 	return nil
 }
 
-func (c *Consumer) processSingleMessage(ctx context.Context, tx pgx.Tx, msg *kafka.Message) error {
-	var order models.OrderRequest
-	if err := json.Unmarshal(msg.Value, &order); err != nil {
-		return fmt.Errorf("failed to unmarshal order: %w", err)
+// processInSavepoint applies msg inside a savepoint, so a failing message
+// leaves neither a partial update nor an aborted batch transaction behind.
+func (c *Consumer) processInSavepoint(ctx context.Context, tx pgx.Tx, msg *kafka.Message) (string, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to begin savepoint: %w", errBatchAborted, err)
+	}
+
+	orderUID, err := c.processMessage(ctx, savepoint, msg)
+	if err != nil {
+		if rollbackErr := savepoint.Rollback(ctx); rollbackErr != nil {
+			return "", fmt.Errorf("%w: failed to roll back savepoint: %w", errBatchAborted, rollbackErr)
+		}
+		return "", err
 	}
 
-	if err := validate.ValidateOrderRequest(&order); err != nil {
+	if err := savepoint.Commit(ctx); err != nil {
+		return "", fmt.Errorf("%w: failed to release savepoint: %w", errBatchAborted, err)
+	}
+
+	return orderUID, nil
+}
+
+// processSingleMessage applies a full order snapshot and returns its UID.
+func (c *Consumer) processSingleMessage(ctx context.Context, tx pgx.Tx, msg *kafka.Message) (string, error) {
+	if err := c.checkStrict(msg); err != nil {
+		return "", fmt.Errorf("strict json decoding failed: %w", err)
+	}
+
+	if err := c.checkSchema(msg); err != nil {
+		return "", fmt.Errorf("order schema validation failed: %w", err)
+	}
+
+	order, err := c.decoders.Decode(msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal order: %w", err)
+	}
+
+	if err := c.applyOrder(ctx, tx, sourceOf(msg), order); err != nil {
+		return "", err
+	}
+
+	return order.OrderUID, nil
+}
+
+// checkStrict rejects unknown fields, duplicate keys and trailing data in
+// JSON orders read from topics configured for strict decoding.
+func (c *Consumer) checkStrict(msg *kafka.Message) error {
+	if !c.isStrict(msg) {
+		return nil
+	}
+
+	version, payload, ok, err := jsonPayload(msg)
+	if !ok || err != nil {
+		return err
+	}
+
+	switch version {
+	case PayloadVersionCurrent:
+		return codec.CheckStrict(payload, &models.OrderRequest{})
+	case PayloadVersionLegacy:
+		return codec.CheckStrict(payload, &legacyOrderRequest{})
+	}
+
+	return nil
+}
+
+// checkSchema validates current JSON payloads against the order schema
+// before they are decoded. Other formats are left to ValidateOrderRequest.
+func (c *Consumer) checkSchema(msg *kafka.Message) error {
+	if !c.schema {
+		return nil
+	}
+
+	version, payload, ok, err := jsonPayload(msg)
+	if !ok || err != nil || version != PayloadVersionCurrent {
+		return err
+	}
+
+	return validate.ValidateOrderJSON(payload)
+}
+
+// jsonPayload returns the unframed payload of a JSON order message.
+func jsonPayload(msg *kafka.Message) (string, []byte, bool, error) {
+	version, contentType := payloadFormat(msg)
+	if contentType != codec.ContentTypeJSON {
+		return version, nil, false, nil
+	}
+
+	payload := msg.Value
+	if registry.IsFramed(payload) {
+		_, unframed, err := registry.Decode(payload)
+		if err != nil {
+			return version, nil, false, err
+		}
+		payload = unframed
+	}
+
+	return version, payload, true, nil
+}
+
+func (c *Consumer) isStrict(msg *kafka.Message) bool {
+	return msg.TopicPartition.Topic != nil && c.strict[*msg.TopicPartition.Topic]
+}
+
+// unmarshal decodes event payloads, strictly for strict topics.
+func (c *Consumer) unmarshal(msg *kafka.Message, data []byte, v any) error {
+	if c.isStrict(msg) {
+		return codec.UnmarshalStrict(data, v)
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func strictTopics(topics []string) map[string]bool {
+	strict := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		strict[topic] = true
+	}
+
+	return strict
+}
+
+func (c *Consumer) applyOrder(ctx context.Context, tx pgx.Tx, source orderSource, order *models.OrderRequest) error {
+	if err := validate.ValidateOrderRequest(order); err != nil {
 		logger.Warn("order validation failed",
 			zap.String("order_uid", order.OrderUID),
 			zap.Error(err))
 		return fmt.Errorf("order validation failed: %w", err)
 	}
 
-	if err := c.saveOrderToDB(ctx, tx, &order); err != nil {
+	warnings, err := c.rules.Check(order)
+	if err != nil {
+		logger.Warn("order consistency check failed",
+			zap.String("order_uid", order.OrderUID),
+			zap.Error(err))
+		return fmt.Errorf("order consistency check failed: %w", err)
+	}
+	for _, warning := range warnings {
+		logger.Warn("order consistency warning",
+			zap.String("order_uid", order.OrderUID),
+			zap.String("rule", warning.Rule),
+			zap.String("message", warning.Message))
+	}
+
+	delivery, err := normalize.Delivery(order.Delivery, order.Locale)
+	if err != nil {
+		return fmt.Errorf("failed to normalize delivery: %w", err)
+	}
+
+	version, versionSource, err := orderVersion(source, order)
+	if err != nil {
+		return fmt.Errorf("failed to resolve order version: %w", err)
+	}
+
+	if err := c.saveOrderToDB(ctx, tx, order, delivery, version, versionSource, warnings, source); err != nil {
+		if errors.Is(err, errs.ErrStaleVersion) {
+			logger.Warn("skipping stale order update", append([]zap.Field{
+				zap.String("order_uid", order.OrderUID),
+				zap.Int64("version", version),
+				zap.Int16("version_source", versionSource),
+			}, source.logFields()...)...)
+		}
 		return fmt.Errorf("failed to save order to DB: %w", err)
 	}
 
 	logger.Info("successfully processed order",
-		zap.String("order_uid", order.OrderUID),
-		zap.String("topic", *msg.TopicPartition.Topic),
-		zap.Int32("partition", msg.TopicPartition.Partition),
-		zap.Int64("offset", int64(msg.TopicPartition.Offset)))
+		append([]zap.Field{zap.String("order_uid", order.OrderUID)}, source.logFields()...)...)
 
 	return nil
 }
 
-func (c *Consumer) saveOrderToDB(ctx context.Context, tx pgx.Tx, order *models.OrderRequest) error {
-	orderID, err := c.saveMainOrder(ctx, tx, order)
+// Version sources, ranked. A version only supersedes one of the same source
+// or of a lower-ranked source, so numbers from unrelated spaces, such as an
+// explicit version and a timestamp in milliseconds, are never compared.
+// Orders stored before sources were recorded have source 0.
+const (
+	versionFromOffset    int16 = 1
+	versionFromTimestamp int16 = 2
+	versionFromOrder     int16 = 3
+)
+
+// orderVersion resolves the version of order and its source: an explicit
+// version from the header or the payload, else the source timestamp, else
+// the offset. A replayed order keeps the source it is stored at.
+func orderVersion(source orderSource, order *models.OrderRequest) (int64, int16, error) {
+	if value, ok := source.header(codec.HeaderOrderVersion); ok {
+		version, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s header %q: %w", codec.HeaderOrderVersion, value, err)
+		}
+
+		versionSource := versionFromOrder
+		if value, ok := source.header(codec.HeaderOrderVersionSource); ok && source.replayed() {
+			parsed, err := strconv.ParseInt(string(value), 10, 16)
+			if err != nil || parsed < 0 || parsed > int64(versionFromOrder) {
+				return 0, 0, fmt.Errorf("invalid %s header %q", codec.HeaderOrderVersionSource, value)
+			}
+			versionSource = int16(parsed)
+		}
+		return version, versionSource, nil
+	}
+
+	if order.Version > 0 {
+		return order.Version, versionFromOrder, nil
+	}
+
+	if !source.timestamp.IsZero() {
+		return source.timestamp.UnixMilli(), versionFromTimestamp, nil
+	}
+
+	if source.offset == nil {
+		return 0, 0, fmt.Errorf("order has no version, timestamp or offset")
+	}
+
+	return *source.offset, versionFromOffset, nil
+}
+
+// saveOrderToDB stores the normalized delivery next to the raw one received
+// in order.
+func (c *Consumer) saveOrderToDB(ctx context.Context, tx pgx.Tx, order *models.OrderRequest, delivery models.DeliveryRequest, version int64, versionSource int16, warnings []models.ValidationWarning, source orderSource) error {
+	orderID, err := c.saveMainOrder(ctx, tx, order, version, versionSource, warnings, source.replayed())
 	if err != nil {
 		return fmt.Errorf("failed to save main order: %w", err)
 	}
 
-	if err := c.saveDelivery(ctx, tx, orderID, order.Delivery); err != nil {
+	if err := c.saveDelivery(ctx, tx, orderID, delivery, order.Delivery); err != nil {
 		return fmt.Errorf("failed to save delivery: %w", err)
 	}
 
@@ -235,19 +572,42 @@ func (c *Consumer) saveOrderToDB(ctx context.Context, tx pgx.Tx, order *models.O
 		return fmt.Errorf("failed to save payment: %w", err)
 	}
 
-	if err := c.saveItems(ctx, tx, orderID, order.Items); err != nil {
+	if err := c.saveItems(ctx, tx, orderID, order.Items, source); err != nil {
 		return fmt.Errorf("failed to save items: %w", err)
 	}
 
 	return nil
 }
 
-func (c *Consumer) saveMainOrder(ctx context.Context, tx pgx.Tx, order *models.OrderRequest) (int64, error) {
+// saveMainOrder upserts the order row unless the stored version, or the
+// tombstone of a deleted order, is newer. A replay rewrites the order at the
+// version it is stored at, so it also replaces an equal version.
+func (c *Consumer) saveMainOrder(ctx context.Context, tx pgx.Tx, order *models.OrderRequest, version int64, versionSource int16, warnings []models.ValidationWarning, replay bool) (int64, error) {
+	if warnings == nil {
+		warnings = []models.ValidationWarning{}
+	}
+	warningsInBytes, err := json.Marshal(warnings)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal validation warnings: %w", err)
+	}
+
+	compare := "<"
+	if replay {
+		compare = "<="
+	}
+
 	query := `
         INSERT INTO "order" (
             order_uid, track_number, entry, locale, internal_signature,
-            customer_id, delivery_service, shardkey, sm_id, oof_shard, date_created
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+            customer_id, delivery_service, shardkey, sm_id, oof_shard, date_created, version,
+            version_source, validation_warnings
+        )
+        SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14::jsonb
+        WHERE NOT EXISTS (
+            SELECT 1 FROM order_tombstone t
+            WHERE t.order_uid = $1
+                AND (t.version_source, t.version) >= ($13::smallint, $12::bigint)
+        )
         ON CONFLICT (order_uid) DO UPDATE SET
             track_number = EXCLUDED.track_number,
             entry = EXCLUDED.entry,
@@ -259,12 +619,16 @@ func (c *Consumer) saveMainOrder(ctx context.Context, tx pgx.Tx, order *models.O
             sm_id = EXCLUDED.sm_id,
             oof_shard = EXCLUDED.oof_shard,
             date_created = EXCLUDED.date_created,
+            version = EXCLUDED.version,
+            version_source = EXCLUDED.version_source,
+            validation_warnings = EXCLUDED.validation_warnings,
             updated_at = CURRENT_TIMESTAMP
+        WHERE ("order".version_source, "order".version) ` + compare + ` (EXCLUDED.version_source, EXCLUDED.version)
         RETURNING id
     `
 
 	var orderID int64
-	err := tx.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		order.OrderUID,
 		order.TrackNumber,
 		order.Entry,
@@ -276,20 +640,27 @@ func (c *Consumer) saveMainOrder(ctx context.Context, tx pgx.Tx, order *models.O
 		order.SmID,
 		order.OofShard,
 		order.DateCreated,
+		version,
+		versionSource,
+		string(warningsInBytes),
 	).Scan(&orderID)
 
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, errs.ErrStaleVersion
+		}
 		return 0, fmt.Errorf("failed to insert/update order: %w", err)
 	}
 
 	return orderID, nil
 }
 
-func (c *Consumer) saveDelivery(ctx context.Context, tx pgx.Tx, orderID int64, delivery models.DeliveryRequest) error {
+func (c *Consumer) saveDelivery(ctx context.Context, tx pgx.Tx, orderID int64, delivery, raw models.DeliveryRequest) error {
 	query := `
         INSERT INTO delivery (
-            order_id, name, phone, zip, city, address, region, email
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+            order_id, name, phone, zip, city, address, region, email,
+            name_raw, phone_raw, city_raw, address_raw, region_raw, email_raw
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
         ON CONFLICT (order_id) DO UPDATE SET
             name = EXCLUDED.name,
             phone = EXCLUDED.phone,
@@ -298,6 +669,12 @@ func (c *Consumer) saveDelivery(ctx context.Context, tx pgx.Tx, orderID int64, d
             address = EXCLUDED.address,
             region = EXCLUDED.region,
             email = EXCLUDED.email,
+            name_raw = EXCLUDED.name_raw,
+            phone_raw = EXCLUDED.phone_raw,
+            city_raw = EXCLUDED.city_raw,
+            address_raw = EXCLUDED.address_raw,
+            region_raw = EXCLUDED.region_raw,
+            email_raw = EXCLUDED.email_raw,
             updated_at = CURRENT_TIMESTAMP
     `
 
@@ -310,6 +687,12 @@ func (c *Consumer) saveDelivery(ctx context.Context, tx pgx.Tx, orderID int64, d
 		delivery.Address,
 		delivery.Region,
 		delivery.Email,
+		raw.Name,
+		raw.Phone,
+		raw.City,
+		raw.Address,
+		raw.Region,
+		raw.Email,
 	)
 
 	if err != nil {
@@ -360,22 +743,55 @@ func (c *Consumer) savePayment(ctx context.Context, tx pgx.Tx, orderID int64, pa
 	return nil
 }
 
-func (c *Consumer) saveItems(ctx context.Context, tx pgx.Tx, orderID int64, items []models.ItemRequest) error {
-	deleteQuery := `DELETE FROM item WHERE order_id = $1`
-	_, err := tx.Exec(ctx, deleteQuery, orderID)
+func (c *Consumer) saveItems(ctx context.Context, tx pgx.Tx, orderID int64, items []models.ItemRequest, source orderSource) error {
+	if err := c.recordItemStatuses(ctx, tx, orderID, items, source); err != nil {
+		return fmt.Errorf("failed to record item statuses: %w", err)
+	}
+
+	changes, err := c.upsertItems(ctx, tx, orderID, items)
 	if err != nil {
-		return fmt.Errorf("failed to delete old items: %w", err)
+		return err
 	}
+	changes.report(orderID)
+
+	return nil
+}
 
+// upsertItems keeps item rows keyed by (order_id, rid) and deletes only the
+// items that are missing from the snapshot.
+func (c *Consumer) upsertItems(ctx context.Context, tx pgx.Tx, orderID int64, items []models.ItemRequest) (itemChangeSet, error) {
 	query := `
         INSERT INTO item (
             order_id, chrt_id, track_number, price, rid, name,
             sale, size, total_price, nm_id, brand, status
         ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        ON CONFLICT (order_id, rid) DO UPDATE SET
+            chrt_id = EXCLUDED.chrt_id,
+            track_number = EXCLUDED.track_number,
+            price = EXCLUDED.price,
+            name = EXCLUDED.name,
+            sale = EXCLUDED.sale,
+            size = EXCLUDED.size,
+            total_price = EXCLUDED.total_price,
+            nm_id = EXCLUDED.nm_id,
+            brand = EXCLUDED.brand,
+            status = EXCLUDED.status,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE (item.chrt_id, item.track_number, item.price, item.name, item.sale, item.size,
+               item.total_price, item.nm_id, item.brand, item.status)
+            IS DISTINCT FROM
+              (EXCLUDED.chrt_id, EXCLUDED.track_number, EXCLUDED.price, EXCLUDED.name, EXCLUDED.sale, EXCLUDED.size,
+               EXCLUDED.total_price, EXCLUDED.nm_id, EXCLUDED.brand, EXCLUDED.status)
+        RETURNING xmax = 0
     `
 
+	var changes itemChangeSet
+	rids := make([]string, 0, len(items))
 	for _, item := range items {
-		_, err := tx.Exec(ctx, query,
+		rids = append(rids, item.Rid)
+
+		var inserted bool
+		err := tx.QueryRow(ctx, query,
 			orderID,
 			item.ChrtID,
 			item.TrackNumber,
@@ -388,13 +804,65 @@ func (c *Consumer) saveItems(ctx context.Context, tx pgx.Tx, orderID int64, item
 			item.NmID,
 			item.Brand,
 			item.Status,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to insert item: %w", err)
+		).Scan(&inserted)
+
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			changes.unchanged = append(changes.unchanged, item.Rid)
+		case err != nil:
+			return itemChangeSet{}, fmt.Errorf("failed to upsert item: %w", err)
+		case inserted:
+			changes.inserted = append(changes.inserted, item.Rid)
+		default:
+			changes.updated = append(changes.updated, item.Rid)
 		}
 	}
 
-	return nil
+	deleteQuery := `
+        DELETE FROM item
+        WHERE order_id = $1 AND rid <> ALL($2::TEXT[])
+        RETURNING rid
+    `
+	rows, err := tx.Query(ctx, deleteQuery, orderID, rids)
+	if err != nil {
+		return itemChangeSet{}, fmt.Errorf("failed to delete removed items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rid string
+		if err := rows.Scan(&rid); err != nil {
+			return itemChangeSet{}, fmt.Errorf("failed to scan removed item: %w", err)
+		}
+		changes.deleted = append(changes.deleted, rid)
+	}
+	if err := rows.Err(); err != nil {
+		return itemChangeSet{}, fmt.Errorf("failed to delete removed items: %w", err)
+	}
+
+	return changes, nil
+}
+
+// itemChangeSet lists the rids of the items changed by a snapshot.
+type itemChangeSet struct {
+	inserted  []string
+	updated   []string
+	unchanged []string
+	deleted   []string
+}
+
+func (cs itemChangeSet) report(orderID int64) {
+	metrics.ConsumerItemChangesTotal.WithLabelValues(metrics.ChangeInserted).Add(float64(len(cs.inserted)))
+	metrics.ConsumerItemChangesTotal.WithLabelValues(metrics.ChangeUpdated).Add(float64(len(cs.updated)))
+	metrics.ConsumerItemChangesTotal.WithLabelValues(metrics.ChangeUnchanged).Add(float64(len(cs.unchanged)))
+	metrics.ConsumerItemChangesTotal.WithLabelValues(metrics.ChangeDeleted).Add(float64(len(cs.deleted)))
+
+	logger.Info("order items synchronized",
+		zap.Int64("order_id", orderID),
+		zap.Strings("inserted", cs.inserted),
+		zap.Strings("updated", cs.updated),
+		zap.Strings("unchanged", cs.unchanged),
+		zap.Strings("deleted", cs.deleted))
 }
 
 func (c *Consumer) commitOffsets(messages []*kafka.Message) error {
@@ -402,29 +870,129 @@ func (c *Consumer) commitOffsets(messages []*kafka.Message) error {
 		return nil
 	}
 
-	offsets := make([]kafka.TopicPartition, 0, len(messages))
-	for _, msg := range messages {
-		offset := kafka.TopicPartition{
-			Topic:     msg.TopicPartition.Topic,
-			Partition: msg.TopicPartition.Partition,
-			Offset:    msg.TopicPartition.Offset + 1,
-		}
-		offsets = append(offsets, offset)
+	offsets := commitPositions(messages)
+	if len(offsets) == 0 {
+		return nil
 	}
 
 	_, err := c.consumer.CommitOffsets(offsets)
 	return err
 }
 
+// commitPositions returns one offset per partition, just past the last
+// message of the batch on it.
+func commitPositions(messages []*kafka.Message) []kafka.TopicPartition {
+	last := make(map[string]kafka.TopicPartition)
+	for _, msg := range messages {
+		if msg == nil {
+			continue
+		}
+		key := partitionKey(msg.TopicPartition)
+		if tp, ok := last[key]; !ok || msg.TopicPartition.Offset > tp.Offset {
+			last[key] = msg.TopicPartition
+		}
+	}
+
+	offsets := make([]kafka.TopicPartition, 0, len(last))
+	for _, tp := range last {
+		offsets = append(offsets, kafka.TopicPartition{
+			Topic:     tp.Topic,
+			Partition: tp.Partition,
+			Offset:    tp.Offset + 1,
+		})
+	}
+	return offsets
+}
+
+// rewind seeks the partitions of a batch that could not be stored back to
+// its first message and drops the messages queued behind it, so the whole
+// batch is consumed again instead of being skipped. The message loop is held
+// until the seek is done, so that no message read before it is queued after
+// the drain. Partitions being revoked are left to their next owner.
+func (c *Consumer) rewind(batch []*kafka.Message, revoked []kafka.TopicPartition) {
+	if revoked == nil {
+		var release chan struct{}
+		batch, release = c.holdMessageLoop(batch)
+		if release != nil {
+			defer close(release)
+		}
+	}
+	batch = c.drainQueued(batch)
+
+	partitions := rewindPositions(batch, revoked)
+	if len(partitions) == 0 {
+		return
+	}
+
+	if _, err := c.consumer.SeekPartitions(partitions); err != nil {
+		logger.Error("failed to rewind partitions",
+			zap.Strings("partitions", formatPartitions(partitions)),
+			zap.Error(err))
+	}
+}
+
+// holdMessageLoop parks the message loop between two reads, queueing into
+// batch whatever it sends meanwhile. Closing the returned channel releases
+// the loop; it is nil when the consumer is stopping.
+func (c *Consumer) holdMessageLoop(batch []*kafka.Message) ([]*kafka.Message, chan struct{}) {
+	release := make(chan struct{})
+	for {
+		select {
+		case c.holdChan <- release:
+			return batch, release
+		case msg := <-c.batchChan:
+			batch = append(batch, msg)
+		case <-c.stopChan:
+			return batch, nil
+		}
+	}
+}
+
+// rewindPositions returns the first offset of every partition in batch,
+// leaving out the revoked ones.
+func rewindPositions(batch []*kafka.Message, revoked []kafka.TopicPartition) []kafka.TopicPartition {
+	skip := make(map[string]struct{}, len(revoked))
+	for _, tp := range revoked {
+		skip[partitionKey(tp)] = struct{}{}
+	}
+
+	first := make(map[string]kafka.TopicPartition)
+	for _, msg := range batch {
+		if msg == nil {
+			continue
+		}
+		key := partitionKey(msg.TopicPartition)
+		if _, ok := skip[key]; ok {
+			continue
+		}
+		if tp, ok := first[key]; !ok || msg.TopicPartition.Offset < tp.Offset {
+			first[key] = msg.TopicPartition
+		}
+	}
+
+	partitions := make([]kafka.TopicPartition, 0, len(first))
+	for _, tp := range first {
+		partitions = append(partitions, tp)
+	}
+	return partitions
+}
+
 func (c *Consumer) Stop() {
 	close(c.stopChan)
-	c.consumer.Close()
 	c.wg.Wait()
+	c.consumer.Close()
+	c.dlq.close()
 	logger.Info("Kafka consumer stopped")
 }
 
-func (c *Consumer) HealthCheck() error {
-	metadata, err := c.consumer.GetMetadata(nil, true, 5000)
+// HealthCheck fetches the cluster metadata, giving up by the deadline of ctx.
+func (c *Consumer) HealthCheck(ctx context.Context) error {
+	timeout, err := timeoutMs(ctx, metadataTimeout)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := c.consumer.GetMetadata(nil, true, timeout)
 	if err != nil {
 		return fmt.Errorf("failed to get metadata: %w", err)
 	}
@@ -435,3 +1003,18 @@ func (c *Consumer) HealthCheck() error {
 
 	return nil
 }
+
+// timeoutMs returns the milliseconds left until the deadline of ctx, or
+// fallback when ctx has none, as a librdkafka request timeout.
+func timeoutMs(ctx context.Context, fallback int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return fallback, nil
+	}
+
+	return max(int(time.Until(deadline).Milliseconds()), 1), nil
+}
@@ -3,16 +3,24 @@ package consumer
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/go-redis/redismock/v8"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/supchaser/wb_l0/internal/app/models"
 	"github.com/supchaser/wb_l0/internal/config"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/kafka/registry"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
 	"github.com/supchaser/wb_l0/internal/utils/logger"
 	"github.com/supchaser/wb_l0/internal/utils/pgxiface"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
 )
 
 func TestMain(m *testing.M) {
@@ -29,10 +37,16 @@ func TestCreateConsumer(t *testing.T) {
 	}{
 		{
 			name:    "successful creation",
-			cfg:     &config.ConsumerConfig{Brokers: []string{"localhost:9092"}, GroupID: "test-group", Topic: "test-topic", AutoOffsetReset: "earliest"},
+			cfg:     &config.ConsumerConfig{Brokers: []string{"localhost:9092"}, GroupID: "test-group", Topic: "test-topic", AutoOffsetReset: "earliest", DLQTopic: "test-topic.dlq"},
 			db:      nil,
 			wantErr: false,
 		},
+		{
+			name:    "missing dlq topic",
+			cfg:     &config.ConsumerConfig{Brokers: []string{"localhost:9092"}, GroupID: "test-group", Topic: "test-topic", AutoOffsetReset: "earliest"},
+			db:      nil,
+			wantErr: true,
+		},
 		{
 			name:    "nil config",
 			cfg:     nil,
@@ -43,7 +57,7 @@ func TestCreateConsumer(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			consumer, err := CreateConsumer(tt.cfg, tt.db)
+			consumer, err := CreateConsumer(tt.cfg, tt.db, nil)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateConsumer() error = %v, wantErr %v", err, tt.wantErr)
@@ -64,8 +78,12 @@ func TestConsumer_ProcessSingleMessage(t *testing.T) {
 	}
 	defer mockDB.Close()
 
+	redisClient, redisMock := redismock.NewClientMock()
+
 	consumer := &Consumer{
-		db: mockDB,
+		db:       mockDB,
+		decoders: defaultDecoders(),
+		cache:    redisClient,
 	}
 
 	fixedTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
@@ -130,15 +148,18 @@ func TestConsumer_ProcessSingleMessage(t *testing.T) {
 		},
 	}
 
+	mockDB.ExpectBegin()
 	mockDB.ExpectBegin()
 	mockDB.ExpectQuery(`INSERT INTO "order"`).
 		WithArgs(
 			order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
-			order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.OofShard, fixedTime,
+			order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.OofShard, fixedTime, int64(123), versionFromOffset, pgxmock.AnyArg(),
 		).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(1)))
 	mockDB.ExpectExec(`INSERT INTO delivery`).
 		WithArgs(int64(1), order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City,
+			order.Delivery.Address, order.Delivery.Region, order.Delivery.Email,
+			order.Delivery.Name, order.Delivery.Phone, order.Delivery.City,
 			order.Delivery.Address, order.Delivery.Region, order.Delivery.Email).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 	mockDB.ExpectExec(`INSERT INTO payment`).
@@ -146,13 +167,20 @@ func TestConsumer_ProcessSingleMessage(t *testing.T) {
 			order.Payment.Amount, order.Payment.PaymentDt, order.Payment.Bank, order.Payment.DeliveryCost,
 			order.Payment.GoodsTotal, order.Payment.CustomFee).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
-	mockDB.ExpectExec(`DELETE FROM item`).WithArgs(int64(1)).WillReturnResult(pgxmock.NewResult("DELETE", 0))
-	mockDB.ExpectExec(`INSERT INTO item`).
+	mockDB.ExpectExec(`INSERT INTO item_status_history`).
+		WithArgs(int64(1), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectQuery(`INSERT INTO item \(`).
 		WithArgs(int64(1), order.Items[0].ChrtID, order.Items[0].TrackNumber, order.Items[0].Price, order.Items[0].Rid,
 			order.Items[0].Name, order.Items[0].Sale, order.Items[0].Size, order.Items[0].TotalPrice,
 			order.Items[0].NmID, order.Items[0].Brand, order.Items[0].Status).
-		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		WillReturnRows(pgxmock.NewRows([]string{"inserted"}).AddRow(true))
+	mockDB.ExpectQuery(`DELETE FROM item`).
+		WithArgs(int64(1), []string{order.Items[0].Rid}).
+		WillReturnRows(pgxmock.NewRows([]string{"rid"}))
 	mockDB.ExpectCommit()
+	mockDB.ExpectCommit()
+	redisMock.ExpectDel("order:test-order-123").SetVal(1)
 
 	messages := []*kafka.Message{msg}
 	err = consumer.processMessageBatch(messages)
@@ -163,6 +191,9 @@ func TestConsumer_ProcessSingleMessage(t *testing.T) {
 	if err := mockDB.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
+	if err := redisMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled redis expectations: %s", err)
+	}
 }
 
 func TestConsumer_ProcessSingleMessage_InvalidJSON(t *testing.T) {
@@ -173,7 +204,8 @@ func TestConsumer_ProcessSingleMessage_InvalidJSON(t *testing.T) {
 	defer mockDB.Close()
 
 	consumer := &Consumer{
-		db: mockDB,
+		db:       mockDB,
+		decoders: defaultDecoders(),
 	}
 
 	msg := &kafka.Message{
@@ -188,7 +220,7 @@ func TestConsumer_ProcessSingleMessage_InvalidJSON(t *testing.T) {
 		t.Fatalf("failed to begin transaction: %v", err)
 	}
 
-	err = consumer.processSingleMessage(ctx, tx, msg)
+	_, err = consumer.processSingleMessage(ctx, tx, msg)
 	if err == nil {
 		t.Error("expected error for invalid JSON, but got none")
 	}
@@ -208,7 +240,8 @@ func TestConsumer_ProcessSingleMessage_ValidationFailed(t *testing.T) {
 	defer mockDB.Close()
 
 	consumer := &Consumer{
-		db: mockDB,
+		db:       mockDB,
+		decoders: defaultDecoders(),
 	}
 
 	invalidOrder := models.OrderRequest{
@@ -227,7 +260,7 @@ func TestConsumer_ProcessSingleMessage_ValidationFailed(t *testing.T) {
 		t.Fatalf("failed to begin transaction: %v", err)
 	}
 
-	err = consumer.processSingleMessage(ctx, tx, msg)
+	_, err = consumer.processSingleMessage(ctx, tx, msg)
 	if err == nil {
 		t.Error("expected validation error, but got none")
 	}
@@ -247,7 +280,8 @@ func TestConsumer_ProcessMessageBatch(t *testing.T) {
 	defer mockDB.Close()
 
 	consumer := &Consumer{
-		db: mockDB,
+		db:       mockDB,
+		decoders: defaultDecoders(),
 	}
 
 	fixedTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
@@ -371,13 +405,14 @@ func TestConsumer_ProcessMessageBatch(t *testing.T) {
 		},
 	}
 
+	mockDB.ExpectBegin()
 	mockDB.ExpectBegin()
 
 	mockDB.ExpectQuery(`INSERT INTO "order"`).
 		WithArgs(
 			order1.OrderUID, order1.TrackNumber, order1.Entry, order1.Locale,
 			order1.InternalSignature, order1.CustomerID, order1.DeliveryService,
-			order1.Shardkey, order1.SmID, order1.OofShard, order1.DateCreated,
+			order1.Shardkey, order1.SmID, order1.OofShard, order1.DateCreated, int64(1), versionFromOffset, pgxmock.AnyArg(),
 		).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(1)))
 
@@ -385,6 +420,8 @@ func TestConsumer_ProcessMessageBatch(t *testing.T) {
 		WithArgs(
 			int64(1), order1.Delivery.Name, order1.Delivery.Phone, order1.Delivery.Zip,
 			order1.Delivery.City, order1.Delivery.Address, order1.Delivery.Region, order1.Delivery.Email,
+			order1.Delivery.Name, order1.Delivery.Phone, order1.Delivery.City,
+			order1.Delivery.Address, order1.Delivery.Region, order1.Delivery.Email,
 		).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
@@ -396,23 +433,29 @@ func TestConsumer_ProcessMessageBatch(t *testing.T) {
 		).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
-	mockDB.ExpectExec(`DELETE FROM item`).
-		WithArgs(int64(1)).
-		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mockDB.ExpectExec(`INSERT INTO item_status_history`).
+		WithArgs(int64(1), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
-	mockDB.ExpectExec(`INSERT INTO item`).
+	mockDB.ExpectQuery(`INSERT INTO item \(`).
 		WithArgs(
 			int64(1), order1.Items[0].ChrtID, order1.Items[0].TrackNumber, order1.Items[0].Price,
 			order1.Items[0].Rid, order1.Items[0].Name, order1.Items[0].Sale, order1.Items[0].Size,
 			order1.Items[0].TotalPrice, order1.Items[0].NmID, order1.Items[0].Brand, order1.Items[0].Status,
 		).
-		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		WillReturnRows(pgxmock.NewRows([]string{"inserted"}).AddRow(true))
+
+	mockDB.ExpectQuery(`DELETE FROM item`).
+		WithArgs(int64(1), []string{order1.Items[0].Rid}).
+		WillReturnRows(pgxmock.NewRows([]string{"rid"}))
+	mockDB.ExpectCommit()
 
+	mockDB.ExpectBegin()
 	mockDB.ExpectQuery(`INSERT INTO "order"`).
 		WithArgs(
 			order2.OrderUID, order2.TrackNumber, order2.Entry, order2.Locale,
 			order2.InternalSignature, order2.CustomerID, order2.DeliveryService,
-			order2.Shardkey, order2.SmID, order2.OofShard, order2.DateCreated,
+			order2.Shardkey, order2.SmID, order2.OofShard, order2.DateCreated, int64(2), versionFromOffset, pgxmock.AnyArg(),
 		).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(2)))
 
@@ -420,6 +463,8 @@ func TestConsumer_ProcessMessageBatch(t *testing.T) {
 		WithArgs(
 			int64(2), order2.Delivery.Name, order2.Delivery.Phone, order2.Delivery.Zip,
 			order2.Delivery.City, order2.Delivery.Address, order2.Delivery.Region, order2.Delivery.Email,
+			order2.Delivery.Name, order2.Delivery.Phone, order2.Delivery.City,
+			order2.Delivery.Address, order2.Delivery.Region, order2.Delivery.Email,
 		).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
@@ -431,18 +476,23 @@ func TestConsumer_ProcessMessageBatch(t *testing.T) {
 		).
 		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
-	mockDB.ExpectExec(`DELETE FROM item`).
-		WithArgs(int64(2)).
-		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mockDB.ExpectExec(`INSERT INTO item_status_history`).
+		WithArgs(int64(2), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
-	mockDB.ExpectExec(`INSERT INTO item`).
+	mockDB.ExpectQuery(`INSERT INTO item \(`).
 		WithArgs(
 			int64(2), order2.Items[0].ChrtID, order2.Items[0].TrackNumber, order2.Items[0].Price,
 			order2.Items[0].Rid, order2.Items[0].Name, order2.Items[0].Sale, order2.Items[0].Size,
 			order2.Items[0].TotalPrice, order2.Items[0].NmID, order2.Items[0].Brand, order2.Items[0].Status,
 		).
-		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		WillReturnRows(pgxmock.NewRows([]string{"inserted"}).AddRow(true))
 
+	mockDB.ExpectQuery(`DELETE FROM item`).
+		WithArgs(int64(2), []string{order2.Items[0].Rid}).
+		WillReturnRows(pgxmock.NewRows([]string{"rid"}))
+
+	mockDB.ExpectCommit()
 	mockDB.ExpectCommit()
 
 	err = consumer.processMessageBatch(messages)
@@ -482,3 +532,1612 @@ func TestConsumer_Stop(t *testing.T) {
 func stringPtr(s string) *string {
 	return &s
 }
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func TestPartitionLag(t *testing.T) {
+	tests := []struct {
+		name      string
+		committed int64
+		low       int64
+		high      int64
+		want      int64
+	}{
+		{name: "caught up", committed: 100, low: 0, high: 100, want: 0},
+		{name: "behind", committed: 40, low: 0, high: 100, want: 60},
+		{name: "nothing committed", committed: int64(kafka.OffsetInvalid), low: 10, high: 100, want: 90},
+		{name: "committed past high watermark", committed: 120, low: 0, high: 100, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := partitionLag(tt.committed, tt.low, tt.high); got != tt.want {
+				t.Errorf("partitionLag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsumer_Status(t *testing.T) {
+	topic := "test-topic"
+	otherTopic := "other-topic"
+	consumer := &Consumer{
+		config: &config.ConsumerConfig{GroupID: "test-group", Topic: topic, MaxReadyLag: 50},
+	}
+
+	status := consumer.Status()
+	if status.LastBatchAt != nil || status.LagUpdatedAt != nil {
+		t.Errorf("expected empty status before any batch, got %+v", status)
+	}
+
+	consumer.recordBatch([]*kafka.Message{
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 10}},
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 12}},
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 3}},
+		{TopicPartition: kafka.TopicPartition{Topic: &otherTopic, Partition: 0, Offset: 99}},
+	})
+	consumer.updateLag([]PartitionStatus{
+		{Topic: topic, Partition: 1, CommittedOffset: 4, HighWatermark: 100, Lag: 96},
+		{Topic: topic, Partition: 0, CommittedOffset: 13, HighWatermark: 13, Lag: 0},
+	})
+	consumer.setPaused(true)
+
+	status = consumer.Status()
+	if status.GroupID != "test-group" || status.Topic != topic {
+		t.Errorf("unexpected group/topic: %s/%s", status.GroupID, status.Topic)
+	}
+	if !status.Paused {
+		t.Error("expected consumer to be reported as paused")
+	}
+	if status.TotalLag != 96 {
+		t.Errorf("TotalLag = %d, want 96", status.TotalLag)
+	}
+	if len(status.Partitions) != 2 || status.Partitions[0].Partition != 0 {
+		t.Fatalf("unexpected partitions: %+v", status.Partitions)
+	}
+	if status.Partitions[0].LastProcessedOffset != 12 || status.Partitions[1].LastProcessedOffset != 3 {
+		t.Errorf("unexpected last processed offsets: %+v", status.Partitions)
+	}
+	if status.LastBatchAt == nil || status.LagUpdatedAt == nil {
+		t.Error("expected batch and lag timestamps to be set")
+	}
+
+	if err := consumer.checkLag(status.TotalLag); err == nil {
+		t.Error("expected lag check to fail when lag exceeds limit")
+	}
+
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	if err := consumer.LagCheck(expired); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected LagCheck to give up on an expired context, got %v", err)
+	}
+}
+
+func TestTimeoutMs(t *testing.T) {
+	expired, cancelExpired := context.WithTimeout(context.Background(), -time.Second)
+	defer cancelExpired()
+	withDeadline, cancelDeadline := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancelDeadline()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		min     int
+		max     int
+		wantErr bool
+	}{
+		{name: "no deadline", ctx: context.Background(), min: 5000, max: 5000},
+		{name: "deadline", ctx: withDeadline, min: 1, max: 2000},
+		{name: "expired", ctx: expired, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := timeoutMs(tt.ctx, 5000)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("timeoutMs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got < tt.min || got > tt.max) {
+				t.Errorf("timeoutMs() = %d, want between %d and %d", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestDropPartitions(t *testing.T) {
+	topic := "test-topic"
+	batch := []*kafka.Message{
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 1}},
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 1}},
+		nil,
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 2}},
+	}
+
+	kept := dropPartitions(batch, []kafka.TopicPartition{{Topic: &topic, Partition: 0}})
+
+	if len(kept) != 1 {
+		t.Fatalf("expected 1 message to be kept, got %d", len(kept))
+	}
+	if kept[0].TopicPartition.Partition != 1 {
+		t.Errorf("expected message from partition 1 to be kept, got partition %d", kept[0].TopicPartition.Partition)
+	}
+}
+
+func TestCommitPositions(t *testing.T) {
+	topic := "test-topic"
+	batch := []*kafka.Message{
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 3}},
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 7}},
+		nil,
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 5}},
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 4}},
+	}
+
+	offsets := commitPositions(batch)
+
+	if len(offsets) != 2 {
+		t.Fatalf("expected one offset per partition, got %d", len(offsets))
+	}
+	for _, tp := range offsets {
+		want := map[int32]kafka.Offset{0: 6, 1: 8}[tp.Partition]
+		if tp.Offset != want {
+			t.Errorf("expected offset %d for partition %d, got %d", want, tp.Partition, tp.Offset)
+		}
+	}
+}
+
+func TestRewindPositions(t *testing.T) {
+	topic := "test-topic"
+	batch := []*kafka.Message{
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 5}},
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 7}},
+		nil,
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 3}},
+	}
+
+	partitions := rewindPositions(batch, nil)
+	if len(partitions) != 2 {
+		t.Fatalf("expected 2 partitions to rewind, got %d", len(partitions))
+	}
+	for _, tp := range partitions {
+		want := map[int32]kafka.Offset{0: 3, 1: 7}[tp.Partition]
+		if tp.Offset != want {
+			t.Errorf("expected partition %d to rewind to %d, got %d", tp.Partition, want, tp.Offset)
+		}
+	}
+
+	partitions = rewindPositions(batch, []kafka.TopicPartition{{Topic: &topic, Partition: 0}})
+	if len(partitions) != 1 || partitions[0].Partition != 1 {
+		t.Errorf("expected only partition 1 to rewind, got %v", formatPartitions(partitions))
+	}
+}
+
+func TestConsumer_HoldMessageLoop(t *testing.T) {
+	topic := "test-topic"
+	consumer := &Consumer{
+		stopChan:  make(chan struct{}),
+		batchChan: make(chan *kafka.Message, 1),
+		holdChan:  make(chan chan struct{}),
+	}
+
+	held := make(chan struct{})
+	released := make(chan struct{})
+	go func() {
+		// Stands in for the message loop blocked on a full batch channel.
+		consumer.batchChan <- &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: 1}}
+		consumer.batchChan <- &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: 2}}
+		release := <-consumer.holdChan
+		close(held)
+		<-release
+		close(released)
+	}()
+
+	batch, release := consumer.holdMessageLoop(nil)
+	<-held
+	batch = consumer.drainQueued(batch)
+
+	if len(batch) != 2 {
+		t.Errorf("expected both messages sent before the hold to be queued, got %d", len(batch))
+	}
+
+	select {
+	case <-released:
+		t.Fatal("expected the message loop to stay held until released")
+	default:
+	}
+
+	close(release)
+	<-released
+
+	close(consumer.stopChan)
+	if _, release := consumer.holdMessageLoop(nil); release != nil {
+		t.Error("expected no hold once the consumer is stopping")
+	}
+}
+
+func TestConsumer_FlushBeforeRevoke(t *testing.T) {
+	topic := "test-topic"
+
+	tests := []struct {
+		name      string
+		lost      bool
+		mockSetup func(mock pgxmock.PgxPoolIface)
+	}{
+		{
+			name: "revoked partitions flush queued messages",
+			lost: false,
+			mockSetup: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:      "lost partitions drop queued messages",
+			lost:      true,
+			mockSetup: func(mock pgxmock.PgxPoolIface) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB, err := pgxmock.NewPool()
+			if err != nil {
+				t.Fatalf("failed to create mock: %v", err)
+			}
+			defer mockDB.Close()
+			tt.mockSetup(mockDB)
+
+			consumer := &Consumer{
+				db:        mockDB,
+				decoders:  defaultDecoders(),
+				config:    &config.ConsumerConfig{EnableAutoCommit: true},
+				stopChan:  make(chan struct{}),
+				batchChan: make(chan *kafka.Message, batchSize),
+				flushChan: make(chan flushRequest),
+				holdChan:  make(chan chan struct{}),
+			}
+
+			consumer.batchChan <- &kafka.Message{
+				Value:          []byte("invalid json"),
+				TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 5},
+			}
+
+			consumer.wg.Add(1)
+			go consumer.batchProcessor()
+
+			consumer.flushBeforeRevoke([]kafka.TopicPartition{{Topic: &topic, Partition: 0}}, tt.lost)
+
+			if len(consumer.batchChan) != 0 {
+				t.Errorf("expected batch channel to be drained, %d messages left", len(consumer.batchChan))
+			}
+
+			close(consumer.stopChan)
+			consumer.wg.Wait()
+
+			if err := mockDB.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestOrderVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		msg        *kafka.Message
+		order      models.OrderRequest
+		want       int64
+		wantSource int16
+		wantErr    bool
+	}{
+		{
+			name: "header takes precedence",
+			msg: &kafka.Message{
+				Headers:        []kafka.Header{{Key: codec.HeaderOrderVersion, Value: []byte("42")}},
+				Timestamp:      time.UnixMilli(1000),
+				TopicPartition: kafka.TopicPartition{Offset: 7},
+			},
+			order:      models.OrderRequest{Version: 5},
+			want:       42,
+			wantSource: versionFromOrder,
+		},
+		{
+			name:    "invalid header",
+			msg:     &kafka.Message{Headers: []kafka.Header{{Key: codec.HeaderOrderVersion, Value: []byte("abc")}}},
+			wantErr: true,
+		},
+		{
+			name: "replay keeps the stored source",
+			msg: &kafka.Message{Headers: []kafka.Header{
+				{Key: codec.HeaderReplay, Value: []byte("incident-42")},
+				{Key: codec.HeaderOrderVersion, Value: []byte("1000")},
+				{Key: codec.HeaderOrderVersionSource, Value: []byte("2")},
+			}},
+			want:       1000,
+			wantSource: versionFromTimestamp,
+		},
+		{
+			name: "source header ignored outside a replay",
+			msg: &kafka.Message{Headers: []kafka.Header{
+				{Key: codec.HeaderOrderVersion, Value: []byte("1000")},
+				{Key: codec.HeaderOrderVersionSource, Value: []byte("1")},
+			}},
+			want:       1000,
+			wantSource: versionFromOrder,
+		},
+		{
+			name: "invalid replayed source",
+			msg: &kafka.Message{Headers: []kafka.Header{
+				{Key: codec.HeaderReplay, Value: []byte("incident-42")},
+				{Key: codec.HeaderOrderVersion, Value: []byte("1000")},
+				{Key: codec.HeaderOrderVersionSource, Value: []byte("9")},
+			}},
+			wantErr: true,
+		},
+		{
+			name:       "payload field",
+			msg:        &kafka.Message{Timestamp: time.UnixMilli(1000), TopicPartition: kafka.TopicPartition{Offset: 7}},
+			order:      models.OrderRequest{Version: 5},
+			want:       5,
+			wantSource: versionFromOrder,
+		},
+		{
+			name:       "message timestamp",
+			msg:        &kafka.Message{Timestamp: time.UnixMilli(1000), TopicPartition: kafka.TopicPartition{Offset: 7}},
+			want:       1000,
+			wantSource: versionFromTimestamp,
+		},
+		{
+			name:       "offset fallback",
+			msg:        &kafka.Message{TopicPartition: kafka.TopicPartition{Offset: 7}},
+			want:       7,
+			wantSource: versionFromOffset,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, source, err := orderVersion(sourceOf(tt.msg), &tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("orderVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("orderVersion() = %v, want %v", got, tt.want)
+			}
+			if source != tt.wantSource {
+				t.Errorf("orderVersion() source = %v, want %v", source, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestConsumer_ProcessSingleMessage_StaleVersion(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	consumer := &Consumer{
+		db:       mockDB,
+		decoders: defaultDecoders(),
+	}
+
+	order := newTestOrderRequest("stale-order")
+	order.Version = 3
+	msgValue, _ := json.Marshal(order)
+	msg := &kafka.Message{
+		Value: msgValue,
+		TopicPartition: kafka.TopicPartition{
+			Topic:     stringPtr("test-topic"),
+			Partition: 0,
+			Offset:    10,
+		},
+	}
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`INSERT INTO "order".*\) < \(EXCLUDED.version_source, EXCLUDED.version\)`).
+		WithArgs(
+			order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+			order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.OofShard,
+			order.DateCreated, int64(3), versionFromOrder, "[]",
+		).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+	ctx := context.Background()
+	tx, err := mockDB.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+
+	_, err = consumer.processSingleMessage(ctx, tx, msg)
+	if !errors.Is(err, errs.ErrStaleVersion) {
+		t.Errorf("expected stale version error, got %v", err)
+	}
+
+	tx.Rollback(ctx)
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestConsumer_ProcessSingleMessage_ReplaySameVersion(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	consumer := &Consumer{
+		db:       mockDB,
+		decoders: defaultDecoders(),
+	}
+
+	order := newTestOrderRequest("replayed-order")
+	msgValue, _ := json.Marshal(order)
+	msg := &kafka.Message{
+		Value: msgValue,
+		Headers: []kafka.Header{
+			{Key: codec.HeaderReplay, Value: []byte("incident-42")},
+			{Key: codec.HeaderOrderVersion, Value: []byte("1704110400000")},
+			{Key: codec.HeaderOrderVersionSource, Value: []byte("2")},
+		},
+		Timestamp: time.Now(),
+		TopicPartition: kafka.TopicPartition{
+			Topic:     stringPtr("test-topic"),
+			Partition: 0,
+			Offset:    12,
+		},
+	}
+
+	// The order is stored at the same version, so only a replay rewrites it.
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`INSERT INTO "order".*\) <= \(EXCLUDED.version_source, EXCLUDED.version\)`).
+		WithArgs(
+			order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+			order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.OofShard,
+			order.DateCreated, int64(1704110400000), versionFromTimestamp, "[]",
+		).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(5)))
+	mockDB.ExpectExec(`INSERT INTO delivery`).
+		WithArgs(int64(5), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectExec(`INSERT INTO payment`).
+		WithArgs(int64(5), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectExec(`INSERT INTO item_status_history`).
+		WithArgs(int64(5), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mockDB.ExpectQuery(`INSERT INTO item \(`).
+		WithArgs(int64(5), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"inserted"}).AddRow(false))
+	mockDB.ExpectQuery(`DELETE FROM item`).
+		WithArgs(int64(5), []string{"rid123"}).
+		WillReturnRows(pgxmock.NewRows([]string{"rid"}))
+
+	ctx := context.Background()
+	tx, err := mockDB.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+
+	if _, err := consumer.processSingleMessage(ctx, tx, msg); err != nil {
+		t.Errorf("processSingleMessage() failed: %v", err)
+	}
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestConsumer_ApplyOrder_ConsistencyRules(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	rules, err := validate.CreateRuleSet(map[string]validate.Severity{
+		validate.RuleAmountMatchesComponents: validate.SeverityReject,
+	})
+	if err != nil {
+		t.Fatalf("failed to create rule set: %v", err)
+	}
+
+	consumer := &Consumer{
+		db:       mockDB,
+		decoders: defaultDecoders(),
+		rules:    rules,
+	}
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{
+			Topic:     stringPtr("test-topic"),
+			Partition: 0,
+			Offset:    11,
+		},
+	}
+
+	mockDB.ExpectBegin()
+	ctx := context.Background()
+	tx, err := mockDB.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+
+	rejected := newTestOrderRequest("rejected-order")
+	rejected.Payment.Amount = 100
+	err = consumer.applyOrder(ctx, tx, sourceOf(msg), &rejected)
+	if !errors.Is(err, errs.ErrValidation) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+
+	warned := newTestOrderRequest("warned-order")
+	warned.Version = 4
+	warned.Items[0].TrackNumber = "OTHER123"
+	mockDB.ExpectQuery(`INSERT INTO "order"`).
+		WithArgs(
+			warned.OrderUID, warned.TrackNumber, warned.Entry, warned.Locale, warned.InternalSignature,
+			warned.CustomerID, warned.DeliveryService, warned.Shardkey, warned.SmID, warned.OofShard,
+			warned.DateCreated, int64(4), versionFromOrder,
+			`[{"rule":"item_track_number_matches_order","path":"items[0].track_number","message":"items[0].track_number \"OTHER123\" does not match order track_number \"TRACK123\""}]`,
+		).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+	err = consumer.applyOrder(ctx, tx, sourceOf(msg), &warned)
+	if !errors.Is(err, errs.ErrStaleVersion) {
+		t.Errorf("expected stale version error, got %v", err)
+	}
+
+	tx.Rollback(ctx)
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestConsumer_SaveOrderToDB_NormalizedDelivery(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	consumer := &Consumer{db: mockDB, decoders: defaultDecoders()}
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: stringPtr("test-topic"), Offset: 12},
+	}
+
+	mockDB.ExpectBegin()
+	ctx := context.Background()
+	tx, err := mockDB.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+
+	order := newTestOrderRequest("normalized-order")
+	order.Version = 5
+	order.Locale = models.LocaleRU
+	order.Delivery.Phone = "8 (999) 123-45-67"
+	order.Delivery.Email = "John@Example.com"
+	order.Delivery.Name = "John Doe "
+
+	mockDB.ExpectQuery(`INSERT INTO "order"`).
+		WithArgs(
+			order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+			order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.OofShard,
+			order.DateCreated, int64(5), versionFromOrder, pgxmock.AnyArg(),
+		).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	mockDB.ExpectExec(`INSERT INTO delivery`).
+		WithArgs(int64(7), "John Doe", "+79991234567", order.Delivery.Zip, order.Delivery.City,
+			order.Delivery.Address, order.Delivery.Region, "john@example.com",
+			"John Doe ", "8 (999) 123-45-67", order.Delivery.City,
+			order.Delivery.Address, order.Delivery.Region, "John@Example.com").
+		WillReturnError(errors.New("stop"))
+
+	err = consumer.applyOrder(ctx, tx, sourceOf(msg), &order)
+	if err == nil {
+		t.Error("expected error from delivery insert")
+	}
+
+	invalid := newTestOrderRequest("invalid-phone")
+	invalid.Delivery.Phone = "12-34"
+	err = consumer.applyOrder(ctx, tx, sourceOf(msg), &invalid)
+	violations, ok := validate.AsValidationErrors(err)
+	if !ok || violations[0].Path != "delivery.phone" {
+		t.Errorf("expected delivery.phone violation, got %v", err)
+	}
+
+	tx.Rollback(ctx)
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMessageEventType(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     *kafka.Message
+		want    models.EventTypeEnum
+		wantErr error
+	}{
+		{
+			name: "snapshot without header",
+			msg:  &kafka.Message{Value: []byte(`{}`)},
+			want: models.EventOrderUpdated,
+		},
+		{
+			name: "tombstone",
+			msg:  &kafka.Message{Key: []byte("order-1")},
+			want: models.EventOrderDeleted,
+		},
+		{
+			name: "explicit delete",
+			msg: &kafka.Message{
+				Value:   []byte(`{}`),
+				Headers: []kafka.Header{{Key: "event-type", Value: []byte("order.deleted")}},
+			},
+			want: models.EventOrderDeleted,
+		},
+		{
+			name: "unknown event type",
+			msg: &kafka.Message{
+				Value:   []byte(`{}`),
+				Headers: []kafka.Header{{Key: "event-type", Value: []byte("order.archived")}},
+			},
+			wantErr: errs.ErrUnknownType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := messageEventType(tt.msg)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("messageEventType() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("messageEventType() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("messageEventType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsumer_ProcessMessageBatch_Delete(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	redisClient, redisMock := redismock.NewClientMock()
+
+	consumer := &Consumer{
+		db:       mockDB,
+		decoders: defaultDecoders(),
+		cache:    redisClient,
+	}
+
+	messages := []*kafka.Message{
+		{
+			Key: []byte("deleted-order"),
+			TopicPartition: kafka.TopicPartition{
+				Topic:     stringPtr("test-topic"),
+				Partition: 0,
+				Offset:    1,
+			},
+		},
+		{
+			Key:     []byte("missing-order"),
+			Value:   []byte(`{"order_uid":"missing-order"}`),
+			Headers: []kafka.Header{{Key: "event-type", Value: []byte("order.deleted")}},
+			TopicPartition: kafka.TopicPartition{
+				Topic:     stringPtr("test-topic"),
+				Partition: 0,
+				Offset:    2,
+			},
+		},
+	}
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectBegin()
+	// The tombstone keeps the stored version when it is newer than the delete.
+	mockDB.ExpectQuery(`DELETE FROM "order"`).
+		WithArgs("deleted-order").
+		WillReturnRows(pgxmock.NewRows([]string{"version_source", "version"}).AddRow(versionFromTimestamp, int64(1704110400000)))
+	mockDB.ExpectExec(`INSERT INTO order_tombstone`).
+		WithArgs("deleted-order", int64(1704110400000), versionFromTimestamp).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectCommit()
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`DELETE FROM "order"`).
+		WithArgs("missing-order").
+		WillReturnRows(pgxmock.NewRows([]string{"version_source", "version"}))
+	mockDB.ExpectExec(`INSERT INTO order_tombstone`).
+		WithArgs("missing-order", int64(2), versionFromOffset).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectCommit()
+	mockDB.ExpectCommit()
+	redisMock.ExpectDel("order:deleted-order", "order:missing-order").SetVal(1)
+
+	if err := consumer.processMessageBatch(messages); err != nil {
+		t.Errorf("processMessageBatch() failed: %v", err)
+	}
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+	if err := redisMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled redis expectations: %s", err)
+	}
+}
+
+func TestConsumer_ProcessMessageBatch_DeleteThenOlderSnapshot(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	redisClient, redisMock := redismock.NewClientMock()
+
+	consumer := &Consumer{
+		db:       mockDB,
+		decoders: defaultDecoders(),
+		cache:    redisClient,
+	}
+
+	// The snapshot was taken before the delete but is delivered after it.
+	order := newTestOrderRequest("deleted-order")
+	deletedAt := time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC)
+	snapshotTime := deletedAt.Add(-time.Hour)
+	tombstone := newTestTombstone(order.OrderUID, 1)
+	tombstone.Timestamp = deletedAt
+	msgValue, _ := json.Marshal(order)
+	snapshot := &kafka.Message{
+		Value:     msgValue,
+		Timestamp: snapshotTime,
+		TopicPartition: kafka.TopicPartition{
+			Topic:     stringPtr("test-topic"),
+			Partition: 0,
+			Offset:    2,
+		},
+	}
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`DELETE FROM "order"`).
+		WithArgs(order.OrderUID).
+		WillReturnRows(pgxmock.NewRows([]string{"version_source", "version"}))
+	mockDB.ExpectExec(`INSERT INTO order_tombstone`).
+		WithArgs(order.OrderUID, deletedAt.UnixMilli(), versionFromTimestamp).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectCommit()
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`INSERT INTO "order".*WHERE NOT EXISTS \(\s*SELECT 1 FROM order_tombstone`).
+		WithArgs(
+			order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+			order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.OofShard,
+			order.DateCreated, snapshotTime.UnixMilli(), versionFromTimestamp, "[]",
+		).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}))
+	mockDB.ExpectRollback()
+	mockDB.ExpectCommit()
+	redisMock.ExpectDel("order:deleted-order").SetVal(1)
+
+	if err := consumer.processMessageBatch([]*kafka.Message{tombstone, snapshot}); err != nil {
+		t.Errorf("processMessageBatch() failed: %v", err)
+	}
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+	if err := redisMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled redis expectations: %s", err)
+	}
+}
+
+func newTestTombstone(orderUID string, offset int64) *kafka.Message {
+	return &kafka.Message{
+		Key: []byte(orderUID),
+		TopicPartition: kafka.TopicPartition{
+			Topic:     stringPtr("test-topic"),
+			Partition: 0,
+			Offset:    kafka.Offset(offset),
+		},
+	}
+}
+
+func TestConsumer_ProcessMessageBatch_FailedMessageIsRolledBackAlone(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	redisClient, redisMock := redismock.NewClientMock()
+
+	consumer := &Consumer{
+		db:       mockDB,
+		decoders: defaultDecoders(),
+		cache:    redisClient,
+	}
+
+	messages := []*kafka.Message{
+		newTestTombstone("broken-order", 1),
+		newTestTombstone("deleted-order", 2),
+	}
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`DELETE FROM "order"`).
+		WithArgs("broken-order").
+		WillReturnError(errors.New("deadlock detected"))
+	mockDB.ExpectRollback()
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`DELETE FROM "order"`).
+		WithArgs("deleted-order").
+		WillReturnRows(pgxmock.NewRows([]string{"version_source", "version"}).AddRow(versionFromOffset, int64(1)))
+	mockDB.ExpectExec(`INSERT INTO order_tombstone`).
+		WithArgs("deleted-order", int64(2), versionFromOffset).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectCommit()
+	mockDB.ExpectCommit()
+	redisMock.ExpectDel("order:deleted-order").SetVal(1)
+
+	if err := consumer.processMessageBatch(messages); err != nil {
+		t.Errorf("processMessageBatch() failed: %v", err)
+	}
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+	if err := redisMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled redis expectations: %s", err)
+	}
+}
+
+func TestConsumer_ProcessMessageBatch_CommitFailure(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	redisClient, redisMock := redismock.NewClientMock()
+
+	consumer := &Consumer{
+		db:       mockDB,
+		decoders: defaultDecoders(),
+		cache:    redisClient,
+	}
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`DELETE FROM "order"`).
+		WithArgs("deleted-order").
+		WillReturnRows(pgxmock.NewRows([]string{"version_source", "version"}).AddRow(versionFromOffset, int64(0)))
+	mockDB.ExpectExec(`INSERT INTO order_tombstone`).
+		WithArgs("deleted-order", int64(1), versionFromOffset).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectCommit()
+	mockDB.ExpectCommit().WillReturnError(errors.New("connection reset"))
+	mockDB.ExpectRollback()
+	redisMock.ExpectDel("order:deleted-order").SetVal(1)
+
+	err = consumer.processMessageBatch([]*kafka.Message{newTestTombstone("deleted-order", 1)})
+	if err == nil {
+		t.Error("expected commit error, got none")
+	}
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+	if err := redisMock.ExpectationsWereMet(); err == nil {
+		t.Error("cache was evicted for an uncommitted batch")
+	}
+}
+
+func TestConsumer_ProcessMessageBatch_SavepointFailureAbortsBatch(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	consumer := &Consumer{
+		db:       mockDB,
+		decoders: defaultDecoders(),
+	}
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectBegin().WillReturnError(errors.New("current transaction is aborted"))
+	mockDB.ExpectRollback()
+
+	err = consumer.processMessageBatch([]*kafka.Message{newTestTombstone("deleted-order", 1)})
+	if !errors.Is(err, errBatchAborted) {
+		t.Errorf("expected batch aborted error, got %v", err)
+	}
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func newTestEnvelopeMessage(t *testing.T, eventType models.EventTypeEnum, orderUID string, payload any, offset int64) *kafka.Message {
+	t.Helper()
+
+	payloadInBytes, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+
+	value, err := json.Marshal(models.EventEnvelope{
+		EventType:  eventType,
+		EventID:    fmt.Sprintf("event-%d", offset),
+		OccurredAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Payload:    payloadInBytes,
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal envelope: %v", err)
+	}
+
+	return &kafka.Message{
+		Key:   []byte(orderUID),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: "content-type", Value: []byte("application/vnd.wb-l0.event+json")},
+			{Key: "event-type", Value: []byte(eventType)},
+		},
+		TopicPartition: kafka.TopicPartition{
+			Topic:     stringPtr("test-topic"),
+			Partition: 0,
+			Offset:    kafka.Offset(offset),
+		},
+	}
+}
+
+func TestConsumer_ProcessMessageBatch_Events(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	redisClient, redisMock := redismock.NewClientMock()
+
+	consumer := &Consumer{
+		db:       mockDB,
+		decoders: defaultDecoders(),
+		cache:    redisClient,
+	}
+
+	statusChanged := models.ItemStatusChangedEvent{OrderUID: "event-order", Rid: "rid123", Status: 300}
+	paymentCaptured := models.PaymentCapturedEvent{
+		OrderUID:    "event-order",
+		Transaction: "txn-1",
+		Amount:      1500,
+		PaymentDt:   1704110400,
+		Bank:        "alpha",
+	}
+	addressChanged := models.DeliveryAddressChangedEvent{
+		OrderUID: "event-order",
+		Zip:      "123456",
+		City:     "Moscow",
+		Address:  "Lenina 1",
+		Region:   "Moscow",
+	}
+
+	messages := []*kafka.Message{
+		newTestEnvelopeMessage(t, models.EventItemStatusChanged, "event-order", statusChanged, 1),
+		newTestEnvelopeMessage(t, models.EventPaymentCaptured, "event-order", paymentCaptured, 2),
+		newTestEnvelopeMessage(t, models.EventDeliveryAddressChanged, "event-order", addressChanged, 3),
+		newTestEnvelopeMessage(t, models.EventItemStatusChanged, "missing-order",
+			models.ItemStatusChangedEvent{OrderUID: "missing-order", Rid: "rid1", Status: 1}, 4),
+		newTestEnvelopeMessage(t, "order.archived", "event-order", statusChanged, 5),
+	}
+
+	occurredAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	claim := func(orderUID string) *pgxmock.ExpectedQuery {
+		return mockDB.ExpectQuery(`WITH target AS`).
+			WithArgs(versionFromTimestamp, occurredAt.UnixMilli(), orderUID)
+	}
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectBegin()
+	claim("event-order").WillReturnRows(pgxmock.NewRows([]string{"current"}).AddRow(true))
+	mockDB.ExpectExec(`INSERT INTO item_status_history`).
+		WithArgs(300, "rid123", "event-order", "test-topic", int32Ptr(0), int64Ptr(1), occurredAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectExec(`UPDATE item`).
+		WithArgs(300, "rid123", "event-order").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectCommit()
+	mockDB.ExpectBegin()
+	claim("event-order").WillReturnRows(pgxmock.NewRows([]string{"current"}).AddRow(true))
+	mockDB.ExpectExec(`UPDATE payment`).
+		WithArgs("txn-1", 1500, 1704110400, "alpha", "event-order").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectCommit()
+	mockDB.ExpectBegin()
+	claim("event-order").WillReturnRows(pgxmock.NewRows([]string{"current"}).AddRow(true))
+	mockDB.ExpectExec(`UPDATE delivery`).
+		WithArgs("123456", "Moscow", "Lenina 1", "Moscow", "Moscow", "Lenina 1", "Moscow", "event-order").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectCommit()
+	mockDB.ExpectBegin()
+	claim("missing-order").WillReturnRows(pgxmock.NewRows([]string{"current"}))
+	mockDB.ExpectRollback()
+	mockDB.ExpectBegin()
+	mockDB.ExpectRollback()
+	mockDB.ExpectCommit()
+	redisMock.ExpectDel("order:event-order", "order:event-order", "order:event-order").SetVal(1)
+
+	if err := consumer.processMessageBatch(messages); err != nil {
+		t.Errorf("processMessageBatch() failed: %v", err)
+	}
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+	if err := redisMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled redis expectations: %s", err)
+	}
+}
+
+func TestConsumer_ProcessMessage_UnknownEnvelopeType(t *testing.T) {
+	consumer := &Consumer{decoders: defaultDecoders()}
+
+	msg := newTestEnvelopeMessage(t, "order.archived", "event-order", models.OrderDeletedEvent{OrderUID: "event-order"}, 1)
+
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	mockDB.ExpectBegin()
+
+	ctx := context.Background()
+	tx, err := mockDB.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+
+	_, err = consumer.processMessage(ctx, tx, msg)
+	if !errors.Is(err, errs.ErrUnknownType) {
+		t.Errorf("expected unknown type error, got %v", err)
+	}
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestConsumer_UpsertItems(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	consumer := &Consumer{db: mockDB}
+
+	items := []models.ItemRequest{
+		{ChrtID: 1, TrackNumber: "TRACK", Price: 100, Rid: "rid-1", Name: "New", Size: "0", TotalPrice: 100, NmID: 1, Brand: "Brand", Status: 202},
+		{ChrtID: 2, TrackNumber: "TRACK", Price: 200, Rid: "rid-2", Name: "Changed", Size: "0", TotalPrice: 200, NmID: 2, Brand: "Brand", Status: 300},
+		{ChrtID: 3, TrackNumber: "TRACK", Price: 300, Rid: "rid-3", Name: "Same", Size: "0", TotalPrice: 300, NmID: 3, Brand: "Brand", Status: 202},
+	}
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`INSERT INTO item \(`).
+		WithArgs(int64(7), 1, "TRACK", 100, "rid-1", "New", 0, "0", 100, 1, "Brand", 202).
+		WillReturnRows(pgxmock.NewRows([]string{"inserted"}).AddRow(true))
+	mockDB.ExpectQuery(`INSERT INTO item \(`).
+		WithArgs(int64(7), 2, "TRACK", 200, "rid-2", "Changed", 0, "0", 200, 2, "Brand", 300).
+		WillReturnRows(pgxmock.NewRows([]string{"inserted"}).AddRow(false))
+	mockDB.ExpectQuery(`INSERT INTO item \(`).
+		WithArgs(int64(7), 3, "TRACK", 300, "rid-3", "Same", 0, "0", 300, 3, "Brand", 202).
+		WillReturnRows(pgxmock.NewRows([]string{"inserted"}))
+	mockDB.ExpectQuery(`DELETE FROM item`).
+		WithArgs(int64(7), []string{"rid-1", "rid-2", "rid-3"}).
+		WillReturnRows(pgxmock.NewRows([]string{"rid"}).AddRow("rid-4").AddRow("rid-5"))
+
+	ctx := context.Background()
+	tx, err := mockDB.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+
+	changes, err := consumer.upsertItems(ctx, tx, 7, items)
+	if err != nil {
+		t.Fatalf("upsertItems() failed: %v", err)
+	}
+
+	want := itemChangeSet{
+		inserted:  []string{"rid-1"},
+		updated:   []string{"rid-2"},
+		unchanged: []string{"rid-3"},
+		deleted:   []string{"rid-4", "rid-5"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("upsertItems() = %+v, want %+v", changes, want)
+	}
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestConsumer_ProcessMessageBatch_StaleEvent(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	redisClient, redisMock := redismock.NewClientMock()
+
+	consumer := &Consumer{
+		db:       mockDB,
+		decoders: defaultDecoders(),
+		cache:    redisClient,
+	}
+
+	// The snapshot was taken an hour after the status change it follows.
+	order := newTestOrderRequest("stale-event-order")
+	snapshotTime := time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC)
+	msgValue, _ := json.Marshal(order)
+	snapshot := &kafka.Message{
+		Value:     msgValue,
+		Timestamp: snapshotTime,
+		TopicPartition: kafka.TopicPartition{
+			Topic:     stringPtr("test-topic"),
+			Partition: 0,
+			Offset:    1,
+		},
+	}
+	event := newTestEnvelopeMessage(t, models.EventItemStatusChanged, order.OrderUID,
+		models.ItemStatusChangedEvent{OrderUID: order.OrderUID, Rid: "rid123", Status: 300}, 2)
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`INSERT INTO "order"`).
+		WithArgs(
+			order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+			order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.OofShard,
+			order.DateCreated, snapshotTime.UnixMilli(), versionFromTimestamp, "[]",
+		).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(1)))
+	mockDB.ExpectExec(`INSERT INTO delivery`).
+		WithArgs(int64(1), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectExec(`INSERT INTO payment`).
+		WithArgs(int64(1), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectExec(`INSERT INTO item_status_history`).
+		WithArgs(int64(1), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectQuery(`INSERT INTO item \(`).
+		WithArgs(int64(1), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"inserted"}).AddRow(true))
+	mockDB.ExpectQuery(`DELETE FROM item`).
+		WithArgs(int64(1), []string{"rid123"}).
+		WillReturnRows(pgxmock.NewRows([]string{"rid"}))
+	mockDB.ExpectCommit()
+	// The event is older than the stored snapshot, so nothing is updated.
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`WITH target AS`).
+		WithArgs(versionFromTimestamp, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).UnixMilli(), order.OrderUID).
+		WillReturnRows(pgxmock.NewRows([]string{"current"}).AddRow(false))
+	mockDB.ExpectRollback()
+	mockDB.ExpectCommit()
+	redisMock.ExpectDel("order:stale-event-order").SetVal(1)
+
+	if err := consumer.processMessageBatch([]*kafka.Message{snapshot, event}); err != nil {
+		t.Errorf("processMessageBatch() failed: %v", err)
+	}
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+	if err := redisMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled redis expectations: %s", err)
+	}
+}
+
+func newTestOrderRequest(orderUID string) models.OrderRequest {
+	return models.OrderRequest{
+		OrderUID:        orderUID,
+		TrackNumber:     "TRACK123",
+		Entry:           "WBIL",
+		Locale:          models.LocaleEN,
+		CustomerID:      "test_customer",
+		DeliveryService: "meest",
+		Shardkey:        "9",
+		SmID:            99,
+		OofShard:        "1",
+		DateCreated:     time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+		Delivery: models.DeliveryRequest{
+			Name:    "John Doe",
+			Phone:   "+1234567890",
+			Zip:     "12345",
+			City:    "New York",
+			Address: "Street 123",
+			Region:  "NY",
+			Email:   "john@example.com",
+		},
+		Payment: models.PaymentRequest{
+			Transaction:  orderUID,
+			Currency:     models.CurrencyUSD,
+			Provider:     "wbpay",
+			Amount:       1500,
+			PaymentDt:    1,
+			Bank:         "alpha",
+			DeliveryCost: 500,
+			GoodsTotal:   1000,
+		},
+		Items: []models.ItemRequest{
+			{
+				ChrtID:      1,
+				TrackNumber: "TRACK123",
+				Price:       1000,
+				Rid:         "rid123",
+				Name:        "Test Item",
+				Size:        "M",
+				TotalPrice:  1000,
+				NmID:        123456,
+				Brand:       "Test Brand",
+				Status:      202,
+			},
+		},
+	}
+}
+
+func TestDeadLetterMessage(t *testing.T) {
+	msg := &kafka.Message{
+		Key:     []byte("order-1"),
+		Value:   []byte(`{"order_uid":""}`),
+		Headers: []kafka.Header{{Key: codec.HeaderContentType, Value: []byte(codec.ContentTypeJSON)}},
+		TopicPartition: kafka.TopicPartition{
+			Topic:     stringPtr("orders"),
+			Partition: 2,
+			Offset:    42,
+		},
+	}
+
+	cause := fmt.Errorf("order validation failed: %w", validate.ValidateOrderUID(""))
+	dead := deadLetterMessage(msg, "orders-dlq", cause)
+
+	if *dead.TopicPartition.Topic != "orders-dlq" {
+		t.Errorf("expected dlq topic, got %s", *dead.TopicPartition.Topic)
+	}
+	if string(dead.Key) != string(msg.Key) || string(dead.Value) != string(msg.Value) {
+		t.Errorf("expected original key and value to be kept")
+	}
+
+	headers := make(map[string]string)
+	for _, header := range dead.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+
+	want := map[string]string{
+		codec.HeaderContentType:      codec.ContentTypeJSON,
+		codec.HeaderDLQError:         cause.Error(),
+		codec.HeaderDLQTopic:         "orders",
+		codec.HeaderDLQPartition:     "2",
+		codec.HeaderDLQOffset:        "42",
+		codec.HeaderValidationErrors: `[{"path":"order_uid","code":"required","message":"order UID cannot be empty"}]`,
+	}
+	if !reflect.DeepEqual(headers, want) {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+
+	dead = deadLetterMessage(msg, "orders-dlq", errors.New("db is down"))
+	for _, header := range dead.Headers {
+		if header.Key == codec.HeaderValidationErrors {
+			t.Errorf("unexpected %s header for non-validation error", codec.HeaderValidationErrors)
+		}
+	}
+}
+
+func TestConsumer_CheckSchema(t *testing.T) {
+	valid, _ := json.Marshal(newTestOrderRequest("schema-order"))
+
+	invalidOrder := newTestOrderRequest("schema-order")
+	invalidOrder.Items[0].Price = 0
+	invalid, _ := json.Marshal(invalidOrder)
+
+	tests := []struct {
+		name      string
+		schema    bool
+		value     []byte
+		headers   []kafka.Header
+		wantErr   bool
+		wantPaths []string
+	}{
+		{name: "Disabled", value: invalid},
+		{name: "Valid", schema: true, value: valid},
+		{name: "Invalid", schema: true, value: invalid, wantErr: true, wantPaths: []string{"items[0].price"}},
+		{name: "MissingFields", schema: true, value: []byte(`{"order_uid": "schema-order", "sm_id": 1}`), wantErr: true},
+		{name: "Framed", schema: true, value: registry.Encode(1, invalid), wantErr: true, wantPaths: []string{"items[0].price"}},
+		{
+			name:    "LegacyVersion",
+			schema:  true,
+			value:   invalid,
+			headers: []kafka.Header{{Key: codec.HeaderVersion, Value: []byte(PayloadVersionLegacy)}},
+		},
+		{
+			name:    "NotJSON",
+			schema:  true,
+			value:   []byte{0x0a},
+			headers: []kafka.Header{{Key: codec.HeaderContentType, Value: []byte(codec.ContentTypeProtobuf)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &Consumer{schema: tt.schema}
+			err := consumer.checkSchema(&kafka.Message{Value: tt.value, Headers: tt.headers})
+
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, errs.ErrValidation) {
+				t.Fatalf("expected validation error, got %v", err)
+			}
+			if tt.wantPaths == nil {
+				return
+			}
+
+			violations, ok := validate.AsValidationErrors(err)
+			if !ok {
+				t.Fatalf("expected validation errors, got %v", err)
+			}
+			var paths []string
+			for _, violation := range violations {
+				paths = append(paths, violation.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("expected paths %v, got %v", tt.wantPaths, paths)
+			}
+		})
+	}
+}
+
+func TestConsumer_CheckStrict(t *testing.T) {
+	strictTopic, looseTopic := "orders", "orders-loose"
+	valid, _ := json.Marshal(newTestOrderRequest("strict-order"))
+	unknown := append(valid[:len(valid)-1:len(valid)-1], []byte(`,"deliver":{}}`)...)
+
+	tests := []struct {
+		name      string
+		topic     *string
+		value     []byte
+		headers   []kafka.Header
+		wantPaths []string
+	}{
+		{name: "Valid", topic: &strictTopic, value: valid},
+		{name: "UnknownField", topic: &strictTopic, value: unknown, wantPaths: []string{"deliver"}},
+		{name: "Framed", topic: &strictTopic, value: registry.Encode(1, unknown), wantPaths: []string{"deliver"}},
+		{name: "LooseTopic", topic: &looseTopic, value: unknown},
+		{name: "NoTopic", value: unknown},
+		{
+			name:      "LegacyVersion",
+			topic:     &strictTopic,
+			value:     []byte(`{"order_uid": "a", "payment": {"goods_total": 1}}`),
+			headers:   []kafka.Header{{Key: codec.HeaderVersion, Value: []byte(PayloadVersionLegacy)}},
+			wantPaths: []string{"payment.goods_total"},
+		},
+		{
+			name:    "NotJSON",
+			topic:   &strictTopic,
+			value:   []byte{0x0a},
+			headers: []kafka.Header{{Key: codec.HeaderContentType, Value: []byte(codec.ContentTypeProtobuf)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &Consumer{strict: strictTopics([]string{strictTopic})}
+			err := consumer.checkStrict(&kafka.Message{
+				TopicPartition: kafka.TopicPartition{Topic: tt.topic},
+				Value:          tt.value,
+				Headers:        tt.headers,
+			})
+
+			if tt.wantPaths == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			violations, ok := validate.AsValidationErrors(err)
+			if !ok {
+				t.Fatalf("expected validation errors, got %v", err)
+			}
+			var paths []string
+			for _, violation := range violations {
+				paths = append(paths, violation.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("expected paths %v, got %v", tt.wantPaths, paths)
+			}
+		})
+	}
+}
+
+func TestConsumer_ProcessEnvelope_Strict(t *testing.T) {
+	topic := "order-events"
+	consumer := &Consumer{
+		decoders: defaultDecoders(),
+		strict:   strictTopics([]string{topic}),
+	}
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic},
+		Value:          []byte(`{"event_type": "order.deleted", "event_id": "e1", "occurred_at": "2024-01-01T00:00:00Z", "payload": {"order_uid": "a"}, "payload": {}}`),
+		Headers:        []kafka.Header{{Key: codec.HeaderContentType, Value: []byte(codec.ContentTypeEvent)}},
+	}
+
+	_, err := consumer.processMessage(context.Background(), nil, msg)
+	if !errors.Is(err, errs.ErrValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	violations, _ := validate.AsValidationErrors(err)
+	if len(violations) != 1 || violations[0].Code != validate.CodeDuplicateField || violations[0].Path != "payload" {
+		t.Errorf("unexpected violations: %v", violations)
+	}
+}
+
+func TestStore_Save(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	store, err := CreateStore(&config.ConsumerConfig{}, mockDB, nil, "import")
+	if err != nil {
+		t.Fatalf("CreateStore() failed: %v", err)
+	}
+
+	saved := newTestOrderRequest("imported-order")
+	invalid := newTestOrderRequest("")
+	stale := newTestOrderRequest("stale-order")
+	stale.Version = 2
+	version := saved.DateCreated.UnixMilli()
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`INSERT INTO "order"`).
+		WithArgs(
+			saved.OrderUID, saved.TrackNumber, saved.Entry, saved.Locale, saved.InternalSignature,
+			saved.CustomerID, saved.DeliveryService, saved.Shardkey, saved.SmID, saved.OofShard,
+			saved.DateCreated, version, versionFromTimestamp, "[]",
+		).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	mockDB.ExpectExec(`INSERT INTO delivery`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectExec(`INSERT INTO payment`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectExec(`INSERT INTO item_status_history`).
+		WithArgs(int64(7), []int32{1}, []string{"rid123"}, []int32{202}, "import", (*int32)(nil),
+			(*int64)(nil), saved.DateCreated).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectQuery(`INSERT INTO item \(`).
+		WithArgs(int64(7), 1, "TRACK123", 1000, "rid123", "Test Item", 0, "M", 1000, 123456, "Test Brand", 202).
+		WillReturnRows(pgxmock.NewRows([]string{"inserted"}).AddRow(true))
+	mockDB.ExpectQuery(`DELETE FROM item`).
+		WithArgs(int64(7), []string{"rid123"}).
+		WillReturnRows(pgxmock.NewRows([]string{"rid"}))
+	mockDB.ExpectQuery(`INSERT INTO "order"`).
+		WithArgs(
+			stale.OrderUID, stale.TrackNumber, stale.Entry, stale.Locale, stale.InternalSignature,
+			stale.CustomerID, stale.DeliveryService, stale.Shardkey, stale.SmID, stale.OofShard,
+			stale.DateCreated, int64(2), versionFromOrder, "[]",
+		).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}))
+	mockDB.ExpectCommit()
+	mockDB.ExpectRollback()
+
+	refused, err := store.Save(context.Background(), []models.OrderRequest{saved, invalid, stale})
+	if err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	if len(refused) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(refused))
+	}
+	if refused[0] != nil {
+		t.Errorf("expected order to be saved, got %v", refused[0])
+	}
+	if !errors.Is(refused[1], errs.ErrValidation) {
+		t.Errorf("expected validation error, got %v", refused[1])
+	}
+	if !errors.Is(refused[2], errs.ErrStaleVersion) {
+		t.Errorf("expected stale version error, got %v", refused[2])
+	}
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_Save_DatabaseError(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mockDB.Close()
+
+	store, err := CreateStore(&config.ConsumerConfig{}, mockDB, nil, "import")
+	if err != nil {
+		t.Fatalf("CreateStore() failed: %v", err)
+	}
+
+	order := newTestOrderRequest("failed-order")
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`INSERT INTO "order"`).
+		WithArgs(
+			order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+			order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.OofShard,
+			order.DateCreated, order.DateCreated.UnixMilli(), versionFromTimestamp, "[]",
+		).
+		WillReturnError(errors.New("connection reset"))
+	mockDB.ExpectRollback()
+
+	refused, err := store.Save(context.Background(), []models.OrderRequest{order})
+	if err == nil {
+		t.Error("expected error from order insert")
+	}
+	if refused != nil {
+		t.Errorf("expected no per-order results, got %v", refused)
+	}
+
+	if err := mockDB.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateStore_InvalidRules(t *testing.T) {
+	_, err := CreateStore(&config.ConsumerConfig{ValidationRules: "unknown_rule=reject"}, nil, nil, "import")
+	if err == nil {
+		t.Error("expected error for unknown validation rule")
+	}
+}
@@ -0,0 +1,198 @@
+package consumer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/kafka/registry"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+const (
+	PayloadVersionLegacy  = "0.9"
+	PayloadVersionCurrent = codec.PayloadVersion
+)
+
+type Decoder func(payload []byte) (*models.OrderRequest, error)
+
+type decoderKey struct {
+	version     string
+	contentType string
+}
+
+type DecoderRegistry struct {
+	mu       sync.RWMutex
+	decoders map[decoderKey]Decoder
+}
+
+func CreateDecoderRegistry(formats ...string) (*DecoderRegistry, error) {
+	if len(formats) == 0 {
+		formats = codec.Formats()
+	}
+
+	registry := &DecoderRegistry{
+		decoders: make(map[decoderKey]Decoder),
+	}
+
+	for _, format := range formats {
+		c, err := codec.ForFormat(format)
+		if err != nil {
+			return nil, err
+		}
+
+		registry.Register(PayloadVersionCurrent, c.ContentType(), c.Unmarshal)
+		if c.ContentType() == codec.ContentTypeJSON {
+			registry.Register(PayloadVersionLegacy, codec.ContentTypeJSON, decodeJSONLegacy)
+		}
+	}
+
+	return registry, nil
+}
+
+func (r *DecoderRegistry) Register(version, contentType string, decoder Decoder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.decoders[decoderKey{version: version, contentType: contentType}] = decoder
+}
+
+// UseSchemaRegistry switches the current-version decoders of schema-based
+// formats to the schema registry wire format. Unframed payloads are still
+// decoded as before.
+func (r *DecoderRegistry) UseSchemaRegistry(client registry.Client) {
+	if client == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range r.decoders {
+		if key.version != PayloadVersionCurrent {
+			continue
+		}
+
+		c, err := codec.ForContentType(key.contentType)
+		if err != nil {
+			continue
+		}
+
+		r.decoders[key] = registry.WrapDeserializer(client, c).Unmarshal
+	}
+}
+
+func (r *DecoderRegistry) Decode(msg *kafka.Message) (*models.OrderRequest, error) {
+	version, contentType := payloadFormat(msg)
+
+	r.mu.RLock()
+	decoder, ok := r.decoders[decoderKey{version: version, contentType: contentType}]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: version %q with content type %q", errs.ErrUnsupportedVersion, version, contentType)
+	}
+
+	order, err := decoder(msg.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s payload v%s: %w", contentType, version, err)
+	}
+
+	return order, nil
+}
+
+func payloadFormat(msg *kafka.Message) (version, contentType string) {
+	version = PayloadVersionCurrent
+	contentType = codec.ContentTypeJSON
+
+	for _, header := range msg.Headers {
+		switch header.Key {
+		case codec.HeaderVersion:
+			version = string(header.Value)
+		case codec.HeaderContentType:
+			contentType = string(header.Value)
+		}
+	}
+
+	return version, contentType
+}
+
+// Legacy payloads predate per-item track numbers, item totals and the
+// payment goods total; they are derived from the rest of the order.
+type legacyOrderRequest struct {
+	models.OrderRequest
+	Payment legacyPaymentRequest `json:"payment"`
+	Items   []legacyItemRequest  `json:"items"`
+}
+
+type legacyPaymentRequest struct {
+	Transaction  string              `json:"transaction"`
+	RequestID    string              `json:"request_id"`
+	Currency     models.CurrencyEnum `json:"currency"`
+	Provider     string              `json:"provider"`
+	Amount       int                 `json:"amount"`
+	PaymentDt    int                 `json:"payment_dt"`
+	Bank         string              `json:"bank"`
+	DeliveryCost int                 `json:"delivery_cost"`
+	CustomFee    int                 `json:"custom_fee"`
+}
+
+type legacyItemRequest struct {
+	ChrtID int    `json:"chrt_id"`
+	Price  int    `json:"price"`
+	Rid    string `json:"rid"`
+	Name   string `json:"name"`
+	Sale   int    `json:"sale"`
+	Size   string `json:"size"`
+	NmID   int    `json:"nm_id"`
+	Brand  string `json:"brand"`
+	Status int    `json:"status"`
+}
+
+func decodeJSONLegacy(payload []byte) (*models.OrderRequest, error) {
+	var legacy legacyOrderRequest
+	if err := json.Unmarshal(payload, &legacy); err != nil {
+		return nil, err
+	}
+
+	order := legacy.OrderRequest
+	order.Items = make([]models.ItemRequest, 0, len(legacy.Items))
+
+	goodsTotal := 0
+	for _, item := range legacy.Items {
+		totalPrice := item.Price * (100 - item.Sale) / 100
+		goodsTotal += totalPrice
+
+		order.Items = append(order.Items, models.ItemRequest{
+			ChrtID:      item.ChrtID,
+			TrackNumber: order.TrackNumber,
+			Price:       item.Price,
+			Rid:         item.Rid,
+			Name:        item.Name,
+			Sale:        item.Sale,
+			Size:        item.Size,
+			TotalPrice:  totalPrice,
+			NmID:        item.NmID,
+			Brand:       item.Brand,
+			Status:      item.Status,
+		})
+	}
+
+	order.Payment = models.PaymentRequest{
+		Transaction:  legacy.Payment.Transaction,
+		RequestID:    legacy.Payment.RequestID,
+		Currency:     legacy.Payment.Currency,
+		Provider:     legacy.Payment.Provider,
+		Amount:       legacy.Payment.Amount,
+		PaymentDt:    legacy.Payment.PaymentDt,
+		Bank:         legacy.Payment.Bank,
+		DeliveryCost: legacy.Payment.DeliveryCost,
+		GoodsTotal:   goodsTotal,
+		CustomFee:    legacy.Payment.CustomFee,
+	}
+
+	return &order, nil
+}
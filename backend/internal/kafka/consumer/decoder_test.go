@@ -0,0 +1,164 @@
+package consumer
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
+)
+
+func TestDecoderRegistry_Decode(t *testing.T) {
+	current := newTestOrderRequest("current-order")
+	currentPayload, _ := json.Marshal(current)
+
+	legacyPayload := []byte(`{
+		"order_uid": "legacy-order",
+		"track_number": "TRACK123",
+		"entry": "WBIL",
+		"locale": "en",
+		"customer_id": "test_customer",
+		"delivery_service": "meest",
+		"shardkey": "9",
+		"sm_id": 99,
+		"oof_shard": "1",
+		"date_created": "2023-01-01T12:00:00Z",
+		"delivery": {"name": "John Doe", "phone": "+1234567890", "zip": "12345", "city": "New York",
+			"address": "Street 123", "region": "NY", "email": "john@example.com"},
+		"payment": {"transaction": "legacy-order", "currency": "USD", "provider": "wbpay", "amount": 1400,
+			"payment_dt": 1, "bank": "alpha", "delivery_cost": 500},
+		"items": [
+			{"chrt_id": 1, "price": 1000, "rid": "rid1", "name": "Item", "sale": 30, "size": "M",
+				"nm_id": 123456, "brand": "Brand", "status": 202},
+			{"chrt_id": 2, "price": 200, "rid": "rid2", "name": "Item", "sale": 0, "size": "L",
+				"nm_id": 654321, "brand": "Brand", "status": 202}
+		]
+	}`)
+
+	tests := []struct {
+		name         string
+		msg          *kafka.Message
+		wantErr      error
+		validateFunc func(t *testing.T, order *models.OrderRequest)
+	}{
+		{
+			name: "no headers defaults to current JSON",
+			msg:  &kafka.Message{Value: currentPayload},
+			validateFunc: func(t *testing.T, order *models.OrderRequest) {
+				assert.Equal(t, current.OrderUID, order.OrderUID)
+				assert.Len(t, order.Items, 1)
+			},
+		},
+		{
+			name: "current version header",
+			msg: &kafka.Message{
+				Value: currentPayload,
+				Headers: []kafka.Header{
+					{Key: codec.HeaderVersion, Value: []byte(PayloadVersionCurrent)},
+					{Key: codec.HeaderContentType, Value: []byte(codec.ContentTypeJSON)},
+				},
+			},
+			validateFunc: func(t *testing.T, order *models.OrderRequest) {
+				assert.Equal(t, current.OrderUID, order.OrderUID)
+			},
+		},
+		{
+			name: "legacy payload is upcast",
+			msg: &kafka.Message{
+				Value:   legacyPayload,
+				Headers: []kafka.Header{{Key: codec.HeaderVersion, Value: []byte(PayloadVersionLegacy)}},
+			},
+			validateFunc: func(t *testing.T, order *models.OrderRequest) {
+				assert.Equal(t, "legacy-order", order.OrderUID)
+				assert.Len(t, order.Items, 2)
+				assert.Equal(t, "TRACK123", order.Items[0].TrackNumber)
+				assert.Equal(t, 700, order.Items[0].TotalPrice)
+				assert.Equal(t, 200, order.Items[1].TotalPrice)
+				assert.Equal(t, 900, order.Payment.GoodsTotal)
+				assert.Equal(t, 1400, order.Payment.Amount)
+				assert.NoError(t, validate.ValidateOrderRequest(order))
+			},
+		},
+		{
+			name: "unknown version is rejected",
+			msg: &kafka.Message{
+				Value:   currentPayload,
+				Headers: []kafka.Header{{Key: codec.HeaderVersion, Value: []byte("7.0")}},
+			},
+			wantErr: errs.ErrUnsupportedVersion,
+		},
+		{
+			name: "unknown content type is rejected",
+			msg: &kafka.Message{
+				Value:   currentPayload,
+				Headers: []kafka.Header{{Key: codec.HeaderContentType, Value: []byte("text/xml")}},
+			},
+			wantErr: errs.ErrUnsupportedVersion,
+		},
+	}
+
+	registry := defaultDecoders()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := registry.Decode(tt.msg)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "expected %v, got %v", tt.wantErr, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			tt.validateFunc(t, order)
+		})
+	}
+}
+
+func TestDecoderRegistry_Formats(t *testing.T) {
+	order := newTestOrderRequest("binary-order")
+
+	for _, format := range codec.Formats() {
+		t.Run(format, func(t *testing.T) {
+			c, err := codec.ForFormat(format)
+			assert.NoError(t, err)
+
+			payload, err := c.Marshal(&order)
+			assert.NoError(t, err)
+
+			msg := &kafka.Message{
+				Value: payload,
+				Headers: []kafka.Header{
+					{Key: codec.HeaderVersion, Value: []byte(PayloadVersionCurrent)},
+					{Key: codec.HeaderContentType, Value: []byte(c.ContentType())},
+				},
+			}
+
+			decoded, err := defaultDecoders().Decode(msg)
+			assert.NoError(t, err)
+			assert.Equal(t, order.OrderUID, decoded.OrderUID)
+			assert.Equal(t, order.Items, decoded.Items)
+
+			restricted, err := CreateDecoderRegistry(codec.FormatJSON)
+			assert.NoError(t, err)
+
+			_, err = restricted.Decode(msg)
+			if c.ContentType() == codec.ContentTypeJSON {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, errs.ErrUnsupportedVersion))
+			}
+		})
+	}
+
+	_, err := CreateDecoderRegistry("xml")
+	assert.Error(t, err)
+}
+
+func defaultDecoders() *DecoderRegistry {
+	registry, _ := CreateDecoderRegistry()
+	return registry
+}
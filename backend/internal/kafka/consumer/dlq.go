@@ -0,0 +1,101 @@
+package consumer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/supchaser/wb_l0/internal/config"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
+	"go.uber.org/zap"
+)
+
+const dlqFlushTimeout = 5000
+
+// deadLetterQueue forwards messages that failed processing to a separate
+// topic, with the failure recorded in headers.
+type deadLetterQueue struct {
+	producer *kafka.Producer
+	topic    string
+}
+
+// createDeadLetterQueue fails without a topic: offsets of failed messages
+// are committed once they are forwarded, so they would otherwise be lost.
+func createDeadLetterQueue(cfg *config.ConsumerConfig) (*deadLetterQueue, error) {
+	if cfg.DLQTopic == "" {
+		return nil, fmt.Errorf("dlq topic is required: set KAFKA_DLQ_TOPIC")
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers":   strings.Join(cfg.Brokers, ","),
+		"acks":                "all",
+		"go.delivery.reports": false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dlq producer: %w", err)
+	}
+
+	return &deadLetterQueue{
+		producer: producer,
+		topic:    cfg.DLQTopic,
+	}, nil
+}
+
+func (d *deadLetterQueue) send(msg *kafka.Message, cause error) {
+	if d == nil {
+		return
+	}
+
+	if err := d.producer.Produce(deadLetterMessage(msg, d.topic, cause), nil); err != nil {
+		logger.Error("failed to send message to dlq",
+			zap.String("dlq_topic", d.topic),
+			zap.Int32("partition", msg.TopicPartition.Partition),
+			zap.Int64("offset", int64(msg.TopicPartition.Offset)),
+			zap.Error(err))
+	}
+}
+
+func (d *deadLetterQueue) close() {
+	if d == nil {
+		return
+	}
+
+	d.producer.Flush(dlqFlushTimeout)
+	d.producer.Close()
+}
+
+func deadLetterMessage(msg *kafka.Message, topic string, cause error) *kafka.Message {
+	var sourceTopic string
+	if msg.TopicPartition.Topic != nil {
+		sourceTopic = *msg.TopicPartition.Topic
+	}
+
+	headers := make([]kafka.Header, 0, len(msg.Headers)+5)
+	headers = append(headers, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: codec.HeaderDLQError, Value: []byte(cause.Error())},
+		kafka.Header{Key: codec.HeaderDLQTopic, Value: []byte(sourceTopic)},
+		kafka.Header{Key: codec.HeaderDLQPartition, Value: []byte(strconv.Itoa(int(msg.TopicPartition.Partition)))},
+		kafka.Header{Key: codec.HeaderDLQOffset, Value: []byte(strconv.FormatInt(int64(msg.TopicPartition.Offset), 10))},
+	)
+
+	if violations, ok := validate.AsValidationErrors(cause); ok {
+		if data, err := json.Marshal(violations); err == nil {
+			headers = append(headers, kafka.Header{Key: codec.HeaderValidationErrors, Value: data})
+		}
+	}
+
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{
+			Topic:     &topic,
+			Partition: kafka.PartitionAny,
+		},
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+}
@@ -0,0 +1,369 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/jackc/pgx/v5"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/app/repository"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/normalize"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
+	"go.uber.org/zap"
+)
+
+// processMessage applies a single message and returns the UID of an order
+// whose cache entry must be evicted once the batch is committed.
+func (c *Consumer) processMessage(ctx context.Context, tx pgx.Tx, msg *kafka.Message) (string, error) {
+	if isEnvelope(msg) {
+		return c.processEnvelope(ctx, tx, msg)
+	}
+
+	eventType, err := messageEventType(msg)
+	if err != nil {
+		return "", err
+	}
+
+	switch eventType {
+	case models.EventOrderDeleted:
+		orderUID := string(msg.Key)
+		if orderUID == "" {
+			return "", fmt.Errorf("%w: delete event without order uid key", errs.ErrValidation)
+		}
+		if err := c.deleteOrder(ctx, tx, orderUID, sourceOf(msg)); err != nil {
+			return "", fmt.Errorf("failed to delete order: %w", err)
+		}
+		return orderUID, nil
+
+	default:
+		return c.processSingleMessage(ctx, tx, msg)
+	}
+}
+
+// messageEventType treats tombstones (null value) as deletes and messages
+// without an event type header as full order snapshots.
+func messageEventType(msg *kafka.Message) (models.EventTypeEnum, error) {
+	if msg.Value == nil {
+		return models.EventOrderDeleted, nil
+	}
+
+	for _, header := range msg.Headers {
+		if header.Key != codec.HeaderEventType {
+			continue
+		}
+
+		switch eventType := models.EventTypeEnum(header.Value); eventType {
+		case models.EventOrderCreated, models.EventOrderUpdated, models.EventOrderDeleted:
+			return eventType, nil
+		default:
+			return "", fmt.Errorf("%w: %q", errs.ErrUnknownType, header.Value)
+		}
+	}
+
+	return models.EventOrderUpdated, nil
+}
+
+func isEnvelope(msg *kafka.Message) bool {
+	for _, header := range msg.Headers {
+		if header.Key == codec.HeaderContentType {
+			return string(header.Value) == codec.ContentTypeEvent
+		}
+	}
+
+	return false
+}
+
+// processEnvelope applies a typed lifecycle event.
+func (c *Consumer) processEnvelope(ctx context.Context, tx pgx.Tx, msg *kafka.Message) (string, error) {
+	var envelope models.EventEnvelope
+	if err := c.unmarshal(msg, msg.Value, &envelope); err != nil {
+		return "", fmt.Errorf("failed to unmarshal event envelope: %w", err)
+	}
+
+	if err := validate.ValidateEventEnvelope(&envelope); err != nil {
+		return "", fmt.Errorf("event envelope validation failed: %w", err)
+	}
+
+	if !isKnownEventType(envelope.EventType) {
+		return "", fmt.Errorf("%w: %q", errs.ErrUnknownType, envelope.EventType)
+	}
+
+	orderUID, err := c.applyEvent(ctx, tx, msg, &envelope)
+	if err != nil {
+		return "", fmt.Errorf("failed to apply %s event %s: %w", envelope.EventType, envelope.EventID, err)
+	}
+
+	logger.Info("successfully applied order event",
+		zap.String("event_type", string(envelope.EventType)),
+		zap.String("event_id", envelope.EventID),
+		zap.String("order_uid", orderUID),
+		zap.Int32("partition", msg.TopicPartition.Partition),
+		zap.Int64("offset", int64(msg.TopicPartition.Offset)))
+
+	return orderUID, nil
+}
+
+func (c *Consumer) applyEvent(ctx context.Context, tx pgx.Tx, msg *kafka.Message, envelope *models.EventEnvelope) (string, error) {
+	// Events without an explicit version are versioned by when they
+	// occurred, as a timestamp.
+	source := sourceOf(msg)
+	source.timestamp = envelope.OccurredAt
+
+	switch envelope.EventType {
+	case models.EventOrderCreated, models.EventOrderUpdated:
+		if c.schema {
+			if err := validate.ValidateOrderJSON(envelope.Payload); err != nil {
+				return "", fmt.Errorf("order schema validation failed: %w", err)
+			}
+		}
+		var order models.OrderRequest
+		if err := c.unmarshal(msg, envelope.Payload, &order); err != nil {
+			return "", fmt.Errorf("failed to unmarshal order: %w", err)
+		}
+		if err := c.applyOrder(ctx, tx, source, &order); err != nil {
+			return "", err
+		}
+		return order.OrderUID, nil
+
+	case models.EventOrderDeleted:
+		var event models.OrderDeletedEvent
+		if err := c.unmarshal(msg, envelope.Payload, &event); err != nil {
+			return "", fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+		if event.OrderUID == "" {
+			event.OrderUID = string(msg.Key)
+		}
+		if event.OrderUID == "" {
+			return "", fmt.Errorf("%w: delete event without order uid", errs.ErrValidation)
+		}
+		return event.OrderUID, c.deleteOrder(ctx, tx, event.OrderUID, source)
+
+	case models.EventItemStatusChanged:
+		var event models.ItemStatusChangedEvent
+		if err := c.unmarshal(msg, envelope.Payload, &event); err != nil {
+			return "", fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+		if err := validate.ValidateItemStatusChanged(&event); err != nil {
+			return "", err
+		}
+		if err := c.claimOrderVersion(ctx, tx, event.OrderUID, source); err != nil {
+			return "", err
+		}
+		return event.OrderUID, c.updateItemStatus(ctx, tx, &event, source)
+
+	case models.EventPaymentCaptured:
+		var event models.PaymentCapturedEvent
+		if err := c.unmarshal(msg, envelope.Payload, &event); err != nil {
+			return "", fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+		if err := validate.ValidatePaymentCaptured(&event); err != nil {
+			return "", err
+		}
+		if err := c.claimOrderVersion(ctx, tx, event.OrderUID, source); err != nil {
+			return "", err
+		}
+		return event.OrderUID, c.capturePayment(ctx, tx, &event)
+
+	case models.EventDeliveryAddressChanged:
+		var event models.DeliveryAddressChangedEvent
+		if err := c.unmarshal(msg, envelope.Payload, &event); err != nil {
+			return "", fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+		if err := validate.ValidateDeliveryAddressChanged(&event); err != nil {
+			return "", err
+		}
+		if err := c.claimOrderVersion(ctx, tx, event.OrderUID, source); err != nil {
+			return "", err
+		}
+		return event.OrderUID, c.changeDeliveryAddress(ctx, tx, &event)
+
+	default:
+		return "", fmt.Errorf("%w: %q", errs.ErrUnknownType, envelope.EventType)
+	}
+}
+
+func isKnownEventType(eventType models.EventTypeEnum) bool {
+	switch eventType {
+	case models.EventOrderCreated, models.EventOrderUpdated, models.EventOrderDeleted,
+		models.EventItemStatusChanged, models.EventPaymentCaptured, models.EventDeliveryAddressChanged:
+		return true
+	default:
+		return false
+	}
+}
+
+// claimOrderVersion moves the order a partial event changes to the version
+// of the event. An order stored at a newer version gives errs.ErrStaleVersion,
+// so an event delivered late does not undo the snapshot or event after it.
+func (c *Consumer) claimOrderVersion(ctx context.Context, tx pgx.Tx, orderUID string, source orderSource) error {
+	version, versionSource, err := orderVersion(source, &models.OrderRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to resolve event version: %w", err)
+	}
+
+	query := `
+		WITH target AS (
+			SELECT id, (version_source, version) <= ($1::smallint, $2::bigint) AS current
+			FROM "order"
+			WHERE order_uid = $3
+			FOR UPDATE
+		), claimed AS (
+			UPDATE "order" o
+			SET version = $2, version_source = $1
+			FROM target
+			WHERE o.id = target.id AND target.current
+		)
+		SELECT current FROM target
+	`
+
+	var current bool
+	err = tx.QueryRow(ctx, query, versionSource, version, orderUID).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: order %s", errs.ErrNotFound, orderUID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim order version: %w", err)
+	}
+
+	if !current {
+		logger.Warn("skipping stale order event", append([]zap.Field{
+			zap.String("order_uid", orderUID),
+			zap.Int64("version", version),
+			zap.Int16("version_source", versionSource),
+		}, source.logFields()...)...)
+		return errs.ErrStaleVersion
+	}
+
+	return nil
+}
+
+func (c *Consumer) updateItemStatus(ctx context.Context, tx pgx.Tx, event *models.ItemStatusChangedEvent, source orderSource) error {
+	if err := c.recordItemStatusChange(ctx, tx, event, source); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE item
+		SET status = $1, updated_at = NOW()
+		WHERE rid = $2
+			AND order_id = (SELECT id FROM "order" WHERE order_uid = $3)
+	`
+
+	result, err := tx.Exec(ctx, query, event.Status, event.Rid, event.OrderUID)
+	if err != nil {
+		return fmt.Errorf("failed to update item status: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: item %s of order %s", errs.ErrNotFound, event.Rid, event.OrderUID)
+	}
+
+	return nil
+}
+
+func (c *Consumer) capturePayment(ctx context.Context, tx pgx.Tx, event *models.PaymentCapturedEvent) error {
+	query := `
+		UPDATE payment
+		SET transaction = $1, amount = $2, payment_dt = $3, bank = $4, updated_at = NOW()
+		WHERE order_id = (SELECT id FROM "order" WHERE order_uid = $5)
+	`
+
+	result, err := tx.Exec(ctx, query, event.Transaction, event.Amount, event.PaymentDt, event.Bank, event.OrderUID)
+	if err != nil {
+		return fmt.Errorf("failed to capture payment: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: payment of order %s", errs.ErrNotFound, event.OrderUID)
+	}
+
+	return nil
+}
+
+func (c *Consumer) changeDeliveryAddress(ctx context.Context, tx pgx.Tx, event *models.DeliveryAddressChangedEvent) error {
+	query := `
+		UPDATE delivery
+		SET zip = $1, city = $2, address = $3, region = $4,
+			city_raw = $5, address_raw = $6, region_raw = $7, updated_at = NOW()
+		WHERE order_id = (SELECT id FROM "order" WHERE order_uid = $8)
+	`
+
+	result, err := tx.Exec(ctx, query,
+		event.Zip, normalize.Text(event.City), normalize.Text(event.Address), normalize.Text(event.Region),
+		event.City, event.Address, event.Region, event.OrderUID)
+	if err != nil {
+		return fmt.Errorf("failed to change delivery address: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: delivery of order %s", errs.ErrNotFound, event.OrderUID)
+	}
+
+	return nil
+}
+
+// deleteOrder deletes the order and leaves a tombstone at the newer of its
+// stored version and the version of the delete, so a snapshot older than the
+// delete, delivered late, does not re-create the order.
+func (c *Consumer) deleteOrder(ctx context.Context, tx pgx.Tx, orderUID string, source orderSource) error {
+	version, versionSource, err := orderVersion(source, &models.OrderRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to resolve delete version: %w", err)
+	}
+
+	query := `DELETE FROM "order" WHERE order_uid = $1 RETURNING version_source, version`
+
+	var (
+		storedSource  int16
+		storedVersion int64
+	)
+	err = tx.QueryRow(ctx, query, orderUID).Scan(&storedSource, &storedVersion)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		logger.Warn("order to delete not found",
+			zap.String("order_uid", orderUID))
+	case err != nil:
+		return err
+	default:
+		logger.Info("order deleted",
+			zap.String("order_uid", orderUID))
+		if storedSource > versionSource || (storedSource == versionSource && storedVersion > version) {
+			version, versionSource = storedVersion, storedSource
+		}
+	}
+
+	tombstoneQuery := `
+		INSERT INTO order_tombstone (order_uid, version, version_source)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (order_uid) DO UPDATE SET
+			version = EXCLUDED.version,
+			version_source = EXCLUDED.version_source,
+			deleted_at = CURRENT_TIMESTAMP
+		WHERE (order_tombstone.version_source, order_tombstone.version) < (EXCLUDED.version_source, EXCLUDED.version)
+	`
+
+	if _, err := tx.Exec(ctx, tombstoneQuery, orderUID, version, versionSource); err != nil {
+		return fmt.Errorf("failed to record order tombstone: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Consumer) evictFromCache(ctx context.Context, orderUIDs []string) {
+	if c.cache == nil || len(orderUIDs) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(orderUIDs))
+	for _, orderUID := range orderUIDs {
+		keys = append(keys, repository.OrderCacheKey(orderUID))
+	}
+
+	if err := c.cache.Del(ctx, keys...).Err(); err != nil {
+		logger.Warn("failed to evict orders from cache",
+			zap.Strings("order_uids", orderUIDs),
+			zap.Error(err))
+	}
+}
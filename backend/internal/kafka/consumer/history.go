@@ -0,0 +1,148 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/jackc/pgx/v5"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"go.uber.org/zap"
+)
+
+// orderSource describes where a change to an order came from. Changes read
+// from Kafka carry the partition, offset and headers of their message;
+// imports have no partition or offset, stored as NULL in the history.
+type orderSource struct {
+	topic     string
+	partition *int32
+	offset    *int64
+	timestamp time.Time
+	headers   []kafka.Header
+}
+
+func sourceOf(msg *kafka.Message) orderSource {
+	partition := msg.TopicPartition.Partition
+	offset := int64(msg.TopicPartition.Offset)
+	source := orderSource{
+		partition: &partition,
+		offset:    &offset,
+		timestamp: msg.Timestamp,
+		headers:   msg.Headers,
+	}
+	if msg.TopicPartition.Topic != nil {
+		source.topic = *msg.TopicPartition.Topic
+	}
+
+	return source
+}
+
+// changedAt is when the change happened, for the item status history.
+func (s orderSource) changedAt() time.Time {
+	if s.timestamp.IsZero() {
+		return time.Now()
+	}
+
+	return s.timestamp
+}
+
+// header returns the value of the header key of the change's message.
+func (s orderSource) header(key string) ([]byte, bool) {
+	for _, header := range s.headers {
+		if header.Key == key {
+			return header.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// replayed reports whether the change was republished by cmd/replay.
+func (s orderSource) replayed() bool {
+	_, ok := s.header(codec.HeaderReplay)
+	return ok
+}
+
+func (s orderSource) logFields() []zap.Field {
+	fields := []zap.Field{zap.String("topic", s.topic)}
+	if s.partition != nil {
+		fields = append(fields, zap.Int32("partition", *s.partition))
+	}
+	if s.offset != nil {
+		fields = append(fields, zap.Int64("offset", *s.offset))
+	}
+
+	return fields
+}
+
+// recordItemStatuses appends a history row for every item whose status
+// differs from the stored one; it must run before the items are replaced.
+func (c *Consumer) recordItemStatuses(ctx context.Context, tx pgx.Tx, orderID int64, items []models.ItemRequest, source orderSource) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	chrtIDs := make([]int32, 0, len(items))
+	rids := make([]string, 0, len(items))
+	statuses := make([]int32, 0, len(items))
+	for _, item := range items {
+		chrtIDs = append(chrtIDs, int32(item.ChrtID))
+		rids = append(rids, item.Rid)
+		statuses = append(statuses, int32(item.Status))
+	}
+
+	query := `
+        INSERT INTO item_status_history (
+            order_id, chrt_id, previous_status, status,
+            source_topic, source_partition, source_offset, changed_at
+        )
+        SELECT $1, n.chrt_id, i.status, n.status, $5, $6, $7, $8
+        FROM unnest($2::INTEGER[], $3::TEXT[], $4::INTEGER[]) AS n (chrt_id, rid, status)
+        LEFT JOIN item i ON i.order_id = $1 AND i.rid = n.rid
+        WHERE i.status IS DISTINCT FROM n.status
+    `
+
+	_, err := tx.Exec(ctx, query,
+		orderID,
+		chrtIDs,
+		rids,
+		statuses,
+		source.topic,
+		source.partition,
+		source.offset,
+		source.changedAt(),
+	)
+
+	return err
+}
+
+func (c *Consumer) recordItemStatusChange(ctx context.Context, tx pgx.Tx, event *models.ItemStatusChangedEvent, source orderSource) error {
+	query := `
+        INSERT INTO item_status_history (
+            order_id, chrt_id, previous_status, status,
+            source_topic, source_partition, source_offset, changed_at
+        )
+        SELECT i.order_id, i.chrt_id, i.status, $1, $4, $5, $6, $7
+        FROM item i
+        WHERE i.rid = $2
+            AND i.order_id = (SELECT id FROM "order" WHERE order_uid = $3)
+            AND i.status <> $1
+    `
+
+	_, err := tx.Exec(ctx, query,
+		event.Status,
+		event.Rid,
+		event.OrderUID,
+		source.topic,
+		source.partition,
+		source.offset,
+		source.changedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record item status change: %w", err)
+	}
+
+	return nil
+}
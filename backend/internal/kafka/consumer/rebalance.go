@@ -0,0 +1,118 @@
+package consumer
+
+import (
+	"fmt"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"go.uber.org/zap"
+)
+
+func (c *Consumer) rebalanceCallback(_ *kafka.Consumer, event kafka.Event) error {
+	switch e := event.(type) {
+	case kafka.AssignedPartitions:
+		logger.Info("partitions assigned",
+			zap.String("group_id", c.config.GroupID),
+			zap.Strings("partitions", formatPartitions(e.Partitions)))
+
+	case kafka.RevokedPartitions:
+		lost := c.consumer.AssignmentLost()
+		logger.Info("partitions revoked",
+			zap.String("group_id", c.config.GroupID),
+			zap.Strings("partitions", formatPartitions(e.Partitions)),
+			zap.Bool("lost", lost))
+
+		c.flushBeforeRevoke(e.Partitions, lost)
+		c.forgetPartitions(e.Partitions)
+
+	default:
+		logger.Warn("unexpected rebalance event",
+			zap.String("event_type", fmt.Sprintf("%T", e)))
+	}
+
+	return nil
+}
+
+func (c *Consumer) flushBeforeRevoke(partitions []kafka.TopicPartition, lost bool) {
+	req := flushRequest{
+		partitions: partitions,
+		lost:       lost,
+		done:       make(chan struct{}),
+	}
+
+	select {
+	case c.flushChan <- req:
+	case <-c.stopChan:
+		return
+	}
+
+	select {
+	case <-req.done:
+		logger.Info("in-flight batch flushed before revocation",
+			zap.Int("partitions", len(partitions)))
+	case <-c.stopChan:
+	}
+}
+
+func (c *Consumer) drainQueued(batch []*kafka.Message) []*kafka.Message {
+	for {
+		select {
+		case msg := <-c.batchChan:
+			batch = append(batch, msg)
+		default:
+			return batch
+		}
+	}
+}
+
+func dropPartitions(batch []*kafka.Message, partitions []kafka.TopicPartition) []*kafka.Message {
+	revoked := make(map[string]struct{}, len(partitions))
+	for _, tp := range partitions {
+		revoked[partitionKey(tp)] = struct{}{}
+	}
+
+	kept := batch[:0]
+	dropped := 0
+	for _, msg := range batch {
+		if msg == nil {
+			continue
+		}
+		if _, ok := revoked[partitionKey(msg.TopicPartition)]; ok {
+			dropped++
+			continue
+		}
+		kept = append(kept, msg)
+	}
+
+	if dropped > 0 {
+		logger.Warn("dropped queued messages for lost partitions",
+			zap.Int("dropped", dropped))
+	}
+
+	return kept
+}
+
+func (c *Consumer) forgetPartitions(partitions []kafka.TopicPartition) {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+
+	for _, tp := range partitions {
+		delete(c.state.lastProcessed, partitionKey(tp))
+	}
+}
+
+func partitionKey(tp kafka.TopicPartition) string {
+	var topic string
+	if tp.Topic != nil {
+		topic = *tp.Topic
+	}
+	return fmt.Sprintf("%s/%d", topic, tp.Partition)
+}
+
+func formatPartitions(partitions []kafka.TopicPartition) []string {
+	result := make([]string, 0, len(partitions))
+	for _, tp := range partitions {
+		result = append(result, partitionKey(tp))
+	}
+	return result
+}
@@ -0,0 +1,285 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/supchaser/wb_l0/internal/metrics"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/responses"
+	"go.uber.org/zap"
+)
+
+type PartitionStatus struct {
+	Topic               string `json:"topic"`
+	Partition           int32  `json:"partition"`
+	CommittedOffset     int64  `json:"committed_offset"`
+	HighWatermark       int64  `json:"high_watermark"`
+	Lag                 int64  `json:"lag"`
+	LastProcessedOffset int64  `json:"last_processed_offset"`
+}
+
+type Status struct {
+	GroupID      string            `json:"group_id"`
+	Topic        string            `json:"topic"`
+	Paused       bool              `json:"paused"`
+	TotalLag     int64             `json:"total_lag"`
+	Partitions   []PartitionStatus `json:"partitions"`
+	LastBatchAt  *time.Time        `json:"last_batch_at,omitempty"`
+	LagUpdatedAt *time.Time        `json:"lag_updated_at,omitempty"`
+}
+
+type consumerState struct {
+	mu            sync.RWMutex
+	lastProcessed map[string]int64
+	partitions    []PartitionStatus
+	totalLag      int64
+	lastBatchAt   time.Time
+	lagUpdatedAt  time.Time
+	paused        bool
+}
+
+func (c *Consumer) lagTracker() {
+	defer c.wg.Done()
+
+	interval := lagInterval
+	if c.config.LagIntervalMs > 0 {
+		interval = time.Duration(c.config.LagIntervalMs) * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopChan:
+			logger.Info("lag tracker stopped")
+			return
+
+		case <-ticker.C:
+			partitions, err := c.computeLag(context.Background())
+			if err != nil {
+				logger.Warn("failed to compute consumer lag", zap.Error(err))
+				continue
+			}
+			c.updateLag(partitions)
+		}
+	}
+}
+
+// computeLag queries the committed offsets and watermarks of the assigned
+// partitions, each query bounded by the deadline of ctx.
+func (c *Consumer) computeLag(ctx context.Context) ([]PartitionStatus, error) {
+	timeout, err := timeoutMs(ctx, lagQueryTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned, err := c.consumer.Assignment()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment: %w", err)
+	}
+	if len(assigned) == 0 {
+		return nil, nil
+	}
+
+	committed, err := c.consumer.Committed(assigned, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get committed offsets: %w", err)
+	}
+
+	partitions := make([]PartitionStatus, 0, len(committed))
+	for _, tp := range committed {
+		if tp.Topic == nil {
+			continue
+		}
+
+		timeout, err := timeoutMs(ctx, lagQueryTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		low, high, err := c.consumer.QueryWatermarkOffsets(*tp.Topic, tp.Partition, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query watermarks for partition %d: %w", tp.Partition, err)
+		}
+
+		partitions = append(partitions, PartitionStatus{
+			Topic:           *tp.Topic,
+			Partition:       tp.Partition,
+			CommittedOffset: int64(tp.Offset),
+			HighWatermark:   high,
+			Lag:             partitionLag(int64(tp.Offset), low, high),
+		})
+	}
+
+	return partitions, nil
+}
+
+func partitionLag(committed, low, high int64) int64 {
+	if committed < 0 {
+		committed = low
+	}
+
+	lag := high - committed
+	if lag < 0 {
+		return 0
+	}
+
+	return lag
+}
+
+func (c *Consumer) updateLag(partitions []PartitionStatus) {
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].Partition < partitions[j].Partition
+	})
+
+	var total int64
+	metrics.ConsumerLag.Reset()
+	for _, p := range partitions {
+		total += p.Lag
+		metrics.ConsumerLag.WithLabelValues(p.Topic, strconv.Itoa(int(p.Partition))).Set(float64(p.Lag))
+	}
+
+	c.state.mu.Lock()
+	c.state.partitions = partitions
+	c.state.totalLag = total
+	c.state.lagUpdatedAt = time.Now()
+	c.state.mu.Unlock()
+
+	logger.Debug("consumer lag updated",
+		zap.Int("partitions", len(partitions)),
+		zap.Int64("total_lag", total))
+}
+
+func (c *Consumer) recordBatch(messages []*kafka.Message) {
+	now := time.Now()
+
+	c.state.mu.Lock()
+	if c.state.lastProcessed == nil {
+		c.state.lastProcessed = make(map[string]int64)
+	}
+	for _, msg := range messages {
+		if msg == nil {
+			continue
+		}
+
+		key := partitionKey(msg.TopicPartition)
+		offset := int64(msg.TopicPartition.Offset)
+		if offset > c.state.lastProcessed[key] {
+			c.state.lastProcessed[key] = offset
+		}
+
+		if msg.TopicPartition.Topic != nil {
+			metrics.ConsumerLastProcessedOffset.
+				WithLabelValues(*msg.TopicPartition.Topic, strconv.Itoa(int(msg.TopicPartition.Partition))).
+				Set(float64(offset))
+		}
+	}
+	c.state.lastBatchAt = now
+	c.state.mu.Unlock()
+
+	metrics.ConsumerLastBatchTimestamp.Set(float64(now.Unix()))
+}
+
+func (c *Consumer) pause() {
+	assigned, err := c.consumer.Assignment()
+	if err != nil {
+		logger.Warn("failed to get assignment for pause", zap.Error(err))
+		return
+	}
+
+	if err := c.consumer.Pause(assigned); err != nil {
+		logger.Warn("failed to pause partitions", zap.Error(err))
+		return
+	}
+
+	c.setPaused(true)
+}
+
+func (c *Consumer) resume() {
+	assigned, err := c.consumer.Assignment()
+	if err != nil {
+		logger.Warn("failed to get assignment for resume", zap.Error(err))
+		return
+	}
+
+	if err := c.consumer.Resume(assigned); err != nil {
+		logger.Warn("failed to resume partitions", zap.Error(err))
+		return
+	}
+
+	c.setPaused(false)
+}
+
+func (c *Consumer) setPaused(paused bool) {
+	c.state.mu.Lock()
+	c.state.paused = paused
+	c.state.mu.Unlock()
+
+	if paused {
+		metrics.ConsumerPaused.Set(1)
+	} else {
+		metrics.ConsumerPaused.Set(0)
+	}
+}
+
+func (c *Consumer) Status() Status {
+	c.state.mu.RLock()
+	defer c.state.mu.RUnlock()
+
+	status := Status{
+		Paused:     c.state.paused,
+		TotalLag:   c.state.totalLag,
+		Partitions: make([]PartitionStatus, 0, len(c.state.partitions)),
+	}
+	if c.config != nil {
+		status.GroupID = c.config.GroupID
+		status.Topic = c.config.Topic
+	}
+
+	for _, p := range c.state.partitions {
+		p.LastProcessedOffset = c.state.lastProcessed[partitionKey(kafka.TopicPartition{Topic: &p.Topic, Partition: p.Partition})]
+		status.Partitions = append(status.Partitions, p)
+	}
+
+	if !c.state.lastBatchAt.IsZero() {
+		lastBatchAt := c.state.lastBatchAt
+		status.LastBatchAt = &lastBatchAt
+	}
+	if !c.state.lagUpdatedAt.IsZero() {
+		lagUpdatedAt := c.state.lagUpdatedAt
+		status.LagUpdatedAt = &lagUpdatedAt
+	}
+
+	return status
+}
+
+// LagCheck refreshes the consumer lag, giving up by the deadline of ctx.
+func (c *Consumer) LagCheck(ctx context.Context) error {
+	partitions, err := c.computeLag(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to compute consumer lag: %w", err)
+	}
+	c.updateLag(partitions)
+
+	return c.checkLag(c.Status().TotalLag)
+}
+
+func (c *Consumer) checkLag(total int64) error {
+	if c.config.MaxReadyLag > 0 && total > int64(c.config.MaxReadyLag) {
+		return fmt.Errorf("consumer lag %d exceeds limit %d", total, c.config.MaxReadyLag)
+	}
+
+	return nil
+}
+
+func (c *Consumer) GetStatus(w http.ResponseWriter, r *http.Request) {
+	responses.DoJSONResponse(w, c.Status(), http.StatusOK)
+}
@@ -0,0 +1,81 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/config"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"github.com/supchaser/wb_l0/internal/utils/pgxiface"
+)
+
+// Store writes orders to Postgres through the same path as consumed order
+// messages, without a Kafka connection. source stands in for the topic in
+// the item status history.
+type Store struct {
+	consumer *Consumer
+	source   string
+}
+
+func CreateStore(cfg *config.ConsumerConfig, db pgxiface.PgxIface, cache *redis.Client, source string) (*Store, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("consumer config is required")
+	}
+
+	rules, err := createRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		consumer: &Consumer{config: cfg, db: db, cache: cache, rules: rules},
+		source:   source,
+	}, nil
+}
+
+// Save stores orders in a single transaction. It returns one entry per order,
+// set when the order was refused as invalid or stale, and an error when the
+// batch could not be stored at all.
+func (s *Store) Save(ctx context.Context, orders []models.OrderRequest) ([]error, error) {
+	tx, err := s.consumer.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	refused := make([]error, len(orders))
+	saved := make([]string, 0, len(orders))
+	for i := range orders {
+		order := &orders[i]
+		err := s.consumer.applyOrder(ctx, tx, s.orderSource(order), order)
+		switch {
+		case err == nil:
+			saved = append(saved, order.OrderUID)
+		case errors.Is(err, errs.ErrValidation), errors.Is(err, errs.ErrStaleVersion):
+			refused[i] = err
+		default:
+			return nil, fmt.Errorf("failed to save order %s: %w", order.OrderUID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.consumer.evictFromCache(ctx, saved)
+
+	return refused, nil
+}
+
+// orderSource describes an imported order to applyOrder. It has no
+// partition or offset, and orders without a version are versioned by
+// date_created, so a backfill does not overwrite updates consumed since.
+func (s *Store) orderSource(order *models.OrderRequest) orderSource {
+	return orderSource{
+		topic:     s.source,
+		timestamp: order.DateCreated,
+	}
+}
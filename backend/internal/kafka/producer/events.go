@@ -0,0 +1,110 @@
+package producer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"go.uber.org/zap"
+)
+
+func (p *Producer) ProduceOrderCreated(ctx context.Context, order models.OrderRequest, topic string) error {
+	return p.ProduceEvent(ctx, models.EventOrderCreated, order.OrderUID, order, topic)
+}
+
+func (p *Producer) ProduceOrderUpdated(ctx context.Context, order models.OrderRequest, topic string) error {
+	return p.ProduceEvent(ctx, models.EventOrderUpdated, order.OrderUID, order, topic)
+}
+
+func (p *Producer) ProduceOrderDeleted(ctx context.Context, orderUID string, topic string) error {
+	return p.ProduceEvent(ctx, models.EventOrderDeleted, orderUID, models.OrderDeletedEvent{OrderUID: orderUID}, topic)
+}
+
+func (p *Producer) ProduceItemStatusChanged(ctx context.Context, event models.ItemStatusChangedEvent, topic string) error {
+	return p.ProduceEvent(ctx, models.EventItemStatusChanged, event.OrderUID, event, topic)
+}
+
+func (p *Producer) ProducePaymentCaptured(ctx context.Context, event models.PaymentCapturedEvent, topic string) error {
+	return p.ProduceEvent(ctx, models.EventPaymentCaptured, event.OrderUID, event, topic)
+}
+
+func (p *Producer) ProduceDeliveryAddressChanged(ctx context.Context, event models.DeliveryAddressChangedEvent, topic string) error {
+	return p.ProduceEvent(ctx, models.EventDeliveryAddressChanged, event.OrderUID, event, topic)
+}
+
+// ProduceEvent wraps payload into an event envelope keyed by order UID, so
+// all events of one order land in the same partition.
+func (p *Producer) ProduceEvent(ctx context.Context, eventType models.EventTypeEnum, orderUID string, payload any, topic string) error {
+	message, err := buildEventMessage(eventType, orderUID, payload, topic)
+	if err != nil {
+		logger.Error("failed to build event message",
+			zap.String("event_type", string(eventType)),
+			zap.String("order_uid", orderUID),
+			zap.Error(err))
+		return err
+	}
+
+	logger.Debug("producing order event to Kafka",
+		zap.String("event_type", string(eventType)),
+		zap.String("order_uid", orderUID),
+		zap.String("topic", topic))
+
+	ctx, cancel := context.WithTimeout(ctx, defaultDeliveryTimeout)
+	defer cancel()
+
+	return p.produceWithRetry(ctx, message, maxRetries)
+}
+
+func buildEventMessage(eventType models.EventTypeEnum, orderUID string, payload any, topic string) (*kafka.Message, error) {
+	payloadInBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+
+	eventID, err := newEventID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate event id: %w", err)
+	}
+
+	now := time.Now()
+	envelope, err := json.Marshal(models.EventEnvelope{
+		EventType:  eventType,
+		EventID:    eventID,
+		OccurredAt: now.UTC(),
+		Payload:    payloadInBytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event envelope: %w", err)
+	}
+
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{
+			Topic:     &topic,
+			Partition: kafka.PartitionAny,
+		},
+		Value: envelope,
+		Key:   []byte(orderUID),
+		Headers: []kafka.Header{
+			{Key: codec.HeaderVersion, Value: []byte(codec.PayloadVersion)},
+			{Key: codec.HeaderContentType, Value: []byte(codec.ContentTypeEvent)},
+			{Key: codec.HeaderEventType, Value: []byte(eventType)},
+		},
+		Timestamp: now,
+	}, nil
+}
+
+func newEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
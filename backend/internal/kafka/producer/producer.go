@@ -2,7 +2,6 @@ package producer
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -12,6 +11,8 @@ import (
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/supchaser/wb_l0/internal/app/models"
 	"github.com/supchaser/wb_l0/internal/config"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/kafka/registry"
 	"github.com/supchaser/wb_l0/internal/utils/errs"
 	"github.com/supchaser/wb_l0/internal/utils/logger"
 	"go.uber.org/zap"
@@ -25,6 +26,7 @@ const (
 
 type Producer struct {
 	producer     *kafka.Producer
+	codec        codec.Codec
 	Config       *config.ProducerConfig
 	wg           sync.WaitGroup
 	closeOnce    sync.Once
@@ -71,6 +73,26 @@ func CreateProducer(cfg *config.ProducerConfig) (*Producer, error) {
 		logger.Info("set default retries", zap.Int("retries", maxRetries))
 	}
 
+	payloadFormat := cfg.PayloadFormat
+	if payloadFormat == "" {
+		payloadFormat = codec.FormatJSON
+	}
+
+	orderCodec, err := codec.ForFormat(payloadFormat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select payload codec: %w", err)
+	}
+
+	schemaRegistry, err := registry.CreateClient(cfg.SchemaRegistryURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema registry client: %w", err)
+	}
+
+	orderCodec, err = registry.Wrap(context.Background(), schemaRegistry, orderCodec, registry.SubjectName(cfg.Topic))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register payload schema: %w", err)
+	}
+
 	p, err := kafka.NewProducer(kafkaConfig)
 	if err != nil {
 		logger.Error("failed to create Kafka producer", zap.Error(err))
@@ -79,6 +101,7 @@ func CreateProducer(cfg *config.ProducerConfig) (*Producer, error) {
 
 	producer := &Producer{
 		producer:     p,
+		codec:        orderCodec,
 		Config:       cfg,
 		deliveryChan: make(chan kafka.Event, 1000),
 	}
@@ -97,15 +120,25 @@ func (p *Producer) Produce(ctx context.Context, order models.OrderRequest, topic
 		zap.String("order_uid", order.OrderUID),
 		zap.String("topic", topic))
 
-	orderInBytes, err := json.Marshal(order)
+	message, err := p.OrderMessage(order, topic)
 	if err != nil {
 		logger.Error("failed to marshal order",
 			zap.String("order_uid", order.OrderUID),
 			zap.Error(err))
-		return fmt.Errorf("failed to marshal order: %w", err)
+		return err
 	}
 
-	message := &kafka.Message{
+	return p.ProduceMessage(ctx, message)
+}
+
+// OrderMessage encodes order with the producer's codec, keyed by order UID.
+func (p *Producer) OrderMessage(order models.OrderRequest, topic string) (*kafka.Message, error) {
+	orderInBytes, err := p.codec.Marshal(&order)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal order: %w", err)
+	}
+
+	return &kafka.Message{
 		TopicPartition: kafka.TopicPartition{
 			Topic:     &topic,
 			Partition: kafka.PartitionAny,
@@ -113,12 +146,15 @@ func (p *Producer) Produce(ctx context.Context, order models.OrderRequest, topic
 		Value: orderInBytes,
 		Key:   []byte(order.OrderUID),
 		Headers: []kafka.Header{
-			{Key: "version", Value: []byte("1.0")},
-			{Key: "content-type", Value: []byte("application/json")},
+			{Key: codec.HeaderVersion, Value: []byte(codec.PayloadVersion)},
+			{Key: codec.HeaderContentType, Value: []byte(p.codec.ContentType())},
 		},
 		Timestamp: time.Now(),
-	}
+	}, nil
+}
 
+// ProduceMessage sends message as is and waits for its delivery.
+func (p *Producer) ProduceMessage(ctx context.Context, message *kafka.Message) error {
 	ctx, cancel := context.WithTimeout(ctx, defaultDeliveryTimeout)
 	defer cancel()
 
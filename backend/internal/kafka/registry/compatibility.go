@@ -0,0 +1,103 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hamba/avro/v2"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+var (
+	protoMessageRegex = regexp.MustCompile(`(?m)^\s*message\s+(\w+)\s*\{`)
+	protoFieldRegex   = regexp.MustCompile(`(?m)^\s*(repeated\s+)?([\w.]+)\s+(\w+)\s*=\s*(\d+)\s*;`)
+)
+
+// CheckCompatibility reports whether data written with previous can still be
+// read with next (backward compatibility).
+func CheckCompatibility(schemaType, previous, next string) error {
+	switch schemaType {
+	case codec.SchemaTypeAvro:
+		return checkAvroCompatibility(previous, next)
+	case codec.SchemaTypeProtobuf:
+		return checkProtobufCompatibility(previous, next)
+	default:
+		return nil
+	}
+}
+
+func checkAvroCompatibility(previous, next string) error {
+	writer, err := avro.Parse(previous)
+	if err != nil {
+		return fmt.Errorf("failed to parse previous avro schema: %w", err)
+	}
+
+	reader, err := avro.Parse(next)
+	if err != nil {
+		return fmt.Errorf("failed to parse new avro schema: %w", err)
+	}
+
+	if err := avro.NewSchemaCompatibility().Compatible(reader, writer); err != nil {
+		return fmt.Errorf("%w: %v", errs.ErrIncompatibleSchema, err)
+	}
+
+	return nil
+}
+
+type protoField struct {
+	name     string
+	typ      string
+	repeated bool
+}
+
+func checkProtobufCompatibility(previous, next string) error {
+	oldMessages := parseProtoMessages(previous)
+	newMessages := parseProtoMessages(next)
+
+	for message, oldFields := range oldMessages {
+		newFields, ok := newMessages[message]
+		if !ok {
+			return fmt.Errorf("%w: message %s was removed", errs.ErrIncompatibleSchema, message)
+		}
+
+		for number, oldField := range oldFields {
+			newField, ok := newFields[number]
+			if !ok {
+				continue
+			}
+			if newField.typ != oldField.typ || newField.repeated != oldField.repeated {
+				return fmt.Errorf("%w: field %s.%s (%s) changed type from %s to %s",
+					errs.ErrIncompatibleSchema, message, oldField.name, number, oldField.typ, newField.typ)
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseProtoMessages(definition string) map[string]map[string]protoField {
+	messages := make(map[string]map[string]protoField)
+
+	bounds := protoMessageRegex.FindAllStringSubmatchIndex(definition, -1)
+	for i, bound := range bounds {
+		name := definition[bound[2]:bound[3]]
+
+		end := len(definition)
+		if i+1 < len(bounds) {
+			end = bounds[i+1][0]
+		}
+
+		fields := make(map[string]protoField)
+		for _, match := range protoFieldRegex.FindAllStringSubmatch(definition[bound[1]:end], -1) {
+			fields[match[4]] = protoField{
+				name:     match[3],
+				typ:      match[2],
+				repeated: match[1] != "",
+			}
+		}
+		messages[name] = fields
+	}
+
+	return messages
+}
@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+const (
+	contentTypeSchemaRegistry = "application/vnd.schemaregistry.v1+json"
+	httpTimeout               = 10 * time.Second
+)
+
+type HTTPClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+type schemaPayload struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+func CreateHTTPClient(baseURL string) *HTTPClient {
+	return &HTTPClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: httpTimeout},
+	}
+}
+
+func (h *HTTPClient) Register(ctx context.Context, subject string, schema Schema) (int, error) {
+	payload := schemaPayload{Schema: schema.Definition, SchemaType: schema.Type}
+
+	var compatibility struct {
+		IsCompatible bool     `json:"is_compatible"`
+		Messages     []string `json:"messages"`
+	}
+	status, err := h.do(ctx, http.MethodPost,
+		fmt.Sprintf("/compatibility/subjects/%s/versions/latest?verbose=true", url.PathEscape(subject)),
+		payload, &compatibility)
+	if err != nil && status != http.StatusNotFound {
+		return 0, fmt.Errorf("failed to check compatibility: %w", err)
+	}
+	if err == nil && !compatibility.IsCompatible {
+		return 0, fmt.Errorf("%w: subject %s: %s",
+			errs.ErrIncompatibleSchema, subject, strings.Join(compatibility.Messages, "; "))
+	}
+
+	var registered struct {
+		ID int `json:"id"`
+	}
+	if _, err := h.do(ctx, http.MethodPost,
+		fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject)),
+		payload, &registered); err != nil {
+		return 0, fmt.Errorf("failed to register schema: %w", err)
+	}
+
+	return registered.ID, nil
+}
+
+func (h *HTTPClient) GetByID(ctx context.Context, id int) (Schema, error) {
+	var payload schemaPayload
+	status, err := h.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &payload)
+	if err != nil {
+		if status == http.StatusNotFound {
+			return Schema{}, fmt.Errorf("%w: schema id %d", errs.ErrNotFound, id)
+		}
+		return Schema{}, fmt.Errorf("failed to fetch schema %d: %w", id, err)
+	}
+
+	schemaType := payload.SchemaType
+	if schemaType == "" {
+		schemaType = "AVRO"
+	}
+
+	return Schema{Type: schemaType, Definition: payload.Schema}, nil
+}
+
+func (h *HTTPClient) do(ctx context.Context, method, path string, body, result any) (int, error) {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return 0, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, h.baseURL+path, &reqBody)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentTypeSchemaRegistry)
+	req.Header.Set("Accept", contentTypeSchemaRegistry)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var registryErr struct {
+			ErrorCode int    `json:"error_code"`
+			Message   string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&registryErr)
+		return resp.StatusCode, fmt.Errorf("schema registry returned %d: %s", resp.StatusCode, registryErr.Message)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,61 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+type MemoryClient struct {
+	mu       sync.RWMutex
+	schemas  []Schema
+	subjects map[string][]int
+}
+
+func CreateMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		subjects: make(map[string][]int),
+	}
+}
+
+func (m *MemoryClient) Register(ctx context.Context, subject string, schema Schema) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	versions := m.subjects[subject]
+	for _, id := range versions {
+		if m.schemas[id-1] == schema {
+			return id, nil
+		}
+	}
+
+	if len(versions) > 0 {
+		latest := m.schemas[versions[len(versions)-1]-1]
+		if latest.Type != schema.Type {
+			return 0, fmt.Errorf("%w: subject %s changed schema type from %s to %s",
+				errs.ErrIncompatibleSchema, subject, latest.Type, schema.Type)
+		}
+		if err := CheckCompatibility(schema.Type, latest.Definition, schema.Definition); err != nil {
+			return 0, fmt.Errorf("subject %s: %w", subject, err)
+		}
+	}
+
+	m.schemas = append(m.schemas, schema)
+	id := len(m.schemas)
+	m.subjects[subject] = append(versions, id)
+
+	return id, nil
+}
+
+func (m *MemoryClient) GetByID(ctx context.Context, id int) (Schema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if id <= 0 || id > len(m.schemas) {
+		return Schema{}, fmt.Errorf("%w: schema id %d", errs.ErrNotFound, id)
+	}
+
+	return m.schemas[id-1], nil
+}
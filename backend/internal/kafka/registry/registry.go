@@ -0,0 +1,63 @@
+package registry
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+const (
+	magicByte  = 0x00
+	headerSize = 5
+
+	memoryURL = "memory://"
+)
+
+type Schema struct {
+	Type       string
+	Definition string
+}
+
+type Client interface {
+	Register(ctx context.Context, subject string, schema Schema) (int, error)
+	GetByID(ctx context.Context, id int) (Schema, error)
+}
+
+func CreateClient(url string) (Client, error) {
+	switch {
+	case url == "":
+		return nil, nil
+	case url == memoryURL:
+		return CreateMemoryClient(), nil
+	case strings.HasPrefix(url, "http://"), strings.HasPrefix(url, "https://"):
+		return CreateHTTPClient(url), nil
+	default:
+		return nil, fmt.Errorf("unsupported schema registry url %q", url)
+	}
+}
+
+func SubjectName(topic string) string {
+	return topic + "-value"
+}
+
+func Encode(schemaID int, payload []byte) []byte {
+	data := make([]byte, headerSize, headerSize+len(payload))
+	data[0] = magicByte
+	binary.BigEndian.PutUint32(data[1:headerSize], uint32(schemaID))
+	return append(data, payload...)
+}
+
+func Decode(data []byte) (int, []byte, error) {
+	if !IsFramed(data) {
+		return 0, nil, fmt.Errorf("%w: payload is not in schema registry wire format", errs.ErrUnsupportedVersion)
+	}
+
+	return int(binary.BigEndian.Uint32(data[1:headerSize])), data[headerSize:], nil
+}
+
+func IsFramed(data []byte) bool {
+	return len(data) >= headerSize && data[0] == magicByte
+}
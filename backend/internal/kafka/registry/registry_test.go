@@ -0,0 +1,226 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitTestLogger()
+	os.Exit(m.Run())
+}
+
+func createTestOrder() *models.OrderRequest {
+	return &models.OrderRequest{
+		OrderUID:        "b563feb7b2b84b6test",
+		TrackNumber:     "WBILMTESTTRACK",
+		Entry:           "WBIL",
+		Locale:          models.LocaleEN,
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		Shardkey:        "9",
+		SmID:            99,
+		DateCreated:     time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		OofShard:        "1",
+		Version:         7,
+		Delivery: models.DeliveryRequest{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.PaymentRequest{
+			Transaction:  "b563feb7b2b84b6test",
+			Currency:     models.CurrencyUSD,
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDt:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []models.ItemRequest{
+			{
+				ChrtID:      9934930,
+				TrackNumber: "WBILMTESTTRACK",
+				Price:       453,
+				Rid:         "ab4219087a764ae0btest",
+				Name:        "Mascaras",
+				Sale:        30,
+				Size:        "0",
+				TotalPrice:  317,
+				NmID:        2389212,
+				Brand:       "Vivienne Sabo",
+				Status:      202,
+			},
+		},
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	data := Encode(42, []byte("payload"))
+
+	assert.Equal(t, []byte{0, 0, 0, 0, 42}, data[:headerSize])
+	assert.True(t, IsFramed(data))
+
+	id, payload, err := Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, id)
+	assert.Equal(t, []byte("payload"), payload)
+
+	_, _, err = Decode([]byte(`{"order_uid":"x"}`))
+	assert.True(t, errors.Is(err, errs.ErrUnsupportedVersion))
+}
+
+func TestCreateClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "disabled", url: "", wantNil: true},
+		{name: "memory", url: "memory://"},
+		{name: "http", url: "http://localhost:8081"},
+		{name: "unsupported", url: "ftp://registry", wantNil: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := CreateClient(tt.url)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantNil, client == nil)
+		})
+	}
+}
+
+func TestMemoryClient_Register(t *testing.T) {
+	const (
+		v1 = `{"type":"record","name":"Order","fields":[{"name":"order_uid","type":"string"}]}`
+		v2 = `{"type":"record","name":"Order","fields":[{"name":"order_uid","type":"string"},{"name":"version","type":"long","default":0}]}`
+		v3 = `{"type":"record","name":"Order","fields":[{"name":"order_uid","type":"long"}]}`
+		v4 = `{"type":"record","name":"Order","fields":[{"name":"order_uid","type":"string"},{"name":"entry","type":"string"}]}`
+
+		p1 = "message Order {\n  string order_uid = 1;\n}\n"
+		p2 = "message Order {\n  string order_uid = 1;\n  int64 version = 2;\n}\n"
+		p3 = "message Order {\n  int64 order_uid = 1;\n}\n"
+	)
+
+	ctx := context.Background()
+	client := CreateMemoryClient()
+
+	id1, err := client.Register(ctx, "orders-value", Schema{Type: codec.SchemaTypeAvro, Definition: v1})
+	assert.NoError(t, err)
+
+	again, err := client.Register(ctx, "orders-value", Schema{Type: codec.SchemaTypeAvro, Definition: v1})
+	assert.NoError(t, err)
+	assert.Equal(t, id1, again)
+
+	id2, err := client.Register(ctx, "orders-value", Schema{Type: codec.SchemaTypeAvro, Definition: v2})
+	assert.NoError(t, err)
+	assert.NotEqual(t, id1, id2)
+
+	_, err = client.Register(ctx, "orders-value", Schema{Type: codec.SchemaTypeAvro, Definition: v3})
+	assert.True(t, errors.Is(err, errs.ErrIncompatibleSchema))
+
+	_, err = client.Register(ctx, "orders-value", Schema{Type: codec.SchemaTypeAvro, Definition: v4})
+	assert.True(t, errors.Is(err, errs.ErrIncompatibleSchema))
+
+	_, err = client.Register(ctx, "orders-value", Schema{Type: codec.SchemaTypeProtobuf, Definition: p1})
+	assert.True(t, errors.Is(err, errs.ErrIncompatibleSchema))
+
+	_, err = client.Register(ctx, "orders-proto-value", Schema{Type: codec.SchemaTypeProtobuf, Definition: p1})
+	assert.NoError(t, err)
+
+	_, err = client.Register(ctx, "orders-proto-value", Schema{Type: codec.SchemaTypeProtobuf, Definition: p2})
+	assert.NoError(t, err)
+
+	_, err = client.Register(ctx, "orders-proto-value", Schema{Type: codec.SchemaTypeProtobuf, Definition: p3})
+	assert.True(t, errors.Is(err, errs.ErrIncompatibleSchema))
+
+	schema, err := client.GetByID(ctx, id2)
+	assert.NoError(t, err)
+	assert.Equal(t, v2, schema.Definition)
+
+	_, err = client.GetByID(ctx, 100)
+	assert.True(t, errors.Is(err, errs.ErrNotFound))
+}
+
+func TestWrap_RoundTrip(t *testing.T) {
+	for _, format := range []string{codec.FormatAvro, codec.FormatProtobuf} {
+		t.Run(format, func(t *testing.T) {
+			client := CreateMemoryClient()
+
+			c, err := codec.ForFormat(format)
+			assert.NoError(t, err)
+
+			serializer, err := Wrap(context.Background(), client, c, SubjectName("orders"))
+			assert.NoError(t, err)
+
+			order := createTestOrder()
+			data, err := serializer.Marshal(order)
+			assert.NoError(t, err)
+			assert.True(t, IsFramed(data))
+
+			deserializer := WrapDeserializer(client, c)
+			decoded, err := deserializer.Unmarshal(data)
+			assert.NoError(t, err)
+			assert.Equal(t, order, decoded)
+
+			if format == codec.FormatProtobuf {
+				plain, err := c.Marshal(order)
+				assert.NoError(t, err)
+				decoded, err = deserializer.Unmarshal(plain)
+				assert.NoError(t, err)
+				assert.Equal(t, order, decoded)
+			}
+		})
+	}
+}
+
+func TestWrap_JSONUnchanged(t *testing.T) {
+	c, err := codec.ForFormat(codec.FormatJSON)
+	assert.NoError(t, err)
+
+	wrapped, err := Wrap(context.Background(), CreateMemoryClient(), c, SubjectName("orders"))
+	assert.NoError(t, err)
+	assert.Equal(t, c, wrapped)
+	assert.Equal(t, c, WrapDeserializer(nil, c))
+}
+
+func TestWrapDeserializer_OlderAvroWriter(t *testing.T) {
+	c, err := codec.ForFormat(codec.FormatAvro)
+	assert.NoError(t, err)
+
+	current := c.(codec.SchemaProvider).SchemaDefinition()
+	older := strings.Replace(current, `{"name": "version", "type": "long", "default": 0},`, "", 1)
+	assert.NotEqual(t, current, older)
+
+	client := CreateMemoryClient()
+	id, err := client.Register(context.Background(), SubjectName("orders"), Schema{Type: codec.SchemaTypeAvro, Definition: older})
+	assert.NoError(t, err)
+
+	order := createTestOrder()
+	payload, err := avro.Config{TagKey: "json"}.Freeze().Marshal(avro.MustParse(older), order)
+	assert.NoError(t, err)
+
+	decoded, err := WrapDeserializer(client, c).Unmarshal(Encode(id, payload))
+	assert.NoError(t, err)
+
+	order.Version = 0
+	assert.Equal(t, order, decoded)
+}
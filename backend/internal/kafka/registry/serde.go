@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type registryCodec struct {
+	codec.Codec
+	client     Client
+	schemaType string
+	schemaID   int
+
+	mu      sync.RWMutex
+	schemas map[int]Schema
+}
+
+// Wrap registers the codec schema under subject and returns a codec that
+// speaks the schema registry wire format. Codecs without a schema and a nil
+// client are returned unchanged.
+func Wrap(ctx context.Context, client Client, c codec.Codec, subject string) (codec.Codec, error) {
+	provider, ok := c.(codec.SchemaProvider)
+	if client == nil || !ok {
+		return c, nil
+	}
+
+	schema := Schema{Type: provider.SchemaType(), Definition: provider.SchemaDefinition()}
+	id, err := client.Register(ctx, subject, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register %s schema for %s: %w", schema.Type, subject, err)
+	}
+
+	return &registryCodec{
+		Codec:      c,
+		client:     client,
+		schemaType: schema.Type,
+		schemaID:   id,
+		schemas:    map[int]Schema{id: schema},
+	}, nil
+}
+
+// WrapDeserializer returns a codec that decodes the schema registry wire
+// format without registering a schema, looking writer schemas up by ID.
+func WrapDeserializer(client Client, c codec.Codec) codec.Codec {
+	provider, ok := c.(codec.SchemaProvider)
+	if client == nil || !ok {
+		return c
+	}
+
+	return &registryCodec{
+		Codec:      c,
+		client:     client,
+		schemaType: provider.SchemaType(),
+		schemas:    make(map[int]Schema),
+	}
+}
+
+func (r *registryCodec) Marshal(order *models.OrderRequest) ([]byte, error) {
+	if r.schemaID == 0 {
+		return nil, fmt.Errorf("%w: codec has no registered schema", errs.ErrUnsupportedVersion)
+	}
+
+	payload, err := r.Codec.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.schemaType == codec.SchemaTypeProtobuf {
+		// message index [0] (the first message in the file) has a
+		// single zero byte as its short form
+		payload = append([]byte{0}, payload...)
+	}
+
+	return Encode(r.schemaID, payload), nil
+}
+
+func (r *registryCodec) Unmarshal(data []byte) (*models.OrderRequest, error) {
+	if !IsFramed(data) {
+		return r.Codec.Unmarshal(data)
+	}
+
+	id, payload, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := r.schema(id)
+	if err != nil {
+		return nil, err
+	}
+	if schema.Type != r.schemaType {
+		return nil, fmt.Errorf("%w: schema %d is %s, expected %s", errs.ErrUnsupportedVersion, id, schema.Type, r.schemaType)
+	}
+
+	switch r.schemaType {
+	case codec.SchemaTypeAvro:
+		if provider, ok := r.Codec.(codec.SchemaProvider); ok && provider.SchemaDefinition() == schema.Definition {
+			return r.Codec.Unmarshal(payload)
+		}
+		return codec.UnmarshalAvroWithWriterSchema(payload, schema.Definition)
+	case codec.SchemaTypeProtobuf:
+		payload, err = skipMessageIndexes(payload)
+		if err != nil {
+			return nil, err
+		}
+		return r.Codec.Unmarshal(payload)
+	default:
+		return r.Codec.Unmarshal(payload)
+	}
+}
+
+func (r *registryCodec) schema(id int) (Schema, error) {
+	r.mu.RLock()
+	schema, ok := r.schemas[id]
+	r.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema, err := r.client.GetByID(context.Background(), id)
+	if err != nil {
+		return Schema{}, fmt.Errorf("failed to fetch schema %d: %w", id, err)
+	}
+
+	r.mu.Lock()
+	r.schemas[id] = schema
+	r.mu.Unlock()
+
+	return schema, nil
+}
+
+func skipMessageIndexes(data []byte) ([]byte, error) {
+	count, n := protowire.ConsumeVarint(data)
+	if n < 0 {
+		return nil, fmt.Errorf("invalid protobuf message indexes: %w", protowire.ParseError(n))
+	}
+	data = data[n:]
+
+	for i := int64(0); i < protowire.DecodeZigZag(count); i++ {
+		_, n = protowire.ConsumeVarint(data)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid protobuf message index: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+	}
+
+	return data, nil
+}
@@ -0,0 +1,320 @@
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/metrics"
+)
+
+type Fault string
+
+const (
+	FaultNone            Fault = ""
+	FaultMalformedJSON   Fault = "malformed-json"
+	FaultMissingField    Fault = "missing-field"
+	FaultInvalidEnum     Fault = "invalid-enum"
+	FaultOversizedString Fault = "oversized-string"
+	FaultDuplicateUID    Fault = "duplicate-uid"
+	FaultStaleVersion    Fault = "stale-version"
+	FaultTombstone       Fault = "tombstone"
+)
+
+var AllFaults = []Fault{
+	FaultMalformedJSON,
+	FaultMissingField,
+	FaultInvalidEnum,
+	FaultOversizedString,
+	FaultDuplicateUID,
+	FaultStaleVersion,
+	FaultTombstone,
+}
+
+const (
+	// oversizedLength is longer than any max_length in the default rules.
+	oversizedLength = 4096
+	// deliveredPoolSize bounds the delivered order UIDs kept as targets for
+	// duplicate, stale and tombstone faults.
+	deliveredPoolSize = 1000
+	// staleAge backdates the timestamp of a stale version, which the consumer
+	// versions orders by, past any earlier delivery of the same order.
+	staleAge = 7 * 24 * time.Hour
+)
+
+// ParseFaults parses fault names; no names enables every fault.
+func ParseFaults(names []string) ([]Fault, error) {
+	if len(names) == 0 {
+		return AllFaults, nil
+	}
+
+	faults := make([]Fault, 0, len(names))
+	for _, name := range names {
+		fault := Fault(strings.TrimSpace(name))
+		if !isKnownFault(fault) {
+			return nil, fmt.Errorf("unknown fault %q", name)
+		}
+		faults = append(faults, fault)
+	}
+
+	return faults, nil
+}
+
+func isKnownFault(fault Fault) bool {
+	for _, known := range AllFaults {
+		if fault == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// needsTarget reports whether the fault reuses the UID of an order that was
+// already delivered.
+func (f Fault) needsTarget() bool {
+	return f == FaultDuplicateUID || f == FaultStaleVersion || f == FaultTombstone
+}
+
+// expectedResult is the consumer_messages_total result the consumer should
+// count for a delivered message carrying the fault.
+func (f Fault) expectedResult() string {
+	switch f {
+	case FaultMalformedJSON, FaultMissingField, FaultInvalidEnum, FaultOversizedString:
+		return metrics.ResultFailed
+	case FaultStaleVersion:
+		return metrics.ResultStale
+	}
+
+	return metrics.ResultProcessed
+}
+
+// MessageProducer is the part of producer.Producer the injector needs to
+// send messages that Produce would refuse to build.
+type MessageProducer interface {
+	OrderMessage(order models.OrderRequest, topic string) (*kafka.Message, error)
+	ProduceMessage(ctx context.Context, message *kafka.Message) error
+}
+
+type FaultSummary struct {
+	Injected map[Fault]int64  `json:"injected"`
+	Expected map[string]int64 `json:"expected"`
+}
+
+// FaultInjector produces orders, replacing a fraction of them with faulty
+// messages that carry a codec.HeaderFault header. Whether an order is
+// replaced, and by which fault, depends only on the seed and its UID.
+//
+// Faults that reuse a delivered order check its UID out of the pool while
+// in flight, so two of them never race on the same order and the expected
+// consumer results stay exact.
+type FaultInjector struct {
+	producer MessageProducer
+	topic    string
+	rate     float64
+	faults   []Fault
+	seed     uint64
+
+	mu        sync.Mutex
+	delivered []string
+	injected  map[Fault]int64
+	expected  map[string]int64
+}
+
+func CreateFaultInjector(producer MessageProducer, topic string, rate float64, faults []Fault, seed int64) (*FaultInjector, error) {
+	if rate < 0 || rate > 1 {
+		return nil, fmt.Errorf("fault rate %v is not between 0 and 1", rate)
+	}
+	if len(faults) == 0 {
+		faults = AllFaults
+	}
+
+	return &FaultInjector{
+		producer: producer,
+		topic:    topic,
+		rate:     rate,
+		faults:   faults,
+		seed:     uint64(seed),
+		injected: make(map[Fault]int64),
+		expected: make(map[string]int64),
+	}, nil
+}
+
+// Produce is a ProduceFunc.
+func (f *FaultInjector) Produce(ctx context.Context, order models.OrderRequest) error {
+	r := rand.New(rand.NewPCG(f.seed, hashUID(order.OrderUID)))
+
+	fault := FaultNone
+	if r.Float64() < f.rate {
+		fault = f.faults[r.IntN(len(f.faults))]
+	}
+
+	var target string
+	if fault.needsTarget() {
+		var ok bool
+		if target, ok = f.checkOut(r); !ok {
+			fault = FaultNone
+		}
+	}
+
+	message, err := f.message(order, fault, target, r)
+	if err == nil {
+		err = f.producer.ProduceMessage(ctx, message)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err != nil {
+		if fault == FaultDuplicateUID || fault == FaultStaleVersion {
+			f.addDelivered(target)
+		}
+		return err
+	}
+
+	switch fault {
+	case FaultNone:
+		f.addDelivered(order.OrderUID)
+	case FaultDuplicateUID, FaultStaleVersion:
+		f.addDelivered(target)
+	}
+	if fault != FaultNone {
+		f.injected[fault]++
+	}
+	f.expected[fault.expectedResult()]++
+
+	return nil
+}
+
+func (f *FaultInjector) Summary() FaultSummary {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	summary := FaultSummary{
+		Injected: make(map[Fault]int64, len(f.injected)),
+		Expected: make(map[string]int64, len(f.expected)),
+	}
+	for fault, n := range f.injected {
+		summary.Injected[fault] = n
+	}
+	for result, n := range f.expected {
+		summary.Expected[result] = n
+	}
+
+	return summary
+}
+
+// checkOut takes a random delivered order UID out of the pool.
+func (f *FaultInjector) checkOut(r *rand.Rand) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.delivered) == 0 {
+		return "", false
+	}
+
+	i := r.IntN(len(f.delivered))
+	target := f.delivered[i]
+	last := len(f.delivered) - 1
+	f.delivered[i] = f.delivered[last]
+	f.delivered = f.delivered[:last]
+
+	return target, true
+}
+
+func (f *FaultInjector) addDelivered(orderUID string) {
+	if len(f.delivered) >= deliveredPoolSize {
+		f.delivered = f.delivered[1:]
+	}
+	f.delivered = append(f.delivered, orderUID)
+}
+
+func (f *FaultInjector) message(order models.OrderRequest, fault Fault, target string, r *rand.Rand) (*kafka.Message, error) {
+	switch fault {
+	case FaultNone:
+		return f.producer.OrderMessage(order, f.topic)
+
+	case FaultTombstone:
+		return &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &f.topic, Partition: kafka.PartitionAny},
+			Key:            []byte(target),
+			Headers:        []kafka.Header{{Key: codec.HeaderFault, Value: []byte(fault)}},
+		}, nil
+
+	case FaultMissingField:
+		switch r.IntN(4) {
+		case 0:
+			order.TrackNumber = ""
+		case 1:
+			order.CustomerID = ""
+		case 2:
+			order.Delivery.Name = ""
+		default:
+			order.Payment.Transaction = ""
+		}
+
+	case FaultInvalidEnum:
+		if r.IntN(2) == 0 {
+			order.Locale = "xx"
+		} else {
+			order.Payment.Currency = "XXX"
+		}
+
+	case FaultOversizedString:
+		if r.IntN(2) == 0 {
+			order.Delivery.Address = strings.Repeat("x", oversizedLength)
+		} else {
+			order.Items[0].Name = strings.Repeat("x", oversizedLength)
+		}
+
+	case FaultDuplicateUID, FaultStaleVersion:
+		order.OrderUID = target
+		order.Payment.Transaction = target
+	}
+
+	message, err := f.producer.OrderMessage(order, f.topic)
+	if err != nil {
+		return nil, err
+	}
+
+	switch fault {
+	case FaultMalformedJSON:
+		data, err := json.Marshal(order)
+		if err != nil {
+			return nil, err
+		}
+		message.Value = data[:len(data)/2]
+		setHeader(message, codec.HeaderContentType, codec.ContentTypeJSON)
+
+	case FaultStaleVersion:
+		message.Timestamp = time.Now().Add(-staleAge)
+	}
+	setHeader(message, codec.HeaderFault, string(fault))
+
+	return message, nil
+}
+
+func setHeader(message *kafka.Message, key, value string) {
+	for i := range message.Headers {
+		if message.Headers[i].Key == key {
+			message.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+
+	message.Headers = append(message.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func hashUID(orderUID string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(orderUID))
+	return h.Sum64()
+}
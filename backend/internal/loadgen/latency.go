@@ -0,0 +1,69 @@
+package loadgen
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// Buckets grow by latencyBucketGrowth from latencyMin, so percentiles are
+	// reported within 2% however long the run is, in constant memory.
+	latencyMin          = 100 * time.Microsecond
+	latencyBucketGrowth = 1.02
+	latencyBuckets      = 700
+)
+
+type latencyHistogram struct {
+	mu      sync.Mutex
+	buckets [latencyBuckets]int64
+	count   int64
+	max     time.Duration
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buckets[latencyBucket(d)]++
+	h.count++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+func latencyBucket(d time.Duration) int {
+	if d <= latencyMin {
+		return 0
+	}
+
+	bucket := int(math.Ceil(math.Log(float64(d)/float64(latencyMin)) / math.Log(latencyBucketGrowth)))
+	return min(bucket, latencyBuckets-1)
+}
+
+func latencyBucketBound(bucket int) time.Duration {
+	return time.Duration(float64(latencyMin) * math.Pow(latencyBucketGrowth, float64(bucket)))
+}
+
+// percentile returns the upper bound of the bucket holding the p-th
+// percentile, capped by the largest latency recorded. The last bucket also
+// holds every longer latency, so it reports the largest one.
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(p / 100 * float64(h.count)))
+	var seen int64
+	for bucket, n := range h.buckets {
+		seen += n
+		if seen >= rank && bucket < latencyBuckets-1 {
+			return min(latencyBucketBound(bucket), h.max)
+		}
+	}
+
+	return h.max
+}
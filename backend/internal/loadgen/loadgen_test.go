@@ -0,0 +1,352 @@
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitTestLogger()
+	m.Run()
+}
+
+func withoutTimestamps(order models.OrderRequest) models.OrderRequest {
+	order.DateCreated = time.Time{}
+	order.Payment.PaymentDt = 0
+	return order
+}
+
+func TestCreateGenerator(t *testing.T) {
+	tests := []struct {
+		name     string
+		itemsMin int
+		itemsMax int
+		wantErr  bool
+	}{
+		{name: "range", itemsMin: 1, itemsMax: 3},
+		{name: "fixed", itemsMin: 5, itemsMax: 5},
+		{name: "no items", itemsMin: 0, itemsMax: 3, wantErr: true},
+		{name: "inverted", itemsMin: 4, itemsMax: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator, err := CreateGenerator(1, "", tt.itemsMin, tt.itemsMax)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			for range 50 {
+				items := len(generator.Next().Items)
+				assert.GreaterOrEqual(t, items, tt.itemsMin)
+				assert.LessOrEqual(t, items, tt.itemsMax)
+			}
+		})
+	}
+}
+
+func TestGenerator_Deterministic(t *testing.T) {
+	first, err := CreateGenerator(42, "", 1, 3)
+	require.NoError(t, err)
+	second, err := CreateGenerator(42, "", 1, 3)
+	require.NoError(t, err)
+	other, err := CreateGenerator(43, "run-", 1, 3)
+	require.NoError(t, err)
+
+	for i := range 10 {
+		a, b := first.Next(), second.Next()
+		assert.Equal(t, withoutTimestamps(a), withoutTimestamps(b))
+		assert.Equal(t, fmt.Sprintf("%s%d", DefaultUIDPrefix(42), i), a.OrderUID)
+	}
+
+	order := other.Next()
+	assert.Equal(t, "run-0", order.OrderUID)
+	assert.NotEqual(t, withoutTimestamps(first.Next()).Items, withoutTimestamps(order).Items)
+}
+
+func TestGenerator_OrdersAreValidAndConsistent(t *testing.T) {
+	generator, err := CreateGenerator(7, "", 1, 4)
+	require.NoError(t, err)
+
+	for range 100 {
+		order := generator.Next()
+		require.NoError(t, validate.ValidateOrderRequest(&order))
+
+		warnings, err := validate.DefaultRuleSet().Check(&order)
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	assert.Equal(t, time.Duration(0), h.percentile(50))
+
+	for i := 1; i <= 100; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	assert.InEpsilon(t, float64(50*time.Millisecond), float64(h.percentile(50)), 0.02)
+	assert.InEpsilon(t, float64(99*time.Millisecond), float64(h.percentile(99)), 0.02)
+	assert.Equal(t, 100*time.Millisecond, h.percentile(100))
+
+	h.record(time.Hour)
+	assert.Equal(t, time.Hour, h.percentile(100))
+}
+
+func TestRun_Count(t *testing.T) {
+	generator, err := CreateGenerator(1, "count-", 1, 1)
+	require.NoError(t, err)
+
+	var (
+		mu   sync.Mutex
+		uids []string
+	)
+	summary := Run(context.Background(), Options{Count: 25, Concurrency: 4}, generator,
+		func(_ context.Context, order models.OrderRequest) error {
+			mu.Lock()
+			uids = append(uids, order.OrderUID)
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+
+	assert.Equal(t, int64(25), summary.Sent)
+	assert.Equal(t, int64(25), summary.Delivered)
+	assert.Zero(t, summary.Failed)
+	assert.Len(t, uids, 25)
+	assert.GreaterOrEqual(t, summary.LatencyP50, time.Millisecond)
+	assert.LessOrEqual(t, summary.LatencyP50, summary.LatencyMax)
+	assert.Positive(t, summary.Throughput)
+}
+
+func TestRun_RateAndBurst(t *testing.T) {
+	generator, err := CreateGenerator(1, "", 1, 1)
+	require.NoError(t, err)
+
+	var (
+		mu       sync.Mutex
+		releases []time.Time
+	)
+	start := time.Now()
+	summary := Run(context.Background(), Options{Rate: 100, Burst: 5, Count: 20, Concurrency: 10}, generator,
+		func(context.Context, models.OrderRequest) error {
+			mu.Lock()
+			releases = append(releases, time.Now())
+			mu.Unlock()
+			return nil
+		})
+
+	assert.Equal(t, int64(20), summary.Delivered)
+	// Four bursts of five, 50ms apart: the last one is released after 150ms.
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	assert.Less(t, releases[4].Sub(releases[0]), 25*time.Millisecond)
+}
+
+func TestRun_FailuresAndCancel(t *testing.T) {
+	generator, err := CreateGenerator(1, "", 1, 1)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int64
+	summary := Run(ctx, Options{Rate: 1000, Concurrency: 2}, generator,
+		func(produceCtx context.Context, _ models.OrderRequest) error {
+			call := calls.Add(1)
+			if call == 10 {
+				cancel()
+			}
+			if produceCtx.Err() != nil {
+				return produceCtx.Err()
+			}
+			if call%2 == 0 {
+				return errors.New("broker unavailable")
+			}
+			return nil
+		})
+
+	assert.Equal(t, summary.Sent, summary.Delivered+summary.Failed)
+	assert.GreaterOrEqual(t, summary.Sent, int64(10))
+	assert.Positive(t, summary.Failed)
+	assert.Positive(t, summary.Delivered)
+}
+
+type fakeMessageProducer struct {
+	mu       sync.Mutex
+	messages []*kafka.Message
+	err      error
+}
+
+func (p *fakeMessageProducer) OrderMessage(order models.OrderRequest, topic string) (*kafka.Message, error) {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(order.OrderUID),
+		Value:          data,
+		Headers:        []kafka.Header{{Key: codec.HeaderContentType, Value: []byte(codec.ContentTypeProtobuf)}},
+	}, nil
+}
+
+func (p *fakeMessageProducer) ProduceMessage(_ context.Context, message *kafka.Message) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, message)
+
+	return nil
+}
+
+func header(message *kafka.Message, key string) string {
+	for _, h := range message.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestParseFaults(t *testing.T) {
+	faults, err := ParseFaults(nil)
+	require.NoError(t, err)
+	assert.Equal(t, AllFaults, faults)
+
+	faults, err = ParseFaults([]string{"tombstone", " stale-version"})
+	require.NoError(t, err)
+	assert.Equal(t, []Fault{FaultTombstone, FaultStaleVersion}, faults)
+
+	_, err = ParseFaults([]string{"flaky-network"})
+	assert.Error(t, err)
+}
+
+func TestCreateFaultInjector_InvalidRate(t *testing.T) {
+	_, err := CreateFaultInjector(&fakeMessageProducer{}, "orders", 1.5, nil, 1)
+	assert.Error(t, err)
+}
+
+func TestFaultInjector_Produce(t *testing.T) {
+	generator, err := CreateGenerator(3, "", 1, 2)
+	require.NoError(t, err)
+
+	producer := &fakeMessageProducer{}
+	injector, err := CreateFaultInjector(producer, "orders", 0.5, nil, 3)
+	require.NoError(t, err)
+
+	for range 400 {
+		require.NoError(t, injector.Produce(context.Background(), generator.Next()))
+	}
+
+	summary := injector.Summary()
+	for _, fault := range AllFaults {
+		assert.Positive(t, summary.Injected[fault], "fault %s", fault)
+	}
+
+	expected := make(map[string]int64)
+	versions := make(map[string]bool)
+	for _, message := range producer.messages {
+		fault := Fault(header(message, codec.HeaderFault))
+		expected[fault.expectedResult()]++
+
+		key := string(message.Key)
+		switch fault {
+		case FaultNone:
+			assert.Equal(t, codec.ContentTypeProtobuf, header(message, codec.HeaderContentType))
+			versions[key] = true
+
+		case FaultMalformedJSON:
+			assert.Equal(t, codec.ContentTypeJSON, header(message, codec.HeaderContentType))
+			assert.False(t, json.Valid(message.Value))
+
+		case FaultMissingField, FaultInvalidEnum, FaultOversizedString:
+			var order models.OrderRequest
+			require.NoError(t, json.Unmarshal(message.Value, &order))
+			assert.ErrorIs(t, validate.ValidateOrderRequest(&order), errs.ErrValidation, "fault %s", fault)
+
+		case FaultDuplicateUID:
+			assert.True(t, versions[key], "duplicate of an order that was not delivered")
+
+		case FaultStaleVersion:
+			assert.True(t, versions[key], "stale version of an order that was not delivered")
+			assert.Empty(t, header(message, codec.HeaderOrderVersion))
+			assert.GreaterOrEqual(t, time.Since(message.Timestamp), staleAge)
+
+		case FaultTombstone:
+			assert.True(t, versions[key], "tombstone for an order that was not delivered")
+			assert.Nil(t, message.Value)
+			delete(versions, key)
+		}
+	}
+	assert.Equal(t, expected, summary.Expected)
+}
+
+func TestFaultInjector_Deterministic(t *testing.T) {
+	faultsOf := func() []string {
+		generator, err := CreateGenerator(9, "", 1, 1)
+		require.NoError(t, err)
+
+		producer := &fakeMessageProducer{}
+		injector, err := CreateFaultInjector(producer, "orders", 0.3,
+			[]Fault{FaultMalformedJSON, FaultInvalidEnum}, 9)
+		require.NoError(t, err)
+
+		for range 50 {
+			require.NoError(t, injector.Produce(context.Background(), generator.Next()))
+		}
+
+		faults := make([]string, len(producer.messages))
+		for i, message := range producer.messages {
+			faults[i] = header(message, codec.HeaderFault)
+		}
+		return faults
+	}
+
+	first := faultsOf()
+	assert.Equal(t, first, faultsOf())
+	assert.Contains(t, first, string(FaultMalformedJSON))
+	assert.Contains(t, first, "")
+}
+
+func TestFaultInjector_FailedDeliveryKeepsTarget(t *testing.T) {
+	generator, err := CreateGenerator(5, "", 1, 1)
+	require.NoError(t, err)
+
+	producer := &fakeMessageProducer{}
+	injector, err := CreateFaultInjector(producer, "orders", 1, []Fault{FaultDuplicateUID}, 5)
+	require.NoError(t, err)
+
+	// Nothing was delivered yet, so the first order is sent unchanged.
+	first := generator.Next()
+	require.NoError(t, injector.Produce(context.Background(), first))
+	assert.Empty(t, header(producer.messages[0], codec.HeaderFault))
+
+	producer.err = errors.New("broker unavailable")
+	assert.Error(t, injector.Produce(context.Background(), generator.Next()))
+
+	producer.err = nil
+	require.NoError(t, injector.Produce(context.Background(), generator.Next()))
+	assert.Equal(t, first.OrderUID, string(producer.messages[1].Key))
+	assert.Equal(t, string(FaultDuplicateUID), header(producer.messages[1], codec.HeaderFault))
+	assert.Equal(t, map[Fault]int64{FaultDuplicateUID: 1}, injector.Summary().Injected)
+}
@@ -0,0 +1,179 @@
+package loadgen
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+)
+
+// Generator builds random but valid orders. Orders depend only on the seed
+// and their sequence number, apart from their timestamps, so a run can be
+// repeated with the same seed and UID prefix.
+type Generator struct {
+	rand     *rand.Rand
+	prefix   string
+	seq      int
+	itemsMin int
+	itemsMax int
+}
+
+// CreateGenerator returns a generator for orders with itemsMin to itemsMax
+// items. An empty prefix is derived from the seed, so runs with different
+// seeds do not overwrite each other's orders.
+func CreateGenerator(seed int64, prefix string, itemsMin, itemsMax int) (*Generator, error) {
+	if itemsMin < 1 || itemsMax < itemsMin {
+		return nil, fmt.Errorf("invalid items per order range %d-%d", itemsMin, itemsMax)
+	}
+	if prefix == "" {
+		prefix = DefaultUIDPrefix(seed)
+	}
+
+	return &Generator{
+		rand:     rand.New(rand.NewSource(seed)),
+		prefix:   prefix,
+		itemsMin: itemsMin,
+		itemsMax: itemsMax,
+	}, nil
+}
+
+func DefaultUIDPrefix(seed int64) string {
+	return fmt.Sprintf("load-%x-", uint64(seed))
+}
+
+func (g *Generator) Next() models.OrderRequest {
+	seq := g.seq
+	g.seq++
+
+	orderUID := fmt.Sprintf("%s%d", g.prefix, seq)
+	trackNumber := fmt.Sprintf("WBILMTESTTRACK%d", seq)
+	r := g.rand
+
+	items := make([]models.ItemRequest, g.itemsMin+r.Intn(g.itemsMax-g.itemsMin+1))
+	goodsTotal := 0
+	for i := range items {
+		items[i] = generateItem(trackNumber, i, r)
+		goodsTotal += items[i].TotalPrice
+	}
+
+	return models.OrderRequest{
+		OrderUID:          orderUID,
+		TrackNumber:       trackNumber,
+		Entry:             "WBIL",
+		Locale:            randomLocale(r),
+		InternalSignature: "",
+		CustomerID:        fmt.Sprintf("customer%d", r.Intn(1000)),
+		DeliveryService:   randomDeliveryService(r),
+		Shardkey:          fmt.Sprintf("%d", r.Intn(10)),
+		SmID:              r.Intn(100),
+		DateCreated:       time.Now(),
+		OofShard:          fmt.Sprintf("%d", r.Intn(2)),
+		Delivery:          generateDelivery(r),
+		Payment:           generatePayment(orderUID, goodsTotal, r),
+		Items:             items,
+	}
+}
+
+func generateDelivery(r *rand.Rand) models.DeliveryRequest {
+	firstNames := []string{"John", "Jane", "Alex", "Maria", "David", "Sarah", "Mike", "Anna"}
+	lastNames := []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis"}
+	cities := []string{"Moscow", "Saint Petersburg", "Novosibirsk", "Yekaterinburg", "Kazan"}
+	regions := []string{"Moscow Oblast", "Leningrad Oblast", "Sverdlovsk Oblast", "Republic of Tatarstan"}
+
+	return models.DeliveryRequest{
+		Name:    fmt.Sprintf("%s %s", firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))]),
+		Phone:   fmt.Sprintf("+7%d", 9000000000+r.Int63n(100000000)),
+		Zip:     fmt.Sprintf("%d", 100000+r.Intn(900000)),
+		City:    cities[r.Intn(len(cities))],
+		Address: fmt.Sprintf("%s st., %d", randomStreet(r), r.Intn(100)+1),
+		Region:  regions[r.Intn(len(regions))],
+		Email:   fmt.Sprintf("test%d@example.com", r.Intn(1000)),
+	}
+}
+
+func generatePayment(orderUID string, goodsTotal int, r *rand.Rand) models.PaymentRequest {
+	deliveryCost := r.Intn(500) + 100
+
+	return models.PaymentRequest{
+		Transaction:  orderUID,
+		RequestID:    "",
+		Currency:     randomCurrency(r),
+		Provider:     "wbpay",
+		Amount:       goodsTotal + deliveryCost,
+		PaymentDt:    int(time.Now().Unix()),
+		Bank:         randomBank(r),
+		DeliveryCost: deliveryCost,
+		GoodsTotal:   goodsTotal,
+		CustomFee:    0,
+	}
+}
+
+func generateItem(trackNumber string, index int, r *rand.Rand) models.ItemRequest {
+	products := []struct {
+		name  string
+		brand string
+		price int
+	}{
+		{"Smartphone", "Samsung", 25000},
+		{"Laptop", "Apple", 150000},
+		{"Headphones", "Sony", 5000},
+		{"Watch", "Casio", 3000},
+		{"Camera", "Canon", 45000},
+		{"Tablet", "Huawei", 20000},
+		{"Speaker", "JBL", 7000},
+	}
+
+	product := products[r.Intn(len(products))]
+	sale := r.Intn(30)
+
+	return models.ItemRequest{
+		ChrtID:      r.Intn(10000000),
+		TrackNumber: trackNumber,
+		Price:       product.price,
+		Rid:         fmt.Sprintf("ab4219087a764ae0btest%d", index),
+		Name:        product.name,
+		Sale:        sale,
+		Size:        fmt.Sprintf("%d", r.Intn(5)),
+		TotalPrice:  product.price - product.price*sale/100,
+		NmID:        r.Intn(1000000),
+		Brand:       product.brand,
+		Status:      202,
+	}
+}
+
+func randomLocale(r *rand.Rand) models.LocaleEnum {
+	locales := []models.LocaleEnum{
+		models.LocaleEN,
+		models.LocaleRU,
+		models.LocaleES,
+		models.LocaleDE,
+		models.LocaleFR,
+	}
+	return locales[r.Intn(len(locales))]
+}
+
+func randomCurrency(r *rand.Rand) models.CurrencyEnum {
+	currencies := []models.CurrencyEnum{
+		models.CurrencyUSD,
+		models.CurrencyEUR,
+		models.CurrencyRUB,
+		models.CurrencyGBP,
+	}
+	return currencies[r.Intn(len(currencies))]
+}
+
+func randomDeliveryService(r *rand.Rand) string {
+	services := []string{"meest", "russian-post", "dhl", "fedex", "ups", "cdek"}
+	return services[r.Intn(len(services))]
+}
+
+func randomBank(r *rand.Rand) string {
+	banks := []string{"sberbank", "alpha", "tinkoff", "vtb", "gazprombank", "raiffeisen"}
+	return banks[r.Intn(len(banks))]
+}
+
+func randomStreet(r *rand.Rand) string {
+	streets := []string{"Lenin", "Pushkin", "Gorky", "Peace", "Victory", "Central", "Green", "Sunset"}
+	return streets[r.Intn(len(streets))]
+}
@@ -0,0 +1,140 @@
+package loadgen
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"go.uber.org/zap"
+)
+
+// Options paces a run: every Burst/Rate seconds Burst orders are released at
+// once, with at most Concurrency of them awaiting delivery. A Rate of 0 does
+// not pace the run and a Count of 0 runs until the context is cancelled.
+type Options struct {
+	Rate        float64
+	Count       int
+	Burst       int
+	Concurrency int
+}
+
+// ProduceFunc publishes order and returns once its delivery is confirmed.
+type ProduceFunc func(ctx context.Context, order models.OrderRequest) error
+
+type Summary struct {
+	Sent       int64         `json:"sent"`
+	Delivered  int64         `json:"delivered"`
+	Failed     int64         `json:"failed"`
+	Elapsed    time.Duration `json:"elapsed"`
+	Throughput float64       `json:"throughput"`
+	LatencyP50 time.Duration `json:"latency_p50"`
+	LatencyP90 time.Duration `json:"latency_p90"`
+	LatencyP95 time.Duration `json:"latency_p95"`
+	LatencyP99 time.Duration `json:"latency_p99"`
+	LatencyMax time.Duration `json:"latency_max"`
+}
+
+func (s Summary) Fields() []zap.Field {
+	return []zap.Field{
+		zap.Int64("sent", s.Sent),
+		zap.Int64("delivered", s.Delivered),
+		zap.Int64("failed", s.Failed),
+		zap.Duration("elapsed", s.Elapsed),
+		zap.Float64("orders_per_second", s.Throughput),
+		zap.Duration("latency_p50", s.LatencyP50),
+		zap.Duration("latency_p90", s.LatencyP90),
+		zap.Duration("latency_p95", s.LatencyP95),
+		zap.Duration("latency_p99", s.LatencyP99),
+		zap.Duration("latency_max", s.LatencyMax),
+	}
+}
+
+// Run produces orders from generator until Count orders were sent or ctx is
+// cancelled, then waits for the orders still in flight. Latency is measured
+// from the call to produce until it returns.
+func Run(ctx context.Context, options Options, generator *Generator, produce ProduceFunc) Summary {
+	burst := max(options.Burst, 1)
+	inFlight := make(chan struct{}, max(options.Concurrency, 1))
+	produceCtx := context.WithoutCancel(ctx)
+
+	var tick <-chan time.Time
+	if options.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(burst) / options.Rate * float64(time.Second)))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var (
+		wg        sync.WaitGroup
+		latencies latencyHistogram
+		sent      int64
+		delivered atomic.Int64
+		failed    atomic.Int64
+	)
+	remaining := func() bool {
+		return options.Count <= 0 || sent < int64(options.Count)
+	}
+
+	start := time.Now()
+
+run:
+	for remaining() {
+		if tick != nil && sent > 0 {
+			select {
+			case <-ctx.Done():
+				break run
+			case <-tick:
+			}
+		}
+
+		for i := 0; i < burst && remaining(); i++ {
+			if ctx.Err() != nil {
+				break run
+			}
+			select {
+			case <-ctx.Done():
+				break run
+			case inFlight <- struct{}{}:
+			}
+
+			order := generator.Next()
+			sent++
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-inFlight }()
+
+				begin := time.Now()
+				if err := produce(produceCtx, order); err != nil {
+					failed.Add(1)
+					logger.Error("failed to produce order",
+						zap.String("order_uid", order.OrderUID),
+						zap.Error(err))
+					return
+				}
+				latencies.record(time.Since(begin))
+				delivered.Add(1)
+			}()
+		}
+	}
+
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	return Summary{
+		Sent:       sent,
+		Delivered:  delivered.Load(),
+		Failed:     failed.Load(),
+		Elapsed:    elapsed,
+		Throughput: float64(delivered.Load()) / elapsed.Seconds(),
+		LatencyP50: latencies.percentile(50),
+		LatencyP90: latencies.percentile(90),
+		LatencyP95: latencies.percentile(95),
+		LatencyP99: latencies.percentile(99),
+		LatencyMax: latencies.percentile(100),
+	}
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "wb_l0"
+
+var (
+	ConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "lag",
+		Help:      "Difference between the high watermark and the committed offset per partition.",
+	}, []string{"topic", "partition"})
+
+	ConsumerLastProcessedOffset = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "last_processed_offset",
+		Help:      "Offset of the last message processed per partition.",
+	}, []string{"topic", "partition"})
+
+	ConsumerLastBatchTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "last_batch_timestamp_seconds",
+		Help:      "Unix time of the last processed batch.",
+	})
+
+	ConsumerPaused = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "paused",
+		Help:      "Whether partition fetching is currently paused (1) or not (0).",
+	})
+
+	ConsumerMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "messages_total",
+		Help:      "Number of consumed messages by processing result.",
+	}, []string{"result"})
+
+	ConsumerItemChangesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "item_changes_total",
+		Help:      "Number of order items inserted, updated, left unchanged or deleted by snapshots.",
+	}, []string{"change"})
+
+	ConsumerBatchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "consumer",
+		Name:      "batch_duration_seconds",
+		Help:      "Time spent processing and committing a batch.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+const (
+	ResultProcessed = "processed"
+	ResultFailed    = "failed"
+	ResultStale     = "stale"
+)
+
+const (
+	ChangeInserted  = "inserted"
+	ChangeUpdated   = "updated"
+	ChangeUnchanged = "unchanged"
+	ChangeDeleted   = "deleted"
+)
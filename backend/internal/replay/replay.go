@@ -0,0 +1,320 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultCheckpointEvery  = 100
+	defaultProgressInterval = 5 * time.Second
+)
+
+// MessageProducer is the part of producer.Producer a replay publishes with.
+type MessageProducer interface {
+	OrderMessage(order models.OrderRequest, topic string) (*kafka.Message, error)
+	ProduceMessage(ctx context.Context, message *kafka.Message) error
+}
+
+// Checkpoint records how far a replay got: the first Replayed orders of its
+// source, described by Selection, were delivered, the last of them being
+// LastOrderUID.
+type Checkpoint struct {
+	ReplayID     string    `json:"replay_id"`
+	Selection    string    `json:"selection"`
+	Replayed     int       `json:"replayed"`
+	LastOrderUID string    `json:"last_order_uid,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// LoadCheckpoint reads the checkpoint at path. A missing file is an empty
+// checkpoint, so the replay starts from the beginning.
+func LoadCheckpoint(path string) (Checkpoint, error) {
+	var checkpoint Checkpoint
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return checkpoint, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return checkpoint, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+
+	return checkpoint, nil
+}
+
+// Save replaces the checkpoint at path through a rename, so an interrupted
+// write never leaves a truncated checkpoint behind.
+func (c Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// Options configure a replay. A Rate of 0 replays as fast as deliveries are
+// confirmed. Without a Checkpoint path the replay cannot be resumed; with
+// one, it is saved every CheckpointEvery orders and when the replay stops,
+// so a crash replays at most CheckpointEvery orders twice. The checkpoint is
+// removed once the replay completes. Selection describes the orders of the
+// source, so a checkpoint is never resumed against a different selection.
+type Options struct {
+	Topic            string
+	ReplayID         string
+	Selection        string
+	Rate             float64
+	Checkpoint       string
+	CheckpointEvery  int
+	ProgressInterval time.Duration
+}
+
+type Stats struct {
+	Skipped  int `json:"skipped"`
+	Replayed int `json:"replayed"`
+}
+
+type Replayer struct {
+	producer     MessageProducer
+	options      Options
+	checkpoint   Checkpoint
+	stats        Stats
+	started      time.Time
+	lastProgress time.Time
+}
+
+func CreateReplayer(producer MessageProducer, options Options) *Replayer {
+	if options.CheckpointEvery <= 0 {
+		options.CheckpointEvery = defaultCheckpointEvery
+	}
+	if options.ProgressInterval <= 0 {
+		options.ProgressInterval = defaultProgressInterval
+	}
+
+	return &Replayer{
+		producer: producer,
+		options:  options,
+	}
+}
+
+// Run publishes the orders of source to the topic, each with a
+// codec.HeaderReplay header, resuming after the orders recorded in the
+// checkpoint. A resumed replay keeps the ID it was started with, and refuses
+// a different ReplayID or Selection.
+func (r *Replayer) Run(ctx context.Context, source Source) (Stats, error) {
+	var err error
+	if r.options.Checkpoint != "" {
+		if r.checkpoint, err = LoadCheckpoint(r.options.Checkpoint); err != nil {
+			return r.stats, err
+		}
+	}
+	if err := r.checkResume(); err != nil {
+		return r.stats, err
+	}
+	if r.checkpoint.ReplayID == "" {
+		r.checkpoint.ReplayID = r.options.ReplayID
+	}
+	if r.checkpoint.ReplayID == "" {
+		r.checkpoint.ReplayID = time.Now().UTC().Format(time.RFC3339)
+	}
+	r.checkpoint.Selection = r.options.Selection
+	resumeAfter := r.checkpoint.Replayed
+
+	logger.Info("starting replay",
+		zap.String("replay_id", r.checkpoint.ReplayID),
+		zap.String("topic", r.options.Topic),
+		zap.Int("resume_after", resumeAfter))
+
+	r.started = time.Now()
+	r.lastProgress = r.started
+
+	var interval time.Duration
+	if r.options.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / r.options.Rate)
+	}
+	next := time.Now()
+
+	position := 0
+	err = source(ctx, func(order Order) error {
+		position++
+		if position <= resumeAfter {
+			r.stats.Skipped++
+			if position == resumeAfter && order.Request.OrderUID != r.checkpoint.LastOrderUID {
+				return fmt.Errorf("checkpoint does not match the source: order %d is %q, expected %q",
+					position, order.Request.OrderUID, r.checkpoint.LastOrderUID)
+			}
+			return nil
+		}
+
+		if interval > 0 {
+			if err := sleepUntil(ctx, next); err != nil {
+				return err
+			}
+			next = maxTime(next, time.Now()).Add(interval)
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := r.publish(ctx, order); err != nil {
+			return fmt.Errorf("failed to replay order %d (%s): %w", position, order.Request.OrderUID, err)
+		}
+
+		r.stats.Replayed++
+		r.checkpoint.Replayed = position
+		r.checkpoint.LastOrderUID = order.Request.OrderUID
+
+		if r.stats.Replayed%r.options.CheckpointEvery == 0 {
+			if err := r.saveCheckpoint(); err != nil {
+				return err
+			}
+		}
+		if time.Since(r.lastProgress) >= r.options.ProgressInterval {
+			r.progress("replay progress")
+		}
+
+		return nil
+	})
+	if err == nil && position < resumeAfter {
+		err = fmt.Errorf("checkpoint does not match the source: it has %d orders, %d were already replayed",
+			position, resumeAfter)
+	}
+
+	if err == nil {
+		err = r.removeCheckpoint()
+	} else if saveErr := r.saveCheckpoint(); saveErr != nil {
+		logger.Error("failed to save checkpoint", zap.Error(saveErr))
+	}
+	r.progress("replay finished")
+
+	return r.stats, err
+}
+
+// checkResume refuses to resume a checkpoint written for another replay.
+func (r *Replayer) checkResume() error {
+	if r.checkpoint.ReplayID == "" {
+		return nil
+	}
+
+	if r.options.ReplayID != "" && r.options.ReplayID != r.checkpoint.ReplayID {
+		return fmt.Errorf("checkpoint %s belongs to replay %q, not %q; remove it to start a new replay",
+			r.options.Checkpoint, r.checkpoint.ReplayID, r.options.ReplayID)
+	}
+	if r.options.Selection != r.checkpoint.Selection {
+		return fmt.Errorf("checkpoint %s was written for orders %q, not %q; remove it to start a new replay",
+			r.options.Checkpoint, r.checkpoint.Selection, r.options.Selection)
+	}
+
+	return nil
+}
+
+// publish sends order with the replay's ID and, when known, the version it
+// is stored at, which the consumer accepts again for a replay.
+func (r *Replayer) publish(ctx context.Context, order Order) error {
+	message, err := r.producer.OrderMessage(order.Request, r.options.Topic)
+	if err != nil {
+		return err
+	}
+	message.Headers = append(message.Headers, kafka.Header{
+		Key:   codec.HeaderReplay,
+		Value: []byte(r.checkpoint.ReplayID),
+	})
+	if order.Stored != nil {
+		message.Headers = append(message.Headers,
+			kafka.Header{
+				Key:   codec.HeaderOrderVersion,
+				Value: []byte(strconv.FormatInt(order.Stored.Version, 10)),
+			},
+			kafka.Header{
+				Key:   codec.HeaderOrderVersionSource,
+				Value: []byte(strconv.FormatInt(int64(order.Stored.Source), 10)),
+			})
+	}
+
+	return r.producer.ProduceMessage(ctx, message)
+}
+
+func (r *Replayer) saveCheckpoint() error {
+	if r.options.Checkpoint == "" {
+		return nil
+	}
+
+	r.checkpoint.UpdatedAt = time.Now().UTC()
+	return r.checkpoint.Save(r.options.Checkpoint)
+}
+
+func (r *Replayer) removeCheckpoint() error {
+	if r.options.Checkpoint == "" {
+		return nil
+	}
+
+	if err := os.Remove(r.options.Checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *Replayer) progress(message string) {
+	r.lastProgress = time.Now()
+	elapsed := r.lastProgress.Sub(r.started)
+
+	logger.Info(message,
+		zap.String("replay_id", r.checkpoint.ReplayID),
+		zap.Int("skipped", r.stats.Skipped),
+		zap.Int("replayed", r.stats.Replayed),
+		zap.String("last_order_uid", r.checkpoint.LastOrderUID),
+		zap.Duration("elapsed", elapsed),
+		zap.Float64("orders_per_second", float64(r.stats.Replayed)/elapsed.Seconds()))
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func sleepUntil(ctx context.Context, t time.Time) error {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,372 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/kafka/codec"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitTestLogger()
+	m.Run()
+}
+
+type fakeProducer struct {
+	messages []*kafka.Message
+	// failAt fails the delivery of the failAt-th message, counting from 1.
+	failAt int
+	calls  int
+}
+
+func (p *fakeProducer) OrderMessage(order models.OrderRequest, topic string) (*kafka.Message, error) {
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(order.OrderUID),
+		Headers:        []kafka.Header{{Key: codec.HeaderContentType, Value: []byte(codec.ContentTypeJSON)}},
+	}, nil
+}
+
+func (p *fakeProducer) ProduceMessage(_ context.Context, message *kafka.Message) error {
+	p.calls++
+	if p.calls == p.failAt {
+		return errors.New("broker unavailable")
+	}
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+func (p *fakeProducer) keys() []string {
+	keys := make([]string, len(p.messages))
+	for i, message := range p.messages {
+		keys[i] = string(message.Key)
+	}
+	return keys
+}
+
+func header(message *kafka.Message, key string) string {
+	for _, h := range message.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func sliceSource(uids ...string) Source {
+	return func(_ context.Context, fn func(Order) error) error {
+		for _, uid := range uids {
+			if err := fn(Order{Request: models.OrderRequest{OrderUID: uid}}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+type fakePager struct {
+	filter models.OrderFilter
+	orders []*models.Order
+	afters []*models.OrderPosition
+}
+
+func (p *fakePager) PageOrders(_ context.Context, filter models.OrderFilter, after *models.OrderPosition, size int) ([]*models.Order, error) {
+	p.filter = filter
+	p.afters = append(p.afters, after)
+
+	start := 0
+	if after != nil {
+		for start < len(p.orders) && p.orders[start].ID <= after.ID {
+			start++
+		}
+	}
+	end := min(start+size, len(p.orders))
+	return p.orders[start:end], nil
+}
+
+func TestPostgresSource(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	pager := &fakePager{orders: []*models.Order{
+		{
+			ID:          1,
+			OrderUID:    "order1",
+			TrackNumber: "TRACK1",
+			Locale:      models.LocaleEN,
+			DateCreated: created,
+			Delivery:    &models.Delivery{ID: 10, Name: "Test Testov", City: "Kiryat Mozkin"},
+			Payment:     &models.Payment{ID: 20, Transaction: "order1", Currency: models.CurrencyUSD, Amount: 1817},
+			Items:       []models.Item{{ID: 30, ChrtID: 9934930, Name: "Mascaras", TotalPrice: 317}},
+		},
+		{ID: 2, OrderUID: "order2", Version: 1704110400000, VersionSource: 2},
+	}}
+	filter := models.OrderFilter{OrderUIDs: []string{"order1", "order2"}}
+
+	var orders []Order
+	err := PostgresSource(pager, filter)(context.Background(), func(order Order) error {
+		orders = append(orders, order)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, filter, pager.filter)
+	require.Len(t, orders, 2)
+	assert.Equal(t, models.OrderRequest{
+		OrderUID:    "order1",
+		TrackNumber: "TRACK1",
+		Locale:      models.LocaleEN,
+		DateCreated: created,
+		Delivery:    models.DeliveryRequest{Name: "Test Testov", City: "Kiryat Mozkin"},
+		Payment:     models.PaymentRequest{Transaction: "order1", Currency: models.CurrencyUSD, Amount: 1817},
+		Items:       []models.ItemRequest{{ChrtID: 9934930, Name: "Mascaras", TotalPrice: 317}},
+	}, orders[0].Request)
+	assert.Equal(t, "order2", orders[1].Request.OrderUID)
+	assert.Empty(t, orders[1].Request.Items)
+	assert.Equal(t, &StoredVersion{Version: 1704110400000, Source: 2}, orders[1].Stored)
+	assert.Zero(t, orders[1].Request.Version)
+}
+
+func TestPostgresSource_Pages(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	pager := &fakePager{}
+	for i := range pageSize + 2 {
+		pager.orders = append(pager.orders, &models.Order{
+			ID:          int64(i + 1),
+			OrderUID:    fmt.Sprintf("order%d", i+1),
+			DateCreated: created,
+		})
+	}
+
+	tests := []struct {
+		name       string
+		limit      int
+		wantOrders int
+		wantPages  int
+	}{
+		{name: "all orders", wantOrders: pageSize + 2, wantPages: 2},
+		{name: "limit within the first page", limit: 3, wantOrders: 3, wantPages: 1},
+		{name: "limit at a page boundary", limit: pageSize, wantOrders: pageSize, wantPages: 1},
+		{name: "limit across pages", limit: pageSize + 1, wantOrders: pageSize + 1, wantPages: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pager.afters = nil
+
+			var uids []string
+			err := PostgresSource(pager, models.OrderFilter{Limit: tt.limit})(context.Background(), func(order Order) error {
+				uids = append(uids, order.Request.OrderUID)
+				return nil
+			})
+
+			require.NoError(t, err)
+			assert.Len(t, uids, tt.wantOrders)
+			assert.Equal(t, "order1", uids[0])
+			require.Len(t, pager.afters, tt.wantPages)
+			assert.Nil(t, pager.afters[0])
+			if tt.wantPages > 1 {
+				assert.Equal(t, &models.OrderPosition{DateCreated: created, ID: pageSize}, pager.afters[1])
+			}
+		})
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte(
+		`{"order_uid":"order1"}`+"\n"+
+			`{"order_uid":`+"\n"+
+			`{"order_uid":"order2"}`+"\n"), 0o644))
+
+	var uids []string
+	err := FileSource(path)(context.Background(), func(order Order) error {
+		assert.Nil(t, order.Stored)
+		uids = append(uids, order.Request.OrderUID)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"order1", "order2"}, uids)
+}
+
+func TestLoadCheckpoint_Missing(t *testing.T) {
+	checkpoint, err := LoadCheckpoint(filepath.Join(t.TempDir(), "missing.json"))
+
+	require.NoError(t, err)
+	assert.Equal(t, Checkpoint{}, checkpoint)
+}
+
+func TestReplayer_Run(t *testing.T) {
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+	producer := &fakeProducer{}
+	replayer := CreateReplayer(producer, Options{Topic: "orders-replay", ReplayID: "incident-42", Checkpoint: checkpointPath})
+
+	stats, err := replayer.Run(context.Background(), sliceSource("order1", "order2", "order3"))
+
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Replayed: 3}, stats)
+	assert.Equal(t, []string{"order1", "order2", "order3"}, producer.keys())
+	for _, message := range producer.messages {
+		assert.Equal(t, "orders-replay", *message.TopicPartition.Topic)
+		assert.Equal(t, "incident-42", header(message, codec.HeaderReplay))
+		assert.Equal(t, codec.ContentTypeJSON, header(message, codec.HeaderContentType))
+		assert.Empty(t, header(message, codec.HeaderOrderVersion))
+	}
+
+	assert.NoFileExists(t, checkpointPath)
+}
+
+func TestReplayer_StoredVersion(t *testing.T) {
+	producer := &fakeProducer{}
+	source := func(_ context.Context, fn func(Order) error) error {
+		return fn(Order{
+			Request: models.OrderRequest{OrderUID: "order1"},
+			Stored:  &StoredVersion{Version: 1704110400000, Source: 2},
+		})
+	}
+
+	_, err := CreateReplayer(producer, Options{Topic: "orders"}).Run(context.Background(), source)
+
+	require.NoError(t, err)
+	require.Len(t, producer.messages, 1)
+	assert.Equal(t, "1704110400000", header(producer.messages[0], codec.HeaderOrderVersion))
+	assert.Equal(t, "2", header(producer.messages[0], codec.HeaderOrderVersionSource))
+}
+
+func TestReplayer_Resume(t *testing.T) {
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+	source := sliceSource("order1", "order2", "order3", "order4")
+	options := Options{Topic: "orders", ReplayID: "first", Selection: "all=true", Checkpoint: checkpointPath}
+
+	failing := &fakeProducer{failAt: 3}
+	stats, err := CreateReplayer(failing, options).Run(context.Background(), source)
+	assert.Error(t, err)
+	assert.Equal(t, Stats{Replayed: 2}, stats)
+
+	checkpoint, err := LoadCheckpoint(checkpointPath)
+	require.NoError(t, err)
+	assert.Equal(t, "all=true", checkpoint.Selection)
+	assert.Equal(t, 2, checkpoint.Replayed)
+	assert.Equal(t, "order2", checkpoint.LastOrderUID)
+
+	// Without a ReplayID the resumed replay keeps the checkpoint's.
+	options.ReplayID = ""
+	producer := &fakeProducer{}
+	stats, err = CreateReplayer(producer, options).Run(context.Background(), source)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Skipped: 2, Replayed: 2}, stats)
+	assert.Equal(t, []string{"order3", "order4"}, producer.keys())
+	assert.Equal(t, "first", header(producer.messages[0], codec.HeaderReplay))
+	assert.NoFileExists(t, checkpointPath)
+
+	// With the checkpoint removed a rerun replays everything again.
+	producer = &fakeProducer{}
+	stats, err = CreateReplayer(producer, options).Run(context.Background(), source)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Replayed: 4}, stats)
+}
+
+func TestReplayer_ResumeConflict(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr string
+	}{
+		{
+			name:    "different replay id",
+			options: Options{ReplayID: "second", Selection: "all=true"},
+			wantErr: `belongs to replay "first", not "second"`,
+		},
+		{
+			name:    "different selection",
+			options: Options{ReplayID: "first", Selection: "uids=order1"},
+			wantErr: `was written for orders "all=true", not "uids=order1"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+			checkpoint := Checkpoint{ReplayID: "first", Selection: "all=true", Replayed: 1, LastOrderUID: "order1"}
+			require.NoError(t, checkpoint.Save(checkpointPath))
+
+			producer := &fakeProducer{}
+			tt.options.Topic = "orders"
+			tt.options.Checkpoint = checkpointPath
+			_, err := CreateReplayer(producer, tt.options).Run(context.Background(), sliceSource("order1", "order2"))
+
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Empty(t, producer.messages)
+			assert.FileExists(t, checkpointPath)
+		})
+	}
+}
+
+func TestReplayer_CheckpointMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		source Source
+	}{
+		{name: "different order", source: sliceSource("order1", "other", "order3")},
+		{name: "shorter source", source: sliceSource("order1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+			require.NoError(t, Checkpoint{ReplayID: "r", Replayed: 2, LastOrderUID: "order2"}.Save(checkpointPath))
+
+			producer := &fakeProducer{}
+			_, err := CreateReplayer(producer, Options{Topic: "orders", Checkpoint: checkpointPath}).
+				Run(context.Background(), tt.source)
+
+			assert.ErrorContains(t, err, "checkpoint does not match the source")
+			assert.Empty(t, producer.messages)
+		})
+	}
+}
+
+func TestReplayer_Rate(t *testing.T) {
+	producer := &fakeProducer{}
+	start := time.Now()
+
+	stats, err := CreateReplayer(producer, Options{Topic: "orders", Rate: 50}).
+		Run(context.Background(), sliceSource("order1", "order2", "order3", "order4", "order5", "order6"))
+
+	require.NoError(t, err)
+	assert.Equal(t, 6, stats.Replayed)
+	// The first order goes out at once, the other five 20ms apart.
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestReplayer_Cancel(t *testing.T) {
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	source := func(ctx context.Context, fn func(Order) error) error {
+		if err := fn(Order{Request: models.OrderRequest{OrderUID: "order1"}}); err != nil {
+			return err
+		}
+		cancel()
+		return fn(Order{Request: models.OrderRequest{OrderUID: "order2"}})
+	}
+
+	stats, err := CreateReplayer(&fakeProducer{}, Options{Topic: "orders", Checkpoint: checkpointPath}).Run(ctx, source)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, Stats{Replayed: 1}, stats)
+
+	checkpoint, err := LoadCheckpoint(checkpointPath)
+	require.NoError(t, err)
+	assert.Equal(t, 1, checkpoint.Replayed)
+	assert.NotEmpty(t, checkpoint.ReplayID)
+}
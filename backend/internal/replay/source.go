@@ -0,0 +1,158 @@
+package replay
+
+import (
+	"context"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/importer"
+	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"go.uber.org/zap"
+)
+
+// Source passes the orders to replay to fn, always in the same order, so an
+// interrupted replay can resume by skipping the orders it already replayed.
+type Source func(ctx context.Context, fn func(Order) error) error
+
+// Order is an order to replay. Orders read from Postgres carry the version
+// they are stored at, so the consumer rewrites them at that version rather
+// than ranking the replay against it.
+type Order struct {
+	Request models.OrderRequest
+	// Stored is nil when the stored version is unknown, as for orders read
+	// from a file.
+	Stored *StoredVersion
+}
+
+// StoredVersion is the version of a stored order and its source, ranked like
+// the version and version_source columns.
+type StoredVersion struct {
+	Version int64
+	Source  int16
+}
+
+// pageSize is the number of stored orders a Postgres source reads at a time.
+const pageSize = 500
+
+// OrderPager is the part of repository.AppRepository a Postgres source reads
+// orders from.
+type OrderPager interface {
+	PageOrders(ctx context.Context, filter models.OrderFilter, after *models.OrderPosition, size int) ([]*models.Order, error)
+}
+
+// PostgresSource replays the stored orders matching filter, oldest first. The
+// orders are read a page at a time, so no transaction stays open while a
+// rate-limited replay publishes them.
+func PostgresSource(repo OrderPager, filter models.OrderFilter) Source {
+	return func(ctx context.Context, fn func(Order) error) error {
+		var after *models.OrderPosition
+		read := 0
+		for {
+			size := pageSize
+			if filter.Limit > 0 {
+				size = min(size, filter.Limit-read)
+			}
+			if size == 0 {
+				return nil
+			}
+
+			orders, err := repo.PageOrders(ctx, filter, after, size)
+			if err != nil {
+				return err
+			}
+			for _, order := range orders {
+				stored := &StoredVersion{Version: order.Version, Source: order.VersionSource}
+				if err := fn(Order{Request: orderRequest(order), Stored: stored}); err != nil {
+					return err
+				}
+			}
+			read += len(orders)
+
+			if len(orders) < size {
+				return nil
+			}
+			last := orders[len(orders)-1]
+			after = &models.OrderPosition{DateCreated: last.DateCreated, ID: last.ID}
+		}
+	}
+}
+
+// FileSource replays the orders of an NDJSON file, such as one captured from
+// a topic. Lines that are not orders are logged and skipped.
+func FileSource(path string) Source {
+	return func(ctx context.Context, fn func(Order) error) error {
+		return importer.Read(path, importer.FormatNDJSON, func(record importer.Record) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if record.Err != nil {
+				logger.Warn("skipping unreadable record",
+					zap.String("position", record.Position),
+					zap.Error(record.Err))
+				return nil
+			}
+			return fn(Order{Request: *record.Order})
+		})
+	}
+}
+
+func orderRequest(order *models.Order) models.OrderRequest {
+	request := models.OrderRequest{
+		OrderUID:          order.OrderUID,
+		TrackNumber:       order.TrackNumber,
+		Entry:             order.Entry,
+		Locale:            order.Locale,
+		InternalSignature: order.InternalSignature,
+		CustomerID:        order.CustomerID,
+		DeliveryService:   order.DeliveryService,
+		Shardkey:          order.Shardkey,
+		SmID:              order.SmID,
+		DateCreated:       order.DateCreated,
+		OofShard:          order.OofShard,
+		Items:             make([]models.ItemRequest, 0, len(order.Items)),
+	}
+
+	if d := order.Delivery; d != nil {
+		request.Delivery = models.DeliveryRequest{
+			Name:    d.Name,
+			Phone:   d.Phone,
+			Zip:     d.Zip,
+			City:    d.City,
+			Address: d.Address,
+			Region:  d.Region,
+			Email:   d.Email,
+		}
+	}
+
+	if p := order.Payment; p != nil {
+		request.Payment = models.PaymentRequest{
+			Transaction:  p.Transaction,
+			RequestID:    p.RequestID,
+			Currency:     p.Currency,
+			Provider:     p.Provider,
+			Amount:       p.Amount,
+			PaymentDt:    p.PaymentDt,
+			Bank:         p.Bank,
+			DeliveryCost: p.DeliveryCost,
+			GoodsTotal:   p.GoodsTotal,
+			CustomFee:    p.CustomFee,
+		}
+	}
+
+	for _, item := range order.Items {
+		request.Items = append(request.Items, models.ItemRequest{
+			ChrtID:      item.ChrtID,
+			TrackNumber: item.TrackNumber,
+			Price:       item.Price,
+			Rid:         item.Rid,
+			Name:        item.Name,
+			Sale:        item.Sale,
+			Size:        item.Size,
+			TotalPrice:  item.TotalPrice,
+			NmID:        item.NmID,
+			Brand:       item.Brand,
+			Status:      item.Status,
+		})
+	}
+
+	return request
+}
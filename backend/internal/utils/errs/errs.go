@@ -3,8 +3,13 @@ package errs
 import "errors"
 
 var (
-	ErrUnknownType    = errors.New("unknown event type")
-	ErrContextTimeout = errors.New("context timeout")
-	ErrValidation     = errors.New("validation error")
-	ErrNotFound       = errors.New("not found")
+	ErrUnknownType         = errors.New("unknown event type")
+	ErrContextTimeout      = errors.New("context timeout")
+	ErrValidation          = errors.New("validation error")
+	ErrNotFound            = errors.New("not found")
+	ErrStaleVersion        = errors.New("stale version")
+	ErrUnsupportedVersion  = errors.New("unsupported payload version")
+	ErrIncompatibleSchema  = errors.New("incompatible schema")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrUnsupportedScript   = errors.New("unsupported script")
 )
@@ -0,0 +1,111 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+// MaxMajorAmount bounds a single amount in major units. Larger values almost
+// always mean minor and major units were mixed up.
+const MaxMajorAmount = 1_000_000_000
+
+// exponents holds the number of minor units digits of each currency
+// (ISO 4217).
+var exponents = map[models.CurrencyEnum]int{
+	models.CurrencyUSD: 2,
+	models.CurrencyEUR: 2,
+	models.CurrencyRUB: 2,
+	models.CurrencyGBP: 2,
+	models.CurrencyJPY: 0,
+	models.CurrencyCNY: 2,
+	models.CurrencyCAD: 2,
+	models.CurrencyAUD: 2,
+	models.CurrencyCHF: 2,
+}
+
+// Money is an amount in the minor units of its currency, e.g. cents for USD
+// and yen for JPY.
+type Money struct {
+	Minor    int64
+	Currency models.CurrencyEnum
+}
+
+func Exponent(currency models.CurrencyEnum) (int, bool) {
+	exponent, ok := exponents[currency]
+	return exponent, ok
+}
+
+func New(minor int64, currency models.CurrencyEnum) (Money, error) {
+	if _, ok := exponents[currency]; !ok {
+		return Money{}, fmt.Errorf("%w: %q", errs.ErrUnsupportedCurrency, currency)
+	}
+
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// FromMajor rounds major to the nearest minor unit, halves away from zero.
+func FromMajor(major *big.Rat, currency models.CurrencyEnum) (Money, error) {
+	exponent, ok := exponents[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", errs.ErrUnsupportedCurrency, currency)
+	}
+
+	minor := new(big.Rat).Mul(major, scale(exponent))
+	rounded := roundHalfAwayFromZero(minor)
+	if !rounded.IsInt64() {
+		return Money{}, fmt.Errorf("amount %s %s overflows", major.FloatString(exponent), currency)
+	}
+
+	return Money{Minor: rounded.Int64(), Currency: currency}, nil
+}
+
+// MaxMinor is the largest amount in minor units accepted for currency.
+func MaxMinor(currency models.CurrencyEnum) (int64, bool) {
+	exponent, ok := exponents[currency]
+	if !ok {
+		return 0, false
+	}
+
+	return MaxMajorAmount * scale(exponent).Num().Int64(), true
+}
+
+func (m Money) Major() *big.Rat {
+	return new(big.Rat).Quo(new(big.Rat).SetInt64(m.Minor), scale(exponents[m.Currency]))
+}
+
+// String formats m with the currency's number of decimals, e.g. "18.17 USD"
+// or "1817 JPY".
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Major().FloatString(exponents[m.Currency]), m.Currency)
+}
+
+func scale(exponent int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil))
+}
+
+func roundHalfAwayFromZero(r *big.Rat) *big.Int {
+	num := new(big.Int).Abs(r.Num())
+	quotient, remainder := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if remainder.Mul(remainder, big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		quotient.Neg(quotient)
+	}
+
+	return quotient
+}
+
+// ParseCurrency accepts currency codes in any case.
+func ParseCurrency(code string) (models.CurrencyEnum, error) {
+	currency := models.CurrencyEnum(strings.ToUpper(strings.TrimSpace(code)))
+	if _, ok := exponents[currency]; !ok {
+		return "", fmt.Errorf("%w: %q", errs.ErrUnsupportedCurrency, code)
+	}
+
+	return currency, nil
+}
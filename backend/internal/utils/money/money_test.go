@@ -0,0 +1,161 @@
+package money
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+func TestMoney_String(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{Minor: 1817, Currency: models.CurrencyUSD}, "18.17 USD"},
+		{Money{Minor: 5, Currency: models.CurrencyEUR}, "0.05 EUR"},
+		{Money{Minor: -1250, Currency: models.CurrencyRUB}, "-12.50 RUB"},
+		{Money{Minor: 1817, Currency: models.CurrencyJPY}, "1817 JPY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.money.String())
+		})
+	}
+}
+
+func TestFromMajor(t *testing.T) {
+	tests := []struct {
+		name     string
+		major    string
+		currency models.CurrencyEnum
+		want     int64
+		wantErr  bool
+	}{
+		{name: "Exact", major: "18.17", currency: models.CurrencyUSD, want: 1817},
+		{name: "RoundsHalfUp", major: "0.125", currency: models.CurrencyUSD, want: 13},
+		{name: "RoundsDown", major: "0.124", currency: models.CurrencyUSD, want: 12},
+		{name: "RoundsHalfAwayFromZero", major: "-0.125", currency: models.CurrencyUSD, want: -13},
+		{name: "NoMinorUnits", major: "99.5", currency: models.CurrencyJPY, want: 100},
+		{name: "UnknownCurrency", major: "1", currency: "XYZ", wantErr: true},
+		{name: "Overflow", major: "1e30", currency: models.CurrencyUSD, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			major, ok := new(big.Rat).SetString(tt.major)
+			assert.True(t, ok)
+
+			got, err := FromMajor(major, tt.currency)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, Money{Minor: tt.want, Currency: tt.currency}, got)
+		})
+	}
+}
+
+func TestMaxMinor(t *testing.T) {
+	maxUSD, ok := MaxMinor(models.CurrencyUSD)
+	assert.True(t, ok)
+	assert.Equal(t, int64(MaxMajorAmount*100), maxUSD)
+
+	maxJPY, ok := MaxMinor(models.CurrencyJPY)
+	assert.True(t, ok)
+	assert.Equal(t, int64(MaxMajorAmount), maxJPY)
+
+	_, ok = MaxMinor("XYZ")
+	assert.False(t, ok)
+}
+
+func TestParseCurrency(t *testing.T) {
+	currency, err := ParseCurrency(" eur ")
+	assert.NoError(t, err)
+	assert.Equal(t, models.CurrencyEUR, currency)
+
+	_, err = ParseCurrency("BTC")
+	assert.True(t, errors.Is(err, errs.ErrUnsupportedCurrency))
+}
+
+func TestParseRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "YAML", data: "base: EUR\nrates:\n  USD: 1.08\n  JPY: 162.4\n"},
+		{name: "JSON", data: `{"base": "EUR", "rates": {"USD": "1.08"}}`},
+		{name: "UnknownBase", data: "base: XYZ\n", wantErr: true},
+		{name: "MissingBase", data: "rates:\n  USD: 1.08\n", wantErr: true},
+		{name: "UnknownCurrency", data: "base: EUR\nrates:\n  BTC: 0.00001\n", wantErr: true},
+		{name: "ZeroRate", data: "base: EUR\nrates:\n  USD: 0\n", wantErr: true},
+		{name: "InvalidRate", data: "base: EUR\nrates:\n  USD: abc\n", wantErr: true},
+		{name: "UnknownKey", data: "base: EUR\nsource: ecb\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates, err := ParseRates([]byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, models.CurrencyEUR, rates.Base())
+		})
+	}
+}
+
+func TestRates_Convert(t *testing.T) {
+	rates, err := ParseRates([]byte("base: EUR\nrates:\n  USD: 1.25\n  JPY: 160\n"))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		money   Money
+		to      models.CurrencyEnum
+		want    Money
+		wantErr bool
+	}{
+		{name: "BaseToQuoted", money: Money{1000, models.CurrencyEUR}, to: models.CurrencyUSD, want: Money{1250, models.CurrencyUSD}},
+		{name: "QuotedToBase", money: Money{1250, models.CurrencyUSD}, to: models.CurrencyEUR, want: Money{1000, models.CurrencyEUR}},
+		{name: "CrossRate", money: Money{1817, models.CurrencyUSD}, to: models.CurrencyJPY, want: Money{2326, models.CurrencyJPY}},
+		{name: "FromZeroExponent", money: Money{160, models.CurrencyJPY}, to: models.CurrencyEUR, want: Money{100, models.CurrencyEUR}},
+		{name: "SameCurrency", money: Money{42, models.CurrencyUSD}, to: models.CurrencyUSD, want: Money{42, models.CurrencyUSD}},
+		{name: "MissingRate", money: Money{100, models.CurrencyEUR}, to: models.CurrencyGBP, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rates.Convert(tt.money, tt.to)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, errs.ErrUnsupportedCurrency))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadRatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("base: USD\nrates:\n  EUR: 0.8\n"), 0o600))
+
+	rates, err := LoadRatesFile(path)
+	assert.NoError(t, err)
+	rate, err := rates.Rate(models.CurrencyEUR, models.CurrencyUSD)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.25", rate.FloatString(2))
+
+	_, err = LoadRatesFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package money
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"gopkg.in/yaml.v3"
+)
+
+// Rates converts between currencies through a base currency. A rate is the
+// price of one unit of the base currency in the quoted currency.
+type Rates struct {
+	base  models.CurrencyEnum
+	rates map[models.CurrencyEnum]*big.Rat
+}
+
+type ratesFile struct {
+	Base  models.CurrencyEnum            `yaml:"base"`
+	Rates map[models.CurrencyEnum]string `yaml:"rates"`
+}
+
+// ParseRates reads a YAML or JSON rate table:
+//
+//	base: EUR
+//	rates:
+//	  USD: 1.08
+//	  JPY: 162.4
+func ParseRates(data []byte) (*Rates, error) {
+	var file ratesFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if _, ok := exponents[file.Base]; !ok {
+		return nil, fmt.Errorf("%w: base %q", errs.ErrUnsupportedCurrency, file.Base)
+	}
+
+	rates := &Rates{
+		base:  file.Base,
+		rates: map[models.CurrencyEnum]*big.Rat{file.Base: big.NewRat(1, 1)},
+	}
+	for currency, value := range file.Rates {
+		if _, ok := exponents[currency]; !ok {
+			return nil, fmt.Errorf("%w: %q", errs.ErrUnsupportedCurrency, currency)
+		}
+
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for %s", value, currency)
+		}
+		rates.rates[currency] = rate
+	}
+
+	return rates, nil
+}
+
+func LoadRatesFile(path string) (*Rates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates: %w", err)
+	}
+
+	rates, err := ParseRates(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates %s: %w", path, err)
+	}
+
+	return rates, nil
+}
+
+func (r *Rates) Base() models.CurrencyEnum {
+	return r.base
+}
+
+// Rate returns how many units of to one unit of from buys.
+func (r *Rates) Rate(from, to models.CurrencyEnum) (*big.Rat, error) {
+	fromRate, ok := r.rates[from]
+	if !ok {
+		return nil, fmt.Errorf("%w: no rate for %s", errs.ErrUnsupportedCurrency, from)
+	}
+	toRate, ok := r.rates[to]
+	if !ok {
+		return nil, fmt.Errorf("%w: no rate for %s", errs.ErrUnsupportedCurrency, to)
+	}
+
+	return new(big.Rat).Quo(toRate, fromRate), nil
+}
+
+func (r *Rates) Convert(m Money, to models.CurrencyEnum) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+
+	rate, err := r.Rate(m.Currency, to)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return FromMajor(new(big.Rat).Mul(m.Major(), rate), to)
+}
@@ -0,0 +1,130 @@
+package normalize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	minPhoneDigits = 7
+	maxPhoneDigits = 15
+)
+
+// region describes how national numbers are written in the default country
+// of a locale. nationalLength is zero where national numbers vary in length.
+type region struct {
+	callingCode    string
+	trunkPrefix    string
+	nationalLength int
+}
+
+// regions maps order locales to the country used as the hint for phone
+// numbers written without an international prefix.
+var regions = map[models.LocaleEnum]region{
+	models.LocaleEN: {callingCode: "1", trunkPrefix: "1", nationalLength: 10},
+	models.LocaleRU: {callingCode: "7", trunkPrefix: "8", nationalLength: 10},
+	models.LocaleES: {callingCode: "34", nationalLength: 9},
+	models.LocaleFR: {callingCode: "33", trunkPrefix: "0", nationalLength: 9},
+	models.LocaleDE: {callingCode: "49", trunkPrefix: "0"},
+	models.LocaleIT: {callingCode: "39"},
+	models.LocaleZH: {callingCode: "86", trunkPrefix: "0"},
+	models.LocaleJA: {callingCode: "81", trunkPrefix: "0"},
+	models.LocaleKO: {callingCode: "82", trunkPrefix: "0"},
+	models.LocaleAR: {callingCode: "966", trunkPrefix: "0", nationalLength: 9},
+}
+
+// Delivery returns the canonical form of delivery: an E.164 phone, a trimmed
+// lower-case email and NFC names and addresses. The zip is kept as is.
+func Delivery(delivery models.DeliveryRequest, locale models.LocaleEnum) (models.DeliveryRequest, error) {
+	phone, err := Phone(delivery.Phone, locale)
+	if err != nil {
+		return models.DeliveryRequest{}, validate.ValidationErrors{{
+			Path:    "delivery.phone",
+			Code:    validate.CodePhone,
+			Message: fmt.Sprintf("delivery phone %s", err),
+		}}
+	}
+
+	return models.DeliveryRequest{
+		Name:    Text(delivery.Name),
+		Phone:   phone,
+		Zip:     delivery.Zip,
+		City:    Text(delivery.City),
+		Address: Text(delivery.Address),
+		Region:  Text(delivery.Region),
+		Email:   Email(delivery.Email),
+	}, nil
+}
+
+// Phone formats raw as E.164. Numbers without a "+" or "00" prefix are read
+// as national numbers of the locale's default country.
+func Phone(raw string, locale models.LocaleEnum) (string, error) {
+	number := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '-', '(', ')', '.', '/':
+			return -1
+		}
+		return r
+	}, raw)
+
+	var digits string
+	switch {
+	case strings.HasPrefix(number, "+"):
+		digits = number[1:]
+	case strings.HasPrefix(number, "00"):
+		digits = number[2:]
+	default:
+		hint, ok := regions[locale]
+		if !ok {
+			return "", fmt.Errorf("has no country code and locale %q gives no region hint", locale)
+		}
+		digits = hint.international(number)
+	}
+
+	if !isDigits(digits) {
+		return "", fmt.Errorf("contains invalid characters")
+	}
+	if len(digits) < minPhoneDigits || len(digits) > maxPhoneDigits || digits[0] == '0' {
+		return "", fmt.Errorf("is not a valid international number")
+	}
+
+	return "+" + digits, nil
+}
+
+func (r region) international(national string) string {
+	if r.trunkPrefix != "" && strings.HasPrefix(national, r.trunkPrefix) &&
+		(r.nationalLength == 0 || len(national) == len(r.trunkPrefix)+r.nationalLength) {
+		return r.callingCode + national[len(r.trunkPrefix):]
+	}
+
+	if r.nationalLength > 0 && len(national) == len(r.callingCode)+r.nationalLength &&
+		strings.HasPrefix(national, r.callingCode) {
+		return national
+	}
+
+	return r.callingCode + national
+}
+
+func Email(raw string) string {
+	return strings.ToLower(strings.TrimSpace(raw))
+}
+
+// Text trims raw and converts it to Unicode NFC, so composed and decomposed
+// spellings of the same name compare equal.
+func Text(raw string) string {
+	return norm.NFC.String(strings.TrimSpace(raw))
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return s != ""
+}
@@ -0,0 +1,93 @@
+package normalize
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
+)
+
+func TestPhone(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		locale  models.LocaleEnum
+		want    string
+		wantErr bool
+	}{
+		{name: "E164", raw: "+79991234567", locale: models.LocaleEN, want: "+79991234567"},
+		{name: "Formatted", raw: "+7 (999) 123-45-67", locale: models.LocaleRU, want: "+79991234567"},
+		{name: "InternationalPrefix", raw: "00 49 30 123456", locale: models.LocaleRU, want: "+4930123456"},
+		{name: "RussianTrunkPrefix", raw: "8 (999) 123-45-67", locale: models.LocaleRU, want: "+79991234567"},
+		{name: "RussianWithoutPlus", raw: "79991234567", locale: models.LocaleRU, want: "+79991234567"},
+		{name: "RussianNational", raw: "999 123 45 67", locale: models.LocaleRU, want: "+79991234567"},
+		{name: "NANPNational", raw: "(212) 555-0123", locale: models.LocaleEN, want: "+12125550123"},
+		{name: "NANPWithTrunk", raw: "1-212-555-0123", locale: models.LocaleEN, want: "+12125550123"},
+		{name: "GermanTrunkPrefix", raw: "030 123456", locale: models.LocaleDE, want: "+4930123456"},
+		{name: "ItalianKeepsLeadingZero", raw: "06 1234 5678", locale: models.LocaleIT, want: "+390612345678"},
+		{name: "FrenchTrunkPrefix", raw: "01 23 45 67 89", locale: models.LocaleFR, want: "+33123456789"},
+		{name: "UnknownLocale", raw: "9991234567", locale: "pt", wantErr: true},
+		{name: "KnownLocaleNotNeededWithPlus", raw: "+9720000000", locale: "pt", want: "+9720000000"},
+		{name: "TooShort", raw: "+12345", locale: models.LocaleEN, wantErr: true},
+		{name: "TooLong", raw: "+1234567890123456", locale: models.LocaleEN, wantErr: true},
+		{name: "ZeroCountryCode", raw: "+0123456789", locale: models.LocaleEN, wantErr: true},
+		{name: "Letters", raw: "+7999CALLME", locale: models.LocaleRU, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Phone(tt.raw, tt.locale)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEmail(t *testing.T) {
+	assert.Equal(t, "john.doe@example.com", Email("  John.Doe@Example.COM \n"))
+}
+
+func TestText(t *testing.T) {
+	decomposed := "Jose\u0301 Garci\u0301a"
+	assert.Equal(t, "Jos\u00e9 Garc\u00eda", Text(" "+decomposed+" "))
+	assert.Equal(t, Text("Jos\u00e9 Garc\u00eda"), Text(decomposed))
+}
+
+func TestDelivery(t *testing.T) {
+	raw := models.DeliveryRequest{
+		Name:    "Renée ",
+		Phone:   "8 (999) 123-45-67",
+		Zip:     "2639809",
+		City:    "Kiryat Mozkin",
+		Address: "Ploshad Mira 15",
+		Region:  "Kraiot",
+		Email:   " Test@Gmail.com",
+	}
+
+	delivery, err := Delivery(raw, models.LocaleRU)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeliveryRequest{
+		Name:    "Renée",
+		Phone:   "+79991234567",
+		Zip:     "2639809",
+		City:    "Kiryat Mozkin",
+		Address: "Ploshad Mira 15",
+		Region:  "Kraiot",
+		Email:   "test@gmail.com",
+	}, delivery)
+
+	raw.Phone = "12-34"
+	_, err = Delivery(raw, models.LocaleRU)
+	assert.True(t, errors.Is(err, errs.ErrValidation))
+	violations, ok := validate.AsValidationErrors(err)
+	assert.True(t, ok)
+	assert.Equal(t, "delivery.phone", violations[0].Path)
+	assert.Equal(t, validate.CodePhone, violations[0].Code)
+}
@@ -8,24 +8,40 @@ import (
 
 	"github.com/supchaser/wb_l0/internal/utils/errs"
 	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
 	"go.uber.org/zap"
 )
 
 var jsonMarshal = json.Marshal
 
 type BadResponse struct {
-	Status int    `json:"status"`
-	Text   string `json:"text"`
+	Status int                       `json:"status"`
+	Text   string                    `json:"text"`
+	Errors validate.ValidationErrors `json:"errors,omitempty"`
 }
 
 func DoBadResponseAndLog(w http.ResponseWriter, statusCode int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
-	response := BadResponse{
+	writeBadResponse(w, BadResponse{
 		Status: statusCode,
 		Text:   message,
-	}
+	})
+}
+
+// DoValidationErrorResponse writes a 400 response that lists every
+// violation collected in err, so clients can fix them in one round trip.
+func DoValidationErrorResponse(w http.ResponseWriter, err error, message string) {
+	violations, _ := validate.AsValidationErrors(err)
+
+	writeBadResponse(w, BadResponse{
+		Status: http.StatusBadRequest,
+		Text:   message,
+		Errors: violations,
+	})
+}
+
+func writeBadResponse(w http.ResponseWriter, response BadResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.Status)
 
 	jsonResponse, err := jsonMarshal(response)
 	if err != nil {
@@ -42,8 +58,9 @@ func DoBadResponseAndLog(w http.ResponseWriter, statusCode int, message string)
 	}
 
 	logger.Warn("Bad response",
-		zap.Int("status", statusCode),
-		zap.String("message", message),
+		zap.Int("status", response.Status),
+		zap.String("message", response.Text),
+		zap.Any("errors", response.Errors),
 	)
 }
 
@@ -75,7 +92,7 @@ func ResponseErrorAndLog(w http.ResponseWriter, err error, funcName string) {
 	case errors.Is(err, errs.ErrNotFound):
 		DoBadResponseAndLog(w, http.StatusNotFound, "order not found")
 	case errors.Is(err, errs.ErrValidation):
-		DoBadResponseAndLog(w, http.StatusBadRequest, "invalid request data")
+		DoValidationErrorResponse(w, err, "invalid request data")
 	default:
 		DoBadResponseAndLog(w, http.StatusInternalServerError, "internal server error")
 		logger.Error(funcName,
@@ -10,8 +10,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/supchaser/wb_l0/internal/app/models"
 	"github.com/supchaser/wb_l0/internal/utils/errs"
 	"github.com/supchaser/wb_l0/internal/utils/logger"
+	"github.com/supchaser/wb_l0/internal/utils/validate"
 )
 
 func TestMain(m *testing.M) {
@@ -248,3 +250,25 @@ func (m *mockResponseWriter) Write(data []byte) (int, error) {
 func (m *mockResponseWriter) WriteHeader(statusCode int) {
 	m.status = statusCode
 }
+
+func TestDoValidationErrorResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	err := validate.ValidatePayment(&models.PaymentRequest{Amount: -1})
+	DoValidationErrorResponse(w, fmt.Errorf("wrapped: %w", err), "invalid request data")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response BadResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "invalid request data", response.Text)
+
+	var paths []string
+	for _, fieldErr := range response.Errors {
+		paths = append(paths, fieldErr.Path)
+	}
+	assert.Equal(t, []string{
+		"payment.transaction", "payment.currency", "payment.provider",
+		"payment.amount", "payment.payment_dt", "payment.bank",
+	}, paths)
+}
@@ -0,0 +1,199 @@
+package validate
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed default_rules.yaml
+var defaultRulesFile []byte
+
+// FieldRule describes the constraints of a single field. Zero values mean
+// "no constraint". An override keeps the default of every attribute it does
+// not set, so an attribute is cleared by setting it to its zero value, or to
+// null for min, max and enum.
+type FieldRule struct {
+	Label     string            `yaml:"label,omitempty"`
+	Required  bool              `yaml:"required,omitempty"`
+	MaxLength int               `yaml:"max_length,omitempty"`
+	Pattern   string            `yaml:"pattern,omitempty"`
+	Enum      []string          `yaml:"enum,omitempty"`
+	Min       *int64            `yaml:"min,omitempty"`
+	Max       *int64            `yaml:"max,omitempty"`
+	MaxFuture time.Duration     `yaml:"max_future,omitempty"`
+	MinItems  int               `yaml:"min_items,omitempty"`
+	Messages  map[string]string `yaml:"messages,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+type Rules struct {
+	Fields      map[string]*FieldRule `yaml:"fields"`
+	Consistency map[string]Severity   `yaml:"consistency"`
+
+	schemaOnce sync.Once
+	schema     *jsonschema.Schema
+	schemaErr  error
+}
+
+var (
+	defaultRules = mustParseDefaultRules()
+	activeRules  atomic.Pointer[Rules]
+)
+
+func mustParseDefaultRules() *Rules {
+	rules, err := parseRules(defaultRulesFile, nil)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded validation rules: %v", err))
+	}
+
+	return rules
+}
+
+// DefaultRules returns the rules embedded in the binary.
+func DefaultRules() *Rules {
+	return defaultRules
+}
+
+// ParseRules reads a YAML or JSON rules document and merges it over the
+// embedded defaults.
+func ParseRules(data []byte) (*Rules, error) {
+	return parseRules(data, defaultRules)
+}
+
+func LoadRulesFile(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read validation rules: %w", err)
+	}
+
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse validation rules %s: %w", path, err)
+	}
+
+	return rules, nil
+}
+
+// UseRules replaces the rules used by the package level validators.
+func UseRules(rules *Rules) {
+	activeRules.Store(rules)
+}
+
+func currentRules() *Rules {
+	if rules := activeRules.Load(); rules != nil {
+		return rules
+	}
+
+	return defaultRules
+}
+
+func (r *Rules) field(key string) *FieldRule {
+	return r.Fields[key]
+}
+
+func parseRules(data []byte, base *Rules) (*Rules, error) {
+	var file Rules
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	// The attributes an override sets are only known from the document, so
+	// each override is decoded again over a copy of its default.
+	var overrides struct {
+		Fields map[string]yaml.Node `yaml:"fields"`
+	}
+	if base != nil {
+		if err := yaml.Unmarshal(data, &overrides); err != nil {
+			return nil, err
+		}
+	}
+
+	rules := &Rules{
+		Fields:      make(map[string]*FieldRule),
+		Consistency: make(map[string]Severity),
+	}
+	if base != nil {
+		for key, rule := range base.Fields {
+			rules.Fields[key] = rule
+		}
+		for code, severity := range base.Consistency {
+			rules.Consistency[code] = severity
+		}
+	}
+
+	for key, rule := range file.Fields {
+		if rule == nil {
+			rule = &FieldRule{}
+		}
+
+		if base != nil {
+			defaults, ok := base.Fields[key]
+			if !ok {
+				return nil, fmt.Errorf("unknown field %q", key)
+			}
+
+			rule = defaults.clone()
+			node := overrides.Fields[key]
+			if err := node.Decode(rule); err != nil {
+				return nil, fmt.Errorf("invalid rule for %s: %w", key, err)
+			}
+		}
+
+		rule.pattern = nil
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern for %s: %w", key, err)
+			}
+			rule.pattern = pattern
+		}
+
+		rules.Fields[key] = rule
+	}
+
+	for code, severity := range file.Consistency {
+		if !isKnownRule(code) {
+			return nil, fmt.Errorf("unknown consistency rule %q", code)
+		}
+		if !isValidSeverity(severity) {
+			return nil, fmt.Errorf("invalid severity %q for rule %s", severity, code)
+		}
+		rules.Consistency[code] = severity
+	}
+
+	return rules, nil
+}
+
+// clone copies f deeply enough for an override to be decoded over the copy
+// without changing f.
+func (f *FieldRule) clone() *FieldRule {
+	c := *f
+	c.Enum = slices.Clone(f.Enum)
+	c.Messages = maps.Clone(f.Messages)
+	if f.Min != nil {
+		c.Min = new(int64)
+		*c.Min = *f.Min
+	}
+	if f.Max != nil {
+		c.Max = new(int64)
+		*c.Max = *f.Max
+	}
+
+	return &c
+}
@@ -0,0 +1,75 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/supchaser/wb_l0/internal/utils/errs"
+)
+
+const (
+	CodeRequired    = "required"
+	CodeMaxLength   = "max_length"
+	CodePattern     = "pattern"
+	CodeEnum        = "enum"
+	CodePositive    = "positive"
+	CodeNonNegative = "non_negative"
+	CodeMin         = "min"
+	CodeMax         = "max"
+	CodeNotFuture   = "not_future"
+	CodeMinItems    = "min_items"
+	CodePhone       = "phone"
+	CodeCurrency    = "currency"
+	CodeDuplicate   = "duplicate"
+
+	CodeUnknownField   = "unknown_field"
+	CodeDuplicateField = "duplicate_field"
+	CodeTrailingData   = "trailing_data"
+)
+
+type FieldError struct {
+	Path    string `json:"path"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every violation found in a request. It unwraps
+// to errs.ErrValidation, so errors.Is keeps working for callers that only
+// care about the kind of failure.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, fieldErr := range v {
+		messages = append(messages, fieldErr.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", errs.ErrValidation, strings.Join(messages, "; "))
+}
+
+func (v ValidationErrors) Unwrap() error {
+	return errs.ErrValidation
+}
+
+func (v *ValidationErrors) add(path, code, format string, args ...any) {
+	*v = append(*v, FieldError{Path: path, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v ValidationErrors) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	return v
+}
+
+// AsValidationErrors extracts the collected violations from err, if any.
+func AsValidationErrors(err error) (ValidationErrors, bool) {
+	var v ValidationErrors
+	if errors.As(err, &v) {
+		return v, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,212 @@
+package validate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"github.com/supchaser/wb_l0/internal/utils/money"
+)
+
+type Severity string
+
+const (
+	SeverityReject Severity = "reject"
+	SeverityWarn   Severity = "warn"
+	SeverityOff    Severity = "off"
+)
+
+const (
+	RuleGoodsTotalMatchesItems      = "goods_total_matches_items"
+	RuleAmountMatchesComponents     = "amount_matches_components"
+	RuleItemTrackNumberMatchesOrder = "item_track_number_matches_order"
+)
+
+type Rule struct {
+	Code     string
+	Severity Severity
+	check    func(order *models.OrderRequest) []violation
+}
+
+type violation struct {
+	path    string
+	message string
+}
+
+// RuleSet holds cross-field invariants that cannot be checked on a single
+// field. Violations of reject rules fail validation, warn rules only report.
+type RuleSet struct {
+	rules []Rule
+}
+
+// DefaultRuleSet takes rule severities from the active rules file.
+func DefaultRuleSet() *RuleSet {
+	rs := &RuleSet{
+		rules: []Rule{
+			{Code: RuleGoodsTotalMatchesItems, Severity: SeverityWarn, check: checkGoodsTotal},
+			{Code: RuleAmountMatchesComponents, Severity: SeverityWarn, check: checkAmount},
+			{Code: RuleItemTrackNumberMatchesOrder, Severity: SeverityWarn, check: checkItemTrackNumbers},
+		},
+	}
+
+	consistency := currentRules().Consistency
+	for i := range rs.rules {
+		if severity, ok := consistency[rs.rules[i].Code]; ok {
+			rs.rules[i].Severity = severity
+		}
+	}
+
+	return rs
+}
+
+// CreateRuleSet returns the default rules with severities overridden by
+// rule code.
+func CreateRuleSet(overrides map[string]Severity) (*RuleSet, error) {
+	rs := DefaultRuleSet()
+
+	for code, severity := range overrides {
+		if !isValidSeverity(severity) {
+			return nil, fmt.Errorf("invalid severity %q for rule %s", severity, code)
+		}
+
+		found := false
+		for i := range rs.rules {
+			if rs.rules[i].Code == code {
+				rs.rules[i].Severity = severity
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown validation rule %q", code)
+		}
+	}
+
+	return rs, nil
+}
+
+// ParseSeverities parses overrides in the form "rule=severity,rule=severity".
+func ParseSeverities(value string) (map[string]Severity, error) {
+	overrides := make(map[string]Severity)
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		code, severity, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule override %q, expected rule=severity", part)
+		}
+		overrides[strings.TrimSpace(code)] = Severity(strings.TrimSpace(severity))
+	}
+
+	return overrides, nil
+}
+
+// Check runs every enabled rule against order. It returns the warnings to
+// record on the order and ValidationErrors if any reject rule fails.
+func (rs *RuleSet) Check(order *models.OrderRequest) ([]models.ValidationWarning, error) {
+	if rs == nil {
+		rs = DefaultRuleSet()
+	}
+
+	var (
+		warnings   []models.ValidationWarning
+		violations ValidationErrors
+	)
+
+	for _, rule := range rs.rules {
+		if rule.Severity == SeverityOff {
+			continue
+		}
+
+		for _, found := range rule.check(order) {
+			if rule.Severity == SeverityReject {
+				violations.add(found.path, rule.Code, "%s", found.message)
+				continue
+			}
+			warnings = append(warnings, models.ValidationWarning{Rule: rule.Code, Path: found.path, Message: found.message})
+		}
+	}
+
+	return warnings, violations.err()
+}
+
+func isKnownRule(code string) bool {
+	switch code {
+	case RuleGoodsTotalMatchesItems, RuleAmountMatchesComponents, RuleItemTrackNumberMatchesOrder:
+		return true
+	default:
+		return false
+	}
+}
+
+func isValidSeverity(severity Severity) bool {
+	switch severity {
+	case SeverityReject, SeverityWarn, SeverityOff:
+		return true
+	default:
+		return false
+	}
+}
+
+func checkGoodsTotal(order *models.OrderRequest) []violation {
+	var sum int64
+	for _, item := range order.Items {
+		sum += int64(item.TotalPrice)
+	}
+
+	if goodsTotal := int64(order.Payment.GoodsTotal); goodsTotal != sum {
+		return []violation{{
+			path: "payment.goods_total",
+			message: fmt.Sprintf("goods_total %s does not match sum of item total_price %s",
+				formatMinor(goodsTotal, order.Payment.Currency), formatMinor(sum, order.Payment.Currency)),
+		}}
+	}
+
+	return nil
+}
+
+// checkAmount compares amounts in minor units of the payment currency,
+// summed as int64 so that large components cannot overflow.
+func checkAmount(order *models.OrderRequest) []violation {
+	payment := order.Payment
+	amount := int64(payment.Amount)
+	expected := int64(payment.GoodsTotal) + int64(payment.DeliveryCost) + int64(payment.CustomFee)
+
+	if amount != expected {
+		return []violation{{
+			path: "payment.amount",
+			message: fmt.Sprintf("amount %s does not match goods_total + delivery_cost + custom_fee %s",
+				formatMinor(amount, payment.Currency), formatMinor(expected, payment.Currency)),
+		}}
+	}
+
+	return nil
+}
+
+// formatMinor formats minor units with the decimals of currency, or as is
+// when the currency is unknown.
+func formatMinor(minor int64, currency models.CurrencyEnum) string {
+	m, err := money.New(minor, currency)
+	if err != nil {
+		return strconv.FormatInt(minor, 10)
+	}
+	return m.String()
+}
+
+func checkItemTrackNumbers(order *models.OrderRequest) []violation {
+	var violations []violation
+	for i, item := range order.Items {
+		if item.TrackNumber != order.TrackNumber {
+			violations = append(violations, violation{
+				path:    fmt.Sprintf("items[%d].track_number", i),
+				message: fmt.Sprintf("items[%d].track_number %q does not match order track_number %q", i, item.TrackNumber, order.TrackNumber),
+			})
+		}
+	}
+
+	return violations
+}
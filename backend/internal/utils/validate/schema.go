@@ -0,0 +1,354 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"github.com/supchaser/wb_l0/internal/app/models"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	SchemaDraft   = "https://json-schema.org/draft/2020-12/schema"
+	OrderSchemaID = "https://wb-l0/schemas/order.json"
+
+	CodeType   = "type"
+	CodeFormat = "format"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	schemaPrinter = message.NewPrinter(language.English)
+)
+
+// OrderSchema describes models.OrderRequest under the active rules.
+func OrderSchema() map[string]any {
+	return currentRules().OrderSchema()
+}
+
+// ValidateOrderJSON checks a raw order document against the schema generated
+// from the active rules.
+func ValidateOrderJSON(data []byte) error {
+	return currentRules().ValidateOrderJSON(data)
+}
+
+// OrderSchema builds a draft 2020-12 schema for models.OrderRequest from the
+// field rules. Checks a schema cannot express, such as max_future and the
+// per-currency amount limits, are left to ValidateOrderRequest.
+func (r *Rules) OrderSchema() map[string]any {
+	schema := r.objectSchema(reflect.TypeOf(models.OrderRequest{}), "")
+	schema["$schema"] = SchemaDraft
+	schema["$id"] = OrderSchemaID
+	schema["title"] = "OrderRequest"
+
+	return schema
+}
+
+func (r *Rules) ValidateOrderJSON(data []byte) error {
+	schema, err := r.compiledSchema()
+	if err != nil {
+		return err
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse order json: %w", err)
+	}
+
+	err = schema.Validate(doc)
+	if err == nil {
+		return nil
+	}
+
+	schemaErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return fmt.Errorf("failed to validate order json: %w", err)
+	}
+
+	var v ValidationErrors
+	r.collectSchemaErrors(&v, schemaErr)
+	return firstPerPath(v).err()
+}
+
+func (r *Rules) compiledSchema() (*jsonschema.Schema, error) {
+	r.schemaOnce.Do(func() {
+		r.schema, r.schemaErr = compileSchema(r.OrderSchema())
+	})
+
+	return r.schema, r.schemaErr
+}
+
+func compileSchema(schema map[string]any) (*jsonschema.Schema, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode order schema: %w", err)
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode order schema: %w", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat()
+	if err := compiler.AddResource(OrderSchemaID, doc); err != nil {
+		return nil, fmt.Errorf("failed to add order schema: %w", err)
+	}
+
+	compiled, err := compiler.Compile(OrderSchemaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile order schema: %w", err)
+	}
+
+	return compiled, nil
+}
+
+func (r *Rules) objectSchema(t reflect.Type, prefix string) map[string]any {
+	properties := make(map[string]any, t.NumField())
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		if name == "" {
+			continue
+		}
+
+		property, isRequired := r.propertySchema(t.Field(i).Type, prefix+name)
+		properties[name] = property
+		if isRequired {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// propertySchema reports whether the property has to be present: a missing
+// property decodes to its zero value, which the rules may reject.
+func (r *Rules) propertySchema(t reflect.Type, key string) (map[string]any, bool) {
+	rule := r.field(key)
+	if rule == nil {
+		rule = &FieldRule{}
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, rule.Required
+	case t.Kind() == reflect.Struct:
+		schema := r.objectSchema(t, key+".")
+		_, hasRequired := schema["required"]
+		return schema, hasRequired
+	case t.Kind() == reflect.Slice:
+		items, _ := r.propertySchema(t.Elem(), key+"[]")
+		schema := map[string]any{"type": "array", "items": items}
+		if rule.MinItems > 0 {
+			schema["minItems"] = rule.MinItems
+		}
+		return schema, rule.MinItems > 0
+	case t.Kind() == reflect.String:
+		return stringSchema(rule), rule.Required
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		schema := map[string]any{"type": "integer"}
+		if rule.Min != nil {
+			schema["minimum"] = *rule.Min
+		}
+		if rule.Max != nil {
+			schema["maximum"] = *rule.Max
+		}
+		return schema, (rule.Min != nil && *rule.Min > 0) || (rule.Max != nil && *rule.Max < 0)
+	}
+
+	return map[string]any{}, false
+}
+
+// stringSchema mirrors checkString: an empty string is only checked by
+// minLength, so it is reported as required rather than as a pattern or enum
+// mismatch.
+func stringSchema(rule *FieldRule) map[string]any {
+	schema := map[string]any{"type": "string"}
+	if rule.Required {
+		schema["minLength"] = 1
+	}
+	if rule.MaxLength > 0 {
+		schema["maxLength"] = rule.MaxLength
+	}
+	if rule.Pattern != "" {
+		schema["pattern"] = "^$|(?:" + rule.Pattern + ")"
+	}
+	if len(rule.Enum) > 0 {
+		schema["enum"] = append(append([]string(nil), rule.Enum...), "")
+	}
+
+	return schema
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" || !field.IsExported() {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
+
+func (r *Rules) collectSchemaErrors(v *ValidationErrors, schemaErr *jsonschema.ValidationError) {
+	if len(schemaErr.Causes) > 0 {
+		for _, cause := range schemaErr.Causes {
+			r.collectSchemaErrors(v, cause)
+		}
+		return
+	}
+
+	path := schemaPath(schemaErr.InstanceLocation)
+	if required, ok := schemaErr.ErrorKind.(*kind.Required); ok {
+		for _, missing := range required.Missing {
+			missingPath := joinPath(path, missing)
+			v.add(missingPath, r.missingCode(missingPath), "%s is required", missingPath)
+		}
+		return
+	}
+
+	label := path
+	if label == "" {
+		label = "order"
+	}
+	v.add(path, schemaCode(schemaErr.ErrorKind), "%s: %s", label, schemaErr.ErrorKind.LocalizedString(schemaPrinter))
+}
+
+// missingCode reports an absent property the way ValidateOrderRequest reports
+// the zero value it decodes to.
+func (r *Rules) missingCode(path string) string {
+	rule := r.field(indexRegex.ReplaceAllString(path, "[]"))
+	switch {
+	case rule == nil:
+		return CodeRequired
+	case rule.MinItems > 0:
+		return CodeMinItems
+	case rule.Min != nil && *rule.Min > 0:
+		return minCode(*rule.Min)
+	}
+
+	return CodeRequired
+}
+
+// firstPerPath keeps one violation per field, like checkString which stops
+// at the first failed check.
+func firstPerPath(v ValidationErrors) ValidationErrors {
+	result := make(ValidationErrors, 0, len(v))
+	seen := make(map[string]int, len(v))
+	for _, fieldErr := range v {
+		i, ok := seen[fieldErr.Path]
+		if !ok {
+			seen[fieldErr.Path] = len(result)
+			result = append(result, fieldErr)
+			continue
+		}
+		if codeRank(fieldErr.Code) < codeRank(result[i].Code) {
+			result[i] = fieldErr
+		}
+	}
+
+	return result
+}
+
+func codeRank(code string) int {
+	switch code {
+	case CodeType:
+		return 0
+	case CodeRequired:
+		return 1
+	case CodeMaxLength:
+		return 2
+	case CodePattern:
+		return 3
+	case CodeEnum:
+		return 4
+	}
+
+	return 5
+}
+
+func schemaCode(errorKind jsonschema.ErrorKind) string {
+	switch k := errorKind.(type) {
+	case *kind.MinLength:
+		return CodeRequired
+	case *kind.MaxLength:
+		return CodeMaxLength
+	case *kind.Pattern:
+		return CodePattern
+	case *kind.Enum:
+		return CodeEnum
+	case *kind.Minimum:
+		if !k.Want.IsInt() || !k.Want.Num().IsInt64() {
+			return CodeMin
+		}
+		return minCode(k.Want.Num().Int64())
+	case *kind.Maximum:
+		return CodeMax
+	case *kind.MinItems:
+		return CodeMinItems
+	case *kind.Type:
+		return CodeType
+	case *kind.Format:
+		return CodeFormat
+	}
+
+	return strings.Join(errorKind.KeywordPath(), ".")
+}
+
+// schemaPath turns a JSON pointer location into the dotted paths used by
+// ValidationErrors, e.g. items[2].price.
+func schemaPath(location []string) string {
+	var sb strings.Builder
+	for _, token := range location {
+		if isIndex(token) {
+			fmt.Fprintf(&sb, "[%s]", token)
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(token)
+	}
+
+	return sb.String()
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+
+	return path + "." + field
+}
+
+func isIndex(token string) bool {
+	if token == "" {
+		return false
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -3,13 +3,17 @@ package validate
 import (
 	"fmt"
 	"regexp"
+	"slices"
+	"strings"
 	"time"
 	"unicode/utf8"
 
 	"github.com/supchaser/wb_l0/internal/app/models"
-	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"github.com/supchaser/wb_l0/internal/utils/money"
 )
 
+// Default maximum lengths. default_rules.yaml sets the same max_length
+// values, which a rules file may override.
 const (
 	MaxOrderUIDLength       = 50
 	MaxTrackNumberLength    = 50
@@ -37,364 +41,364 @@ const (
 	MaxItemBrandLength      = 100
 )
 
-var (
-	orderUIDRegex     = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
-	trackNumberRegex  = regexp.MustCompile(`^[A-Z0-9]+$`)
-	entryRegex        = regexp.MustCompile(`^[A-Z]+$`)
-	shardkeyRegex     = regexp.MustCompile(`^[0-9]+$`)
-	oofShardRegex     = regexp.MustCompile(`^[0-9]+$`)
-	phoneRegex        = regexp.MustCompile(`^\+?[0-9\s\-\(\)]+$`)
-	zipRegex          = regexp.MustCompile(`^[0-9A-Za-z\-]+$`)
-	emailRegex        = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
-	paymentTransRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
-	itemRidRegex      = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
-)
+var indexRegex = regexp.MustCompile(`\[\d+\]`)
 
 func ValidateOrderRequest(order *models.OrderRequest) error {
-	if err := ValidateMainOrder(order); err != nil {
-		return err
-	}
-
-	if err := ValidateDelivery(&order.Delivery); err != nil {
-		return fmt.Errorf("delivery validation failed: %w", err)
-	}
+	var v ValidationErrors
+	rules := currentRules()
 
-	if err := ValidatePayment(&order.Payment); err != nil {
-		return fmt.Errorf("payment validation failed: %w", err)
-	}
-
-	if err := ValidateItems(order.Items); err != nil {
-		return fmt.Errorf("items validation failed: %w", err)
-	}
+	validateMainOrder(&v, rules, order)
+	validateDelivery(&v, rules, &order.Delivery)
+	validatePayment(&v, rules, &order.Payment)
+	validateItems(&v, rules, order.Items)
+	validateAmounts(&v, order)
 
-	return nil
+	return v.err()
 }
 
 func ValidateMainOrder(order *models.OrderRequest) error {
-	if order.OrderUID == "" {
-		return fmt.Errorf("%w: order_uid is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(order.OrderUID) > MaxOrderUIDLength {
-		return fmt.Errorf("%w: order_uid cannot be longer than %d characters", errs.ErrValidation, MaxOrderUIDLength)
-	}
-	if !orderUIDRegex.MatchString(order.OrderUID) {
-		return fmt.Errorf("%w: order_uid contains invalid characters", errs.ErrValidation)
-	}
-
-	if order.TrackNumber == "" {
-		return fmt.Errorf("%w: track_number is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(order.TrackNumber) > MaxTrackNumberLength {
-		return fmt.Errorf("%w: track_number cannot be longer than %d characters", errs.ErrValidation, MaxTrackNumberLength)
-	}
-	if !trackNumberRegex.MatchString(order.TrackNumber) {
-		return fmt.Errorf("%w: track_number can only contain uppercase letters and numbers", errs.ErrValidation)
-	}
+	var v ValidationErrors
+	validateMainOrder(&v, currentRules(), order)
+	return v.err()
+}
 
-	if order.Entry == "" {
-		return fmt.Errorf("%w: entry is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(order.Entry) > MaxEntryLength {
-		return fmt.Errorf("%w: entry cannot be longer than %d characters", errs.ErrValidation, MaxEntryLength)
-	}
-	if !entryRegex.MatchString(order.Entry) {
-		return fmt.Errorf("%w: entry can only contain uppercase letters", errs.ErrValidation)
-	}
+func ValidateDelivery(delivery *models.DeliveryRequest) error {
+	var v ValidationErrors
+	validateDelivery(&v, currentRules(), delivery)
+	return v.err()
+}
 
-	if order.Locale == "" {
-		return fmt.Errorf("%w: locale is required", errs.ErrValidation)
-	}
-	if !isValidLocale(order.Locale) {
-		return fmt.Errorf("%w: invalid locale value", errs.ErrValidation)
-	}
+func ValidatePayment(payment *models.PaymentRequest) error {
+	var v ValidationErrors
+	validatePayment(&v, currentRules(), payment)
+	return v.err()
+}
 
-	if utf8.RuneCountInString(order.InternalSignature) > MaxInternalSigLength {
-		return fmt.Errorf("%w: internal_signature cannot be longer than %d characters", errs.ErrValidation, MaxInternalSigLength)
-	}
+func ValidateItems(items []models.ItemRequest) error {
+	var v ValidationErrors
+	validateItems(&v, currentRules(), items)
+	return v.err()
+}
 
-	if order.CustomerID == "" {
-		return fmt.Errorf("%w: customer_id is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(order.CustomerID) > MaxCustomerIDLength {
-		return fmt.Errorf("%w: customer_id cannot be longer than %d characters", errs.ErrValidation, MaxCustomerIDLength)
-	}
+func validateMainOrder(v *ValidationErrors, r *Rules, order *models.OrderRequest) {
+	r.checkString(v, "order_uid", "order_uid", order.OrderUID)
+	r.checkString(v, "track_number", "track_number", order.TrackNumber)
+	r.checkString(v, "entry", "entry", order.Entry)
+	r.checkString(v, "locale", "locale", string(order.Locale))
+	r.checkString(v, "internal_signature", "internal_signature", order.InternalSignature)
+	r.checkString(v, "customer_id", "customer_id", order.CustomerID)
+	r.checkString(v, "delivery_service", "delivery_service", order.DeliveryService)
+	r.checkString(v, "shardkey", "shardkey", order.Shardkey)
+	r.checkInt(v, "sm_id", "sm_id", int64(order.SmID))
+	r.checkString(v, "oof_shard", "oof_shard", order.OofShard)
+	r.checkTime(v, "date_created", "date_created", order.DateCreated)
+	r.checkInt(v, "version", "version", order.Version)
+}
 
-	if order.DeliveryService == "" {
-		return fmt.Errorf("%w: delivery_service is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(order.DeliveryService) > MaxDeliveryServiceLen {
-		return fmt.Errorf("%w: delivery_service cannot be longer than %d characters", errs.ErrValidation, MaxDeliveryServiceLen)
-	}
+func validateDelivery(v *ValidationErrors, r *Rules, delivery *models.DeliveryRequest) {
+	r.checkString(v, "delivery.name", "delivery.name", delivery.Name)
+	r.checkString(v, "delivery.phone", "delivery.phone", delivery.Phone)
+	r.checkString(v, "delivery.zip", "delivery.zip", delivery.Zip)
+	r.checkString(v, "delivery.city", "delivery.city", delivery.City)
+	r.checkString(v, "delivery.address", "delivery.address", delivery.Address)
+	r.checkString(v, "delivery.region", "delivery.region", delivery.Region)
+	r.checkString(v, "delivery.email", "delivery.email", delivery.Email)
+}
 
-	if order.Shardkey == "" {
-		return fmt.Errorf("%w: shardkey is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(order.Shardkey) > MaxShardkeyLength {
-		return fmt.Errorf("%w: shardkey cannot be longer than %d characters", errs.ErrValidation, MaxShardkeyLength)
-	}
-	if !shardkeyRegex.MatchString(order.Shardkey) {
-		return fmt.Errorf("%w: shardkey can only contain numbers", errs.ErrValidation)
-	}
+func validatePayment(v *ValidationErrors, r *Rules, payment *models.PaymentRequest) {
+	r.checkString(v, "payment.transaction", "payment.transaction", payment.Transaction)
+	r.checkString(v, "payment.request_id", "payment.request_id", payment.RequestID)
+	r.checkString(v, "payment.currency", "payment.currency", string(payment.Currency))
+	r.checkString(v, "payment.provider", "payment.provider", payment.Provider)
+	r.checkInt(v, "payment.amount", "payment.amount", int64(payment.Amount))
+	r.checkInt(v, "payment.payment_dt", "payment.payment_dt", int64(payment.PaymentDt))
+	r.checkString(v, "payment.bank", "payment.bank", payment.Bank)
+	r.checkInt(v, "payment.delivery_cost", "payment.delivery_cost", int64(payment.DeliveryCost))
+	r.checkInt(v, "payment.goods_total", "payment.goods_total", int64(payment.GoodsTotal))
+	r.checkInt(v, "payment.custom_fee", "payment.custom_fee", int64(payment.CustomFee))
+}
 
-	if order.SmID <= 0 {
-		return fmt.Errorf("%w: sm_id must be positive", errs.ErrValidation)
+func validateItems(v *ValidationErrors, r *Rules, items []models.ItemRequest) {
+	if !r.checkCount(v, "items", "items", len(items)) {
+		return
 	}
 
-	if order.OofShard == "" {
-		return fmt.Errorf("%w: oof_shard is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(order.OofShard) > MaxOofShardLength {
-		return fmt.Errorf("%w: oof_shard cannot be longer than %d characters", errs.ErrValidation, MaxOofShardLength)
-	}
-	if !oofShardRegex.MatchString(order.OofShard) {
-		return fmt.Errorf("%w: oof_shard can only contain numbers", errs.ErrValidation)
-	}
+	seen := make(map[string]int, len(items))
+	for i, item := range items {
+		validateItem(v, r, item, i)
 
-	if order.DateCreated.IsZero() {
-		return fmt.Errorf("%w: date_created is required", errs.ErrValidation)
-	}
-	if order.DateCreated.After(time.Now().Add(24 * time.Hour)) {
-		return fmt.Errorf("%w: date_created cannot be in the future", errs.ErrValidation)
+		if item.Rid == "" {
+			continue
+		}
+		if first, ok := seen[item.Rid]; ok {
+			v.add(fmt.Sprintf("items[%d].rid", i), CodeDuplicate, "item[%d].rid duplicates item[%d].rid", i, first)
+			continue
+		}
+		seen[item.Rid] = i
 	}
+}
 
-	return nil
+func validateItem(v *ValidationErrors, r *Rules, item models.ItemRequest, index int) {
+	path := func(field string) string {
+		return fmt.Sprintf("items[%d].%s", index, field)
+	}
+
+	r.checkInt(v, "items[].chrt_id", path("chrt_id"), int64(item.ChrtID))
+	r.checkString(v, "items[].track_number", path("track_number"), item.TrackNumber)
+	r.checkInt(v, "items[].price", path("price"), int64(item.Price))
+	r.checkString(v, "items[].rid", path("rid"), item.Rid)
+	r.checkString(v, "items[].name", path("name"), item.Name)
+	r.checkInt(v, "items[].sale", path("sale"), int64(item.Sale))
+	r.checkString(v, "items[].size", path("size"), item.Size)
+	r.checkInt(v, "items[].total_price", path("total_price"), int64(item.TotalPrice))
+	r.checkInt(v, "items[].nm_id", path("nm_id"), int64(item.NmID))
+	r.checkString(v, "items[].brand", path("brand"), item.Brand)
+	r.checkInt(v, "items[].status", path("status"), int64(item.Status))
 }
 
-func ValidateDelivery(delivery *models.DeliveryRequest) error {
-	if delivery.Name == "" {
-		return fmt.Errorf("%w: delivery name is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(delivery.Name) > MaxDeliveryNameLength {
-		return fmt.Errorf("%w: delivery name cannot be longer than %d characters", errs.ErrValidation, MaxDeliveryNameLength)
+// validateAmounts checks that amounts, read as minor units of the payment
+// currency, stay within a plausible range for that currency.
+func validateAmounts(v *ValidationErrors, order *models.OrderRequest) {
+	currency := order.Payment.Currency
+	if !isValidCurrency(currency) {
+		return
 	}
 
-	if delivery.Phone == "" {
-		return fmt.Errorf("%w: delivery phone is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(delivery.Phone) > MaxDeliveryPhoneLength {
-		return fmt.Errorf("%w: delivery phone cannot be longer than %d characters", errs.ErrValidation, MaxDeliveryPhoneLength)
-	}
-	if !phoneRegex.MatchString(delivery.Phone) {
-		return fmt.Errorf("%w: delivery phone contains invalid characters", errs.ErrValidation)
+	maxMinor, ok := money.MaxMinor(currency)
+	if !ok {
+		v.add("payment.currency", CodeCurrency, "payment currency %s has no minor unit definition", currency)
+		return
 	}
 
-	if delivery.Zip == "" {
-		return fmt.Errorf("%w: delivery zip is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(delivery.Zip) > MaxDeliveryZipLength {
-		return fmt.Errorf("%w: delivery zip cannot be longer than %d characters", errs.ErrValidation, MaxDeliveryZipLength)
-	}
-	if !zipRegex.MatchString(delivery.Zip) {
-		return fmt.Errorf("%w: delivery zip contains invalid characters", errs.ErrValidation)
+	check := func(path string, value int) {
+		if int64(value) > maxMinor {
+			v.add(path, CodeMax, "%s exceeds the maximum amount for %s", path, currency)
+		}
 	}
 
-	if delivery.City == "" {
-		return fmt.Errorf("%w: delivery city is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(delivery.City) > MaxDeliveryCityLength {
-		return fmt.Errorf("%w: delivery city cannot be longer than %d characters", errs.ErrValidation, MaxDeliveryCityLength)
+	check("payment.amount", order.Payment.Amount)
+	check("payment.delivery_cost", order.Payment.DeliveryCost)
+	check("payment.goods_total", order.Payment.GoodsTotal)
+	check("payment.custom_fee", order.Payment.CustomFee)
+	for i, item := range order.Items {
+		check(fmt.Sprintf("items[%d].price", i), item.Price)
+		check(fmt.Sprintf("items[%d].total_price", i), item.TotalPrice)
 	}
+}
 
-	if delivery.Address == "" {
-		return fmt.Errorf("%w: delivery address is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(delivery.Address) > MaxDeliveryAddrLength {
-		return fmt.Errorf("%w: delivery address cannot be longer than %d characters", errs.ErrValidation, MaxDeliveryAddrLength)
+func (r *Rules) checkString(v *ValidationErrors, key, path, value string) {
+	rule := r.field(key)
+	if rule == nil {
+		return
 	}
+	label := rule.label(path)
 
-	if delivery.Region == "" {
-		return fmt.Errorf("%w: delivery region is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(delivery.Region) > MaxDeliveryRegionLength {
-		return fmt.Errorf("%w: delivery region cannot be longer than %d characters", errs.ErrValidation, MaxDeliveryRegionLength)
+	switch {
+	case value == "":
+		if rule.Required {
+			v.add(path, CodeRequired, "%s", rule.message(CodeRequired, "%s is required", label))
+		}
+	case rule.MaxLength > 0 && utf8.RuneCountInString(value) > rule.MaxLength:
+		v.add(path, CodeMaxLength, "%s", rule.message(CodeMaxLength, "%s cannot be longer than %d characters", label, rule.MaxLength))
+	case rule.pattern != nil && !rule.pattern.MatchString(value):
+		v.add(path, CodePattern, "%s", rule.message(CodePattern, "%s contains invalid characters", label))
+	case len(rule.Enum) > 0 && !slices.Contains(rule.Enum, value):
+		v.add(path, CodeEnum, "%s", rule.message(CodeEnum, "%s must be one of %s", label, strings.Join(rule.Enum, ", ")))
 	}
+}
 
-	if delivery.Email == "" {
-		return fmt.Errorf("%w: delivery email is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(delivery.Email) > MaxDeliveryEmailLength {
-		return fmt.Errorf("%w: delivery email cannot be longer than %d characters", errs.ErrValidation, MaxDeliveryEmailLength)
+func (r *Rules) checkInt(v *ValidationErrors, key, path string, value int64) {
+	rule := r.field(key)
+	if rule == nil {
+		return
+	}
+	label := rule.label(path)
+
+	switch {
+	case rule.Min != nil && value < *rule.Min:
+		switch code := minCode(*rule.Min); code {
+		case CodeNonNegative:
+			v.add(path, code, "%s", rule.message(code, "%s cannot be negative", label))
+		case CodePositive:
+			v.add(path, code, "%s", rule.message(code, "%s must be positive", label))
+		default:
+			v.add(path, code, "%s", rule.message(code, "%s must be at least %d", label, *rule.Min))
+		}
+	case rule.Max != nil && value > *rule.Max:
+		v.add(path, CodeMax, "%s", rule.message(CodeMax, "%s cannot be greater than %d", label, *rule.Max))
 	}
-	if !emailRegex.MatchString(delivery.Email) {
-		return fmt.Errorf("%w: delivery email is invalid", errs.ErrValidation)
+}
+
+func minCode(min int64) string {
+	switch min {
+	case 0:
+		return CodeNonNegative
+	case 1:
+		return CodePositive
 	}
 
-	return nil
+	return CodeMin
 }
 
-func ValidatePayment(payment *models.PaymentRequest) error {
-	if payment.Transaction == "" {
-		return fmt.Errorf("%w: payment transaction is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(payment.Transaction) > MaxPaymentTransLength {
-		return fmt.Errorf("%w: payment transaction cannot be longer than %d characters", errs.ErrValidation, MaxPaymentTransLength)
-	}
-	if !paymentTransRegex.MatchString(payment.Transaction) {
-		return fmt.Errorf("%w: payment transaction contains invalid characters", errs.ErrValidation)
+func (r *Rules) checkTime(v *ValidationErrors, key, path string, value time.Time) {
+	rule := r.field(key)
+	if rule == nil {
+		return
 	}
+	label := rule.label(path)
 
-	if utf8.RuneCountInString(payment.RequestID) > MaxPaymentReqIDLength {
-		return fmt.Errorf("%w: payment request_id cannot be longer than %d characters", errs.ErrValidation, MaxPaymentReqIDLength)
+	switch {
+	case value.IsZero():
+		if rule.Required {
+			v.add(path, CodeRequired, "%s", rule.message(CodeRequired, "%s is required", label))
+		}
+	case rule.MaxFuture > 0 && value.After(time.Now().Add(rule.MaxFuture)):
+		v.add(path, CodeNotFuture, "%s", rule.message(CodeNotFuture, "%s cannot be in the future", label))
 	}
+}
 
-	if payment.Currency == "" {
-		return fmt.Errorf("%w: payment currency is required", errs.ErrValidation)
-	}
-	if !isValidCurrency(payment.Currency) {
-		return fmt.Errorf("%w: invalid payment currency", errs.ErrValidation)
+// checkCount reports whether the collection may be validated further.
+func (r *Rules) checkCount(v *ValidationErrors, key, path string, count int) bool {
+	rule := r.field(key)
+	if rule == nil || count >= rule.MinItems {
+		return true
 	}
 
-	if payment.Provider == "" {
-		return fmt.Errorf("%w: payment provider is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(payment.Provider) > MaxPaymentProviderLen {
-		return fmt.Errorf("%w: payment provider cannot be longer than %d characters", errs.ErrValidation, MaxPaymentProviderLen)
-	}
+	v.add(path, CodeMinItems, "%s", rule.message(CodeMinItems, "%s must contain at least %d entries", rule.label(path), rule.MinItems))
+	return false
+}
 
-	if payment.Amount < 0 {
-		return fmt.Errorf("%w: payment amount cannot be negative", errs.ErrValidation)
-	}
+func (r *Rules) allows(key, value string) bool {
+	rule := r.field(key)
+	return rule != nil && slices.Contains(rule.Enum, value)
+}
 
-	if payment.PaymentDt <= 0 {
-		return fmt.Errorf("%w: payment_dt must be positive", errs.ErrValidation)
+// label names the field in messages. Labels with "[]" take the index from
+// the path and fall back to the path itself when it has none.
+func (f *FieldRule) label(path string) string {
+	if f.Label == "" {
+		return path
 	}
 
-	if payment.Bank == "" {
-		return fmt.Errorf("%w: payment bank is required", errs.ErrValidation)
-	}
-	if utf8.RuneCountInString(payment.Bank) > MaxPaymentBankLength {
-		return fmt.Errorf("%w: payment bank cannot be longer than %d characters", errs.ErrValidation, MaxPaymentBankLength)
+	if strings.Contains(f.Label, "[]") {
+		index := indexRegex.FindString(path)
+		if index == "" {
+			return path
+		}
+		return strings.Replace(f.Label, "[]", index, 1)
 	}
 
-	if payment.DeliveryCost < 0 {
-		return fmt.Errorf("%w: delivery_cost cannot be negative", errs.ErrValidation)
-	}
+	return f.Label
+}
 
-	if payment.GoodsTotal < 0 {
-		return fmt.Errorf("%w: goods_total cannot be negative", errs.ErrValidation)
+func (f *FieldRule) message(code, format string, args ...any) string {
+	if message := f.Messages[code]; message != "" {
+		return message
 	}
 
-	if payment.CustomFee < 0 {
-		return fmt.Errorf("%w: custom_fee cannot be negative", errs.ErrValidation)
-	}
+	return fmt.Sprintf(format, args...)
+}
+
+func isValidLocale(locale models.LocaleEnum) bool {
+	return currentRules().allows("locale", string(locale))
+}
 
-	return nil
+func isValidCurrency(currency models.CurrencyEnum) bool {
+	return currentRules().allows("payment.currency", string(currency))
 }
 
-func ValidateItems(items []models.ItemRequest) error {
-	if len(items) == 0 {
-		return fmt.Errorf("%w: at least one item is required", errs.ErrValidation)
+// ValidateOrderUID checks an order UID looked up by clients against the
+// order_uid rule.
+func ValidateOrderUID(orderUID string) error {
+	var v ValidationErrors
+	rule := currentRules().field("order_uid")
+	if rule == nil {
+		rule = &FieldRule{}
 	}
 
-	for i, item := range items {
-		if err := validateItem(item, i); err != nil {
-			return err
-		}
+	switch {
+	case orderUID == "":
+		v.add("order_uid", CodeRequired, "order UID cannot be empty")
+	case rule.MaxLength > 0 && utf8.RuneCountInString(orderUID) > rule.MaxLength:
+		v.add("order_uid", CodeMaxLength, "order UID too long")
+	case rule.pattern != nil && !rule.pattern.MatchString(orderUID):
+		v.add("order_uid", CodePattern, "order_uid contains invalid characters")
 	}
 
-	return nil
+	return v.err()
 }
 
-func validateItem(item models.ItemRequest, index int) error {
-	if item.ChrtID <= 0 {
-		return fmt.Errorf("%w: item[%d].chrt_id must be positive", errs.ErrValidation, index)
-	}
-
-	if item.TrackNumber == "" {
-		return fmt.Errorf("%w: item[%d].track_number is required", errs.ErrValidation, index)
-	}
-	if utf8.RuneCountInString(item.TrackNumber) > MaxItemTrackNumberLen {
-		return fmt.Errorf("%w: item[%d].track_number cannot be longer than %d characters", errs.ErrValidation, index, MaxItemTrackNumberLen)
-	}
+func ValidateOrderFilter(filter *models.OrderFilter) error {
+	var v ValidationErrors
 
-	if item.Price <= 0 {
-		return fmt.Errorf("%w: item[%d].price must be positive", errs.ErrValidation, index)
+	if filter.Locale != "" && !isValidLocale(filter.Locale) {
+		v.add("locale", CodeEnum, "unsupported locale: %s", filter.Locale)
 	}
-
-	if item.Rid == "" {
-		return fmt.Errorf("%w: item[%d].rid is required", errs.ErrValidation, index)
+	if filter.Currency != "" && !isValidCurrency(filter.Currency) {
+		v.add("currency", CodeEnum, "unsupported currency: %s", filter.Currency)
 	}
-	if utf8.RuneCountInString(item.Rid) > MaxItemRidLength {
-		return fmt.Errorf("%w: item[%d].rid cannot be longer than %d characters", errs.ErrValidation, index, MaxItemRidLength)
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedTo.After(filter.CreatedFrom) {
+		v.add("created_to", CodeMin, "created_to must be after created_from")
 	}
-	if !itemRidRegex.MatchString(item.Rid) {
-		return fmt.Errorf("%w: item[%d].rid contains invalid characters", errs.ErrValidation, index)
+	if filter.Limit < 0 {
+		v.add("limit", CodeNonNegative, "limit cannot be negative")
 	}
 
-	if item.Name == "" {
-		return fmt.Errorf("%w: item[%d].name is required", errs.ErrValidation, index)
-	}
-	if utf8.RuneCountInString(item.Name) > MaxItemNameLength {
-		return fmt.Errorf("%w: item[%d].name cannot be longer than %d characters", errs.ErrValidation, index, MaxItemNameLength)
-	}
+	return v.err()
+}
 
-	if item.Sale < 0 {
-		return fmt.Errorf("%w: item[%d].sale cannot be negative", errs.ErrValidation, index)
-	}
+func ValidateEventEnvelope(envelope *models.EventEnvelope) error {
+	var v ValidationErrors
 
-	if item.Size == "" {
-		return fmt.Errorf("%w: item[%d].size is required", errs.ErrValidation, index)
+	if envelope.EventType == "" {
+		v.add("event_type", CodeRequired, "event_type is required")
 	}
-	if utf8.RuneCountInString(item.Size) > MaxItemSizeLength {
-		return fmt.Errorf("%w: item[%d].size cannot be longer than %d characters", errs.ErrValidation, index, MaxItemSizeLength)
+	if envelope.EventID == "" {
+		v.add("event_id", CodeRequired, "event_id is required")
 	}
-
-	if item.TotalPrice <= 0 {
-		return fmt.Errorf("%w: item[%d].total_price must be positive", errs.ErrValidation, index)
+	if envelope.OccurredAt.IsZero() {
+		v.add("occurred_at", CodeRequired, "occurred_at is required")
 	}
-
-	if item.NmID <= 0 {
-		return fmt.Errorf("%w: item[%d].nm_id must be positive", errs.ErrValidation, index)
+	if len(envelope.Payload) == 0 {
+		v.add("payload", CodeRequired, "payload is required")
 	}
 
-	if item.Brand == "" {
-		return fmt.Errorf("%w: item[%d].brand is required", errs.ErrValidation, index)
-	}
-	if utf8.RuneCountInString(item.Brand) > MaxItemBrandLength {
-		return fmt.Errorf("%w: item[%d].brand cannot be longer than %d characters", errs.ErrValidation, index, MaxItemBrandLength)
-	}
+	return v.err()
+}
 
-	if item.Status < 0 {
-		return fmt.Errorf("%w: item[%d].status cannot be negative", errs.ErrValidation, index)
-	}
+func ValidateItemStatusChanged(event *models.ItemStatusChangedEvent) error {
+	var v ValidationErrors
+	rules := currentRules()
 
-	return nil
-}
+	rules.checkString(&v, "order_uid", "order_uid", event.OrderUID)
+	rules.checkString(&v, "items[].rid", "rid", event.Rid)
+	rules.checkInt(&v, "items[].status", "status", int64(event.Status))
 
-func isValidLocale(locale models.LocaleEnum) bool {
-	switch locale {
-	case models.LocaleEN, models.LocaleRU, models.LocaleES, models.LocaleFR,
-		models.LocaleDE, models.LocaleIT, models.LocaleZH, models.LocaleJA,
-		models.LocaleKO, models.LocaleAR:
-		return true
-	default:
-		return false
-	}
+	return v.err()
 }
 
-func isValidCurrency(currency models.CurrencyEnum) bool {
-	switch currency {
-	case models.CurrencyUSD, models.CurrencyEUR, models.CurrencyRUB, models.CurrencyGBP,
-		models.CurrencyJPY, models.CurrencyCNY, models.CurrencyCAD, models.CurrencyAUD,
-		models.CurrencyCHF:
-		return true
-	default:
-		return false
-	}
-}
+func ValidatePaymentCaptured(event *models.PaymentCapturedEvent) error {
+	var v ValidationErrors
+	rules := currentRules()
 
-func ValidateOrderUID(orderUID string) error {
-	if orderUID == "" {
-		return fmt.Errorf("order UID cannot be empty")
-	}
+	rules.checkString(&v, "order_uid", "order_uid", event.OrderUID)
+	rules.checkString(&v, "payment.transaction", "transaction", event.Transaction)
+	rules.checkInt(&v, "payment.amount", "amount", int64(event.Amount))
+	rules.checkInt(&v, "payment.payment_dt", "payment_dt", int64(event.PaymentDt))
+	rules.checkString(&v, "payment.bank", "bank", event.Bank)
 
-	if len(orderUID) > 50 {
-		return fmt.Errorf("order UID too long")
-	}
+	return v.err()
+}
 
-	if !orderUIDRegex.MatchString(orderUID) {
-		return fmt.Errorf("%w: order_uid contains invalid characters", errs.ErrValidation)
-	}
+func ValidateDeliveryAddressChanged(event *models.DeliveryAddressChangedEvent) error {
+	var v ValidationErrors
+	rules := currentRules()
+
+	rules.checkString(&v, "order_uid", "order_uid", event.OrderUID)
+	rules.checkString(&v, "delivery.zip", "zip", event.Zip)
+	rules.checkString(&v, "delivery.city", "city", event.City)
+	rules.checkString(&v, "delivery.address", "address", event.Address)
+	rules.checkString(&v, "delivery.region", "region", event.Region)
 
-	return nil
+	return v.err()
 }
@@ -1,14 +1,18 @@
 package validate
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/supchaser/wb_l0/internal/app/models"
 	"github.com/supchaser/wb_l0/internal/utils/errs"
+	"github.com/supchaser/wb_l0/internal/utils/money"
 )
 
 func TestValidateOrderRequest(t *testing.T) {
@@ -401,6 +405,70 @@ func TestValidateOrderUID(t *testing.T) {
 	}
 }
 
+func TestValidateOrderFilter(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		filter   models.OrderFilter
+		wantCode string
+	}{
+		{name: "Empty", filter: models.OrderFilter{}},
+		{name: "Valid", filter: models.OrderFilter{CreatedFrom: from, CreatedTo: from.AddDate(0, 1, 0), Locale: "en", Currency: "USD", Limit: 100}},
+		{name: "UnknownLocale", filter: models.OrderFilter{Locale: "pt"}, wantCode: CodeEnum},
+		{name: "UnknownCurrency", filter: models.OrderFilter{Currency: "BTC"}, wantCode: CodeEnum},
+		{name: "EmptyRange", filter: models.OrderFilter{CreatedFrom: from, CreatedTo: from}, wantCode: CodeMin},
+		{name: "NegativeLimit", filter: models.OrderFilter{Limit: -1}, wantCode: CodeNonNegative},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOrderFilter(&tt.filter)
+			if tt.wantCode == "" {
+				assert.NoError(t, err)
+				return
+			}
+			violations, ok := AsValidationErrors(err)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantCode, violations[0].Code)
+		})
+	}
+}
+
+func TestMaxLengthConstants(t *testing.T) {
+	fields := DefaultRules().Fields
+	for key, want := range map[string]int{
+		"order_uid":            MaxOrderUIDLength,
+		"track_number":         MaxTrackNumberLength,
+		"entry":                MaxEntryLength,
+		"internal_signature":   MaxInternalSigLength,
+		"customer_id":          MaxCustomerIDLength,
+		"delivery_service":     MaxDeliveryServiceLen,
+		"shardkey":             MaxShardkeyLength,
+		"oof_shard":            MaxOofShardLength,
+		"delivery.name":        MaxDeliveryNameLength,
+		"delivery.phone":       MaxDeliveryPhoneLength,
+		"delivery.zip":         MaxDeliveryZipLength,
+		"delivery.city":        MaxDeliveryCityLength,
+		"delivery.address":     MaxDeliveryAddrLength,
+		"delivery.region":      MaxDeliveryRegionLength,
+		"delivery.email":       MaxDeliveryEmailLength,
+		"payment.transaction":  MaxPaymentTransLength,
+		"payment.request_id":   MaxPaymentReqIDLength,
+		"payment.provider":     MaxPaymentProviderLen,
+		"payment.bank":         MaxPaymentBankLength,
+		"items[].track_number": MaxItemTrackNumberLen,
+		"items[].rid":          MaxItemRidLength,
+		"items[].name":         MaxItemNameLength,
+		"items[].size":         MaxItemSizeLength,
+		"items[].brand":        MaxItemBrandLength,
+	} {
+		if assert.Contains(t, fields, key) {
+			assert.Equal(t, want, fields[key].MaxLength, key)
+		}
+	}
+}
+
 func TestIsValidLocale(t *testing.T) {
 	tests := []struct {
 		locale models.LocaleEnum
@@ -558,3 +626,533 @@ func createValidItemsRequest() []models.ItemRequest {
 		},
 	}
 }
+
+func TestValidateEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate func() error
+		wantErr  bool
+		errMsg   string
+	}{
+		{
+			name: "ValidEnvelope",
+			validate: func() error {
+				return ValidateEventEnvelope(&models.EventEnvelope{
+					EventType:  models.EventItemStatusChanged,
+					EventID:    "event-1",
+					OccurredAt: time.Now(),
+					Payload:    []byte(`{}`),
+				})
+			},
+		},
+		{
+			name: "EnvelopeWithoutEventID",
+			validate: func() error {
+				return ValidateEventEnvelope(&models.EventEnvelope{
+					EventType:  models.EventItemStatusChanged,
+					OccurredAt: time.Now(),
+					Payload:    []byte(`{}`),
+				})
+			},
+			wantErr: true,
+			errMsg:  "event_id is required",
+		},
+		{
+			name: "ValidItemStatusChanged",
+			validate: func() error {
+				return ValidateItemStatusChanged(&models.ItemStatusChangedEvent{OrderUID: "order-1", Rid: "rid-1", Status: 202})
+			},
+		},
+		{
+			name: "ItemStatusChangedWithoutRid",
+			validate: func() error {
+				return ValidateItemStatusChanged(&models.ItemStatusChangedEvent{OrderUID: "order-1", Status: 202})
+			},
+			wantErr: true,
+			errMsg:  "rid is required",
+		},
+		{
+			name: "PaymentCapturedWithInvalidOrderUID",
+			validate: func() error {
+				return ValidatePaymentCaptured(&models.PaymentCapturedEvent{
+					OrderUID:    "order@1",
+					Transaction: "txn-1",
+					PaymentDt:   1,
+					Bank:        "alpha",
+				})
+			},
+			wantErr: true,
+			errMsg:  "order_uid contains invalid characters",
+		},
+		{
+			name: "PaymentCapturedWithoutBank",
+			validate: func() error {
+				return ValidatePaymentCaptured(&models.PaymentCapturedEvent{
+					OrderUID:    "order-1",
+					Transaction: "txn-1",
+					PaymentDt:   1,
+				})
+			},
+			wantErr: true,
+			errMsg:  "payment bank is required",
+		},
+		{
+			name: "ValidDeliveryAddressChanged",
+			validate: func() error {
+				return ValidateDeliveryAddressChanged(&models.DeliveryAddressChangedEvent{
+					OrderUID: "order-1",
+					Zip:      "123456",
+					City:     "Moscow",
+					Address:  "Lenina 1",
+					Region:   "Moscow",
+				})
+			},
+		},
+		{
+			name: "DeliveryAddressChangedWithoutCity",
+			validate: func() error {
+				return ValidateDeliveryAddressChanged(&models.DeliveryAddressChangedEvent{
+					OrderUID: "order-1",
+					Zip:      "123456",
+					Address:  "Lenina 1",
+					Region:   "Moscow",
+				})
+			},
+			wantErr: true,
+			errMsg:  "delivery city is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				assert.True(t, errors.Is(err, errs.ErrValidation))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRuleSet_Check(t *testing.T) {
+	tests := []struct {
+		name         string
+		overrides    map[string]Severity
+		modify       func(order *models.OrderRequest)
+		wantWarnings []string
+		wantErr      bool
+		wantPath     string
+	}{
+		{
+			name:   "ConsistentOrder",
+			modify: func(order *models.OrderRequest) {},
+		},
+		{
+			name: "GoodsTotalMismatchWarns",
+			modify: func(order *models.OrderRequest) {
+				order.Payment.GoodsTotal = 300
+				order.Payment.Amount = 1800
+			},
+			wantWarnings: []string{RuleGoodsTotalMatchesItems},
+		},
+		{
+			name:      "AmountMismatchRejects",
+			overrides: map[string]Severity{RuleAmountMatchesComponents: SeverityReject},
+			modify: func(order *models.OrderRequest) {
+				order.Payment.Amount = 100
+			},
+			wantErr:  true,
+			wantPath: "payment.amount",
+		},
+		{
+			name:      "ItemTrackNumberMismatchDisabled",
+			overrides: map[string]Severity{RuleItemTrackNumberMatchesOrder: SeverityOff},
+			modify: func(order *models.OrderRequest) {
+				order.Items[0].TrackNumber = "OTHERTRACK"
+			},
+		},
+		{
+			name: "ItemTrackNumberMismatchWarns",
+			modify: func(order *models.OrderRequest) {
+				order.Items = append(order.Items, order.Items[0], order.Items[0])
+				order.Items[1].TrackNumber = "OTHERTRACK"
+				order.Items[2].TrackNumber = "OTHERTRACK"
+				order.Payment.GoodsTotal = 951
+				order.Payment.Amount = 2451
+			},
+			wantWarnings: []string{RuleItemTrackNumberMatchesOrder, RuleItemTrackNumberMatchesOrder},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := CreateRuleSet(tt.overrides)
+			assert.NoError(t, err)
+
+			order := createValidOrderRequest()
+			tt.modify(order)
+
+			warnings, err := rules.Check(order)
+			if tt.wantErr {
+				violations, ok := AsValidationErrors(err)
+				assert.True(t, ok)
+				assert.True(t, errors.Is(err, errs.ErrValidation))
+				assert.Equal(t, tt.wantPath, violations[0].Path)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			var codes []string
+			for _, warning := range warnings {
+				codes = append(codes, warning.Rule)
+			}
+			assert.Equal(t, tt.wantWarnings, codes)
+		})
+	}
+}
+
+func TestRuleSet_Check_MinorUnits(t *testing.T) {
+	tests := []struct {
+		currency models.CurrencyEnum
+		want     string
+	}{
+		{currency: models.CurrencyUSD, want: "amount 18.00 USD does not match goods_total + delivery_cost + custom_fee 18.17 USD"},
+		{currency: models.CurrencyJPY, want: "amount 1800 JPY does not match goods_total + delivery_cost + custom_fee 1817 JPY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.currency), func(t *testing.T) {
+			order := createValidOrderRequest()
+			order.Payment.Currency = tt.currency
+			order.Payment.Amount = 1800
+
+			warnings, err := DefaultRuleSet().Check(order)
+			assert.NoError(t, err)
+			assert.Equal(t, []models.ValidationWarning{
+				{Rule: RuleAmountMatchesComponents, Path: "payment.amount", Message: tt.want},
+			}, warnings)
+		})
+	}
+}
+
+func TestCreateRuleSet(t *testing.T) {
+	overrides, err := ParseSeverities(" goods_total_matches_items=reject, amount_matches_components=off ")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Severity{
+		RuleGoodsTotalMatchesItems:  SeverityReject,
+		RuleAmountMatchesComponents: SeverityOff,
+	}, overrides)
+
+	_, err = CreateRuleSet(overrides)
+	assert.NoError(t, err)
+
+	_, err = ParseSeverities("goods_total_matches_items")
+	assert.Error(t, err)
+
+	_, err = CreateRuleSet(map[string]Severity{"unknown_rule": SeverityWarn})
+	assert.Error(t, err)
+
+	_, err = CreateRuleSet(map[string]Severity{RuleGoodsTotalMatchesItems: "fatal"})
+	assert.Error(t, err)
+}
+
+func TestValidateOrderRequest_CollectsAllViolations(t *testing.T) {
+	order := createValidOrderRequest()
+	order.OrderUID = ""
+	order.Delivery.Email = "not-an-email"
+	order.Payment.Currency = "XXX"
+	order.Items = append(order.Items, order.Items[0], order.Items[0])
+	order.Items[2].Price = 0
+	order.Items[2].Rid = "bad rid"
+
+	err := ValidateOrderRequest(order)
+	assert.True(t, errors.Is(err, errs.ErrValidation))
+
+	violations, ok := AsValidationErrors(fmt.Errorf("wrapped: %w", err))
+	assert.True(t, ok)
+	assert.Equal(t, ValidationErrors{
+		{Path: "order_uid", Code: CodeRequired, Message: "order_uid is required"},
+		{Path: "delivery.email", Code: CodePattern, Message: "delivery email is invalid"},
+		{Path: "payment.currency", Code: CodeEnum, Message: "invalid payment currency"},
+		{Path: "items[1].rid", Code: CodeDuplicate, Message: "item[1].rid duplicates item[0].rid"},
+		{Path: "items[2].price", Code: CodePositive, Message: "item[2].price must be positive"},
+		{Path: "items[2].rid", Code: CodePattern, Message: "item[2].rid contains invalid characters"},
+	}, violations)
+	assert.Equal(t, "validation error: order_uid is required; delivery email is invalid; invalid payment currency; "+
+		"item[1].rid duplicates item[0].rid; item[2].price must be positive; item[2].rid contains invalid characters", err.Error())
+
+	assert.NoError(t, ValidateOrderRequest(createValidOrderRequest()))
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "Empty", data: ""},
+		{name: "YAML", data: "fields:\n  order_uid:\n    required: true\n    max_length: 10\n"},
+		{name: "JSON", data: `{"fields": {"payment.currency": {"required": true, "enum": ["USD", "EUR"]}}}`},
+		{name: "UnknownField", data: "fields:\n  order_id:\n    required: true\n", wantErr: true},
+		{name: "UnknownKey", data: "fields:\n  order_uid:\n    maximum: 10\n", wantErr: true},
+		{name: "InvalidPattern", data: "fields:\n  entry:\n    pattern: '['\n", wantErr: true},
+		{name: "UnknownConsistencyRule", data: "consistency:\n  totals: warn\n", wantErr: true},
+		{name: "InvalidSeverity", data: "consistency:\n  amount_matches_components: fatal\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRules([]byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, len(DefaultRules().Fields), len(rules.Fields))
+		})
+	}
+}
+
+func TestParseRules_MergesAttributes(t *testing.T) {
+	data := `
+fields:
+  track_number:
+    max_length: 20
+  shardkey:
+    pattern: ''
+    messages:
+      max_length: shardkey is too long
+  sm_id:
+    min: null
+  locale:
+    enum: [en, ru]
+`
+	rules, err := ParseRules([]byte(data))
+	assert.NoError(t, err)
+
+	trackNumber := rules.Fields["track_number"]
+	assert.Equal(t, 20, trackNumber.MaxLength)
+	assert.True(t, trackNumber.Required)
+	assert.Equal(t, "^[A-Z0-9]+$", trackNumber.Pattern)
+	assert.Equal(t, "track_number can only contain uppercase letters and numbers", trackNumber.Messages[CodePattern])
+
+	shardkey := rules.Fields["shardkey"]
+	assert.True(t, shardkey.Required)
+	assert.Equal(t, 10, shardkey.MaxLength)
+	assert.Empty(t, shardkey.Pattern)
+	assert.Nil(t, shardkey.pattern)
+	assert.Equal(t, map[string]string{
+		CodePattern:   "shardkey can only contain numbers",
+		CodeMaxLength: "shardkey is too long",
+	}, shardkey.Messages)
+
+	assert.Nil(t, rules.Fields["sm_id"].Min)
+	assert.Equal(t, []string{"en", "ru"}, rules.Fields["locale"].Enum)
+	assert.Equal(t, "invalid locale value", rules.Fields["locale"].Messages[CodeEnum])
+
+	// The defaults are left untouched.
+	assert.Equal(t, 50, DefaultRules().Fields["track_number"].MaxLength)
+	assert.NotNil(t, DefaultRules().Fields["sm_id"].Min)
+	assert.Len(t, DefaultRules().Fields["shardkey"].Messages, 1)
+}
+
+func TestUseRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	data := `
+fields:
+  order_uid:
+    required: true
+    max_length: 10
+    pattern: '^[a-z0-9]+$'
+  payment.currency:
+    required: true
+    enum: [EUR]
+consistency:
+  amount_matches_components: reject
+`
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	rules, err := LoadRulesFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "invalid payment currency", rules.Fields["payment.currency"].Messages[CodeEnum])
+
+	UseRules(rules)
+	defer UseRules(DefaultRules())
+
+	err = ValidateOrderRequest(createValidOrderRequest())
+	violations, ok := AsValidationErrors(err)
+	assert.True(t, ok)
+	assert.Equal(t, ValidationErrors{
+		{Path: "order_uid", Code: CodeMaxLength, Message: "order_uid cannot be longer than 10 characters"},
+		{Path: "payment.currency", Code: CodeEnum, Message: "invalid payment currency"},
+	}, violations)
+
+	assert.NoError(t, ValidateOrderUID("abcdefghij"))
+	assert.ErrorContains(t, ValidateOrderUID("abcdefghijk"), "order UID too long")
+	assert.ErrorContains(t, ValidateOrderUID("ABC"), "order_uid contains invalid characters")
+
+	order := createValidOrderRequest()
+	order.Payment.Amount = 1
+	_, err = DefaultRuleSet().Check(order)
+	assert.True(t, errors.Is(err, errs.ErrValidation))
+
+	_, err = LoadRulesFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestOrderSchema(t *testing.T) {
+	schema := OrderSchema()
+
+	assert.Equal(t, SchemaDraft, schema["$schema"])
+	assert.ElementsMatch(t, []string{
+		"order_uid", "track_number", "entry", "locale", "customer_id", "delivery_service",
+		"shardkey", "sm_id", "oof_shard", "date_created", "delivery", "payment", "items",
+	}, schema["required"])
+
+	properties := schema["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string", "maxLength": 100}, properties["internal_signature"])
+
+	items := properties["items"].(map[string]any)
+	assert.Equal(t, 1, items["minItems"])
+	price := items["items"].(map[string]any)["properties"].(map[string]any)["price"]
+	assert.Equal(t, map[string]any{"type": "integer", "minimum": int64(1)}, price)
+
+	_, err := compileSchema(schema)
+	assert.NoError(t, err)
+}
+
+func TestValidateOrderJSON_AgreesWithValidateOrderRequest(t *testing.T) {
+	type document = map[string]any
+
+	field := func(doc document, path ...string) document {
+		for _, key := range path {
+			switch next := doc[key].(type) {
+			case document:
+				doc = next
+			case []any:
+				doc = next[0].(document)
+			}
+		}
+		return doc
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(doc document)
+		valid  bool
+		paths  []string
+	}{
+		{name: "Valid", mutate: func(doc document) {}, valid: true},
+		{name: "OptionalFieldsMissing", mutate: func(doc document) {
+			delete(doc, "internal_signature")
+			delete(doc, "version")
+			delete(field(doc, "payment"), "request_id")
+			delete(field(doc, "payment"), "custom_fee")
+			delete(field(doc, "items"), "sale")
+		}, valid: true},
+		{name: "UnknownFieldIgnored", mutate: func(doc document) { doc["comment"] = "fragile" }, valid: true},
+		{name: "SeveralItems", mutate: func(doc document) {
+			second := make(document)
+			for key, value := range field(doc, "items") {
+				second[key] = value
+			}
+			second["rid"] = "second-rid"
+			doc["items"] = append(doc["items"].([]any), second)
+		}, valid: true},
+		{name: "MissingOrderUID", mutate: func(doc document) { delete(doc, "order_uid") }, paths: []string{"order_uid"}},
+		{name: "EmptyOrderUID", mutate: func(doc document) { doc["order_uid"] = "" }, paths: []string{"order_uid"}},
+		{name: "InvalidTrackNumber", mutate: func(doc document) { doc["track_number"] = "wbil" }, paths: []string{"track_number"}},
+		{name: "LongEntry", mutate: func(doc document) { doc["entry"] = "WBILWBILWBIL" }, paths: []string{"entry"}},
+		{name: "UnknownLocale", mutate: func(doc document) { doc["locale"] = "pt" }, paths: []string{"locale"}},
+		{name: "LongInternalSignature", mutate: func(doc document) {
+			doc["internal_signature"] = string(make([]rune, MaxInternalSigLength+1))
+		}, paths: []string{"internal_signature"}},
+		{name: "ZeroSmID", mutate: func(doc document) { doc["sm_id"] = 0 }, paths: []string{"sm_id"}},
+		{name: "MissingSmID", mutate: func(doc document) { delete(doc, "sm_id") }, paths: []string{"sm_id"}},
+		{name: "NegativeVersion", mutate: func(doc document) { doc["version"] = -1 }, paths: []string{"version"}},
+		{name: "InvalidEmail", mutate: func(doc document) { field(doc, "delivery")["email"] = "test@" }, paths: []string{"delivery.email"}},
+		{name: "InvalidPhone", mutate: func(doc document) { field(doc, "delivery")["phone"] = "call me" }, paths: []string{"delivery.phone"}},
+		{name: "MissingDeliveryName", mutate: func(doc document) { delete(field(doc, "delivery"), "name") }, paths: []string{"delivery.name"}},
+		{name: "UnknownCurrency", mutate: func(doc document) { field(doc, "payment")["currency"] = "BTC" }, paths: []string{"payment.currency"}},
+		{name: "NegativeAmount", mutate: func(doc document) { field(doc, "payment")["amount"] = -5 }, paths: []string{"payment.amount"}},
+		{name: "MissingPaymentDt", mutate: func(doc document) { delete(field(doc, "payment"), "payment_dt") }, paths: []string{"payment.payment_dt"}},
+		{name: "EmptyItems", mutate: func(doc document) { doc["items"] = []any{} }, paths: []string{"items"}},
+		{name: "MissingItems", mutate: func(doc document) { delete(doc, "items") }, paths: []string{"items"}},
+		{name: "ZeroItemPrice", mutate: func(doc document) { field(doc, "items")["price"] = 0 }, paths: []string{"items[0].price"}},
+		{name: "InvalidItemRid", mutate: func(doc document) { field(doc, "items")["rid"] = "ab 42" }, paths: []string{"items[0].rid"}},
+		{name: "SeveralViolations", mutate: func(doc document) {
+			doc["shardkey"] = "x"
+			field(doc, "delivery")["zip"] = ""
+			field(doc, "items")["brand"] = ""
+		}, paths: []string{"shardkey", "delivery.zip", "items[0].brand"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(createValidOrderRequest())
+			assert.NoError(t, err)
+
+			var doc document
+			assert.NoError(t, json.Unmarshal(data, &doc))
+			tt.mutate(doc)
+			data, err = json.Marshal(doc)
+			assert.NoError(t, err)
+
+			schemaErr := ValidateOrderJSON(data)
+
+			var order models.OrderRequest
+			assert.NoError(t, json.Unmarshal(data, &order))
+			orderErr := ValidateOrderRequest(&order)
+
+			assert.Equal(t, tt.valid, schemaErr == nil, "schema: %v", schemaErr)
+			assert.Equal(t, tt.valid, orderErr == nil, "ValidateOrderRequest: %v", orderErr)
+			if tt.valid {
+				return
+			}
+
+			assert.True(t, errors.Is(schemaErr, errs.ErrValidation))
+			schemaViolations, _ := AsValidationErrors(schemaErr)
+			orderViolations, _ := AsValidationErrors(orderErr)
+			assert.ElementsMatch(t, tt.paths, violationPaths(schemaViolations))
+			assert.ElementsMatch(t, tt.paths, violationPaths(orderViolations))
+			assert.Equal(t, violationCodes(orderViolations), violationCodes(schemaViolations))
+		})
+	}
+}
+
+func violationPaths(violations ValidationErrors) []string {
+	paths := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		paths = append(paths, violation.Path)
+	}
+	return paths
+}
+
+func violationCodes(violations ValidationErrors) map[string]string {
+	codes := make(map[string]string, len(violations))
+	for _, violation := range violations {
+		codes[violation.Path] = violation.Code
+	}
+	return codes
+}
+
+func TestValidateOrderRequest_AmountLimits(t *testing.T) {
+	usd := createValidOrderRequest()
+	usd.Payment.Amount = money.MaxMajorAmount * 100
+	assert.NoError(t, ValidateOrderRequest(usd))
+
+	jpy := createValidOrderRequest()
+	jpy.Payment.Currency = models.CurrencyJPY
+	jpy.Payment.Amount = money.MaxMajorAmount * 100
+	jpy.Items[0].Price = money.MaxMajorAmount + 1
+
+	violations, ok := AsValidationErrors(ValidateOrderRequest(jpy))
+	assert.True(t, ok)
+	assert.Equal(t, ValidationErrors{
+		{Path: "payment.amount", Code: CodeMax, Message: "payment.amount exceeds the maximum amount for JPY"},
+		{Path: "items[0].price", Code: CodeMax, Message: "items[0].price exceeds the maximum amount for JPY"},
+	}, violations)
+}
@@ -0,0 +1,2 @@
+ALTER TABLE "order"
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+CREATE TABLE
+    IF NOT EXISTS item_status_history (
+        id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+        order_id BIGINT NOT NULL,
+        chrt_id INTEGER NOT NULL,
+        previous_status INTEGER,
+        status INTEGER NOT NULL,
+        source_topic TEXT NOT NULL DEFAULT '',
+        source_partition INTEGER NOT NULL DEFAULT 0,
+        source_offset BIGINT NOT NULL DEFAULT 0,
+        changed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (order_id) REFERENCES "order" (id) ON UPDATE CASCADE ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS idx_item_status_history_order_id ON item_status_history (order_id, changed_at);
@@ -0,0 +1,2 @@
+ALTER TABLE item
+    DROP CONSTRAINT IF EXISTS uq_item_order_id_rid;
@@ -0,0 +1,3 @@
+-- Fails if an order holds the same rid twice; resolve such rows by hand first.
+ALTER TABLE item
+    ADD CONSTRAINT uq_item_order_id_rid UNIQUE (order_id, rid);
@@ -0,0 +1,2 @@
+ALTER TABLE "order"
+    ADD COLUMN IF NOT EXISTS validation_warnings JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_delivery_phone;
+
+ALTER TABLE delivery
+    DROP COLUMN IF EXISTS email_raw,
+    DROP COLUMN IF EXISTS region_raw,
+    DROP COLUMN IF EXISTS address_raw,
+    DROP COLUMN IF EXISTS city_raw,
+    DROP COLUMN IF EXISTS phone_raw,
+    DROP COLUMN IF EXISTS name_raw;
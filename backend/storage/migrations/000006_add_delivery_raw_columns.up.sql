@@ -0,0 +1,27 @@
+ALTER TABLE delivery
+    ADD COLUMN IF NOT EXISTS name_raw TEXT,
+    ADD COLUMN IF NOT EXISTS phone_raw TEXT,
+    ADD COLUMN IF NOT EXISTS city_raw TEXT,
+    ADD COLUMN IF NOT EXISTS address_raw TEXT,
+    ADD COLUMN IF NOT EXISTS region_raw TEXT,
+    ADD COLUMN IF NOT EXISTS email_raw TEXT;
+
+UPDATE delivery
+SET
+    name_raw = name,
+    phone_raw = phone,
+    city_raw = city,
+    address_raw = address,
+    region_raw = region,
+    email_raw = email
+WHERE phone_raw IS NULL;
+
+ALTER TABLE delivery
+    ALTER COLUMN name_raw SET NOT NULL,
+    ALTER COLUMN phone_raw SET NOT NULL,
+    ALTER COLUMN city_raw SET NOT NULL,
+    ALTER COLUMN address_raw SET NOT NULL,
+    ALTER COLUMN region_raw SET NOT NULL,
+    ALTER COLUMN email_raw SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_delivery_phone ON delivery (phone);
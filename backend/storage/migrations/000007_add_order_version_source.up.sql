@@ -0,0 +1,2 @@
+ALTER TABLE "order"
+    ADD COLUMN IF NOT EXISTS version_source SMALLINT NOT NULL DEFAULT 0;
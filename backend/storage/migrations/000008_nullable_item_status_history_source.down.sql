@@ -0,0 +1,13 @@
+UPDATE item_status_history
+SET
+    source_partition = COALESCE(source_partition, 0),
+    source_offset = COALESCE(source_offset, 0)
+WHERE
+    source_partition IS NULL
+    OR source_offset IS NULL;
+
+ALTER TABLE item_status_history
+    ALTER COLUMN source_partition SET DEFAULT 0,
+    ALTER COLUMN source_partition SET NOT NULL,
+    ALTER COLUMN source_offset SET DEFAULT 0,
+    ALTER COLUMN source_offset SET NOT NULL;
@@ -0,0 +1,13 @@
+ALTER TABLE item_status_history
+    ALTER COLUMN source_partition DROP NOT NULL,
+    ALTER COLUMN source_partition DROP DEFAULT,
+    ALTER COLUMN source_offset DROP NOT NULL,
+    ALTER COLUMN source_offset DROP DEFAULT;
+
+UPDATE item_status_history
+SET
+    source_partition = NULL,
+    source_offset = NULL
+WHERE
+    source_partition < 0
+    OR source_offset < 0;
@@ -0,0 +1,7 @@
+CREATE TABLE
+    IF NOT EXISTS order_tombstone (
+        order_uid TEXT PRIMARY KEY,
+        version BIGINT NOT NULL,
+        version_source SMALLINT NOT NULL,
+        deleted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
@@ -37,6 +37,9 @@ CREATE TABLE
         sm_id INTEGER NOT NULL,
         oof_shard TEXT NOT NULL,
         date_created TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        version BIGINT NOT NULL DEFAULT 0,
+        version_source SMALLINT NOT NULL DEFAULT 0,
+        validation_warnings JSONB NOT NULL DEFAULT '[]',
         updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
     );
 
@@ -51,6 +54,12 @@ CREATE TABLE
         address TEXT NOT NULL,
         region TEXT NOT NULL,
         email TEXT NOT NULL,
+        name_raw TEXT NOT NULL,
+        phone_raw TEXT NOT NULL,
+        city_raw TEXT NOT NULL,
+        address_raw TEXT NOT NULL,
+        region_raw TEXT NOT NULL,
+        email_raw TEXT NOT NULL,
         created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
         updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
         FOREIGN KEY (order_id) REFERENCES "order" (id) ON UPDATE CASCADE ON DELETE CASCADE
@@ -92,9 +101,32 @@ CREATE TABLE
         status INTEGER NOT NULL,
         created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
         updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        CONSTRAINT uq_item_order_id_rid UNIQUE (order_id, rid),
         FOREIGN KEY (order_id) REFERENCES "order" (id) ON UPDATE CASCADE ON DELETE CASCADE
     );
 
+CREATE TABLE
+    IF NOT EXISTS item_status_history (
+        id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+        order_id BIGINT NOT NULL,
+        chrt_id INTEGER NOT NULL,
+        previous_status INTEGER,
+        status INTEGER NOT NULL,
+        source_topic TEXT NOT NULL DEFAULT '',
+        source_partition INTEGER,
+        source_offset BIGINT,
+        changed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (order_id) REFERENCES "order" (id) ON UPDATE CASCADE ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    IF NOT EXISTS order_tombstone (
+        order_uid TEXT PRIMARY KEY,
+        version BIGINT NOT NULL,
+        version_source SMALLINT NOT NULL,
+        deleted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
 CREATE UNIQUE INDEX idx_order_order_uid ON "order" (order_uid);
 
 CREATE INDEX idx_order_track_number ON "order" (track_number);
@@ -109,6 +141,8 @@ CREATE INDEX idx_delivery_order_id ON delivery (order_id);
 
 CREATE INDEX idx_delivery_email ON delivery (email);
 
+CREATE INDEX idx_delivery_phone ON delivery (phone);
+
 CREATE INDEX idx_payment_order_id ON payment (order_id);
 
 CREATE INDEX idx_payment_transaction ON payment (transaction);
@@ -118,3 +152,5 @@ CREATE INDEX idx_item_order_id ON item (order_id);
 CREATE INDEX idx_item_chrt_id ON item (chrt_id);
 
 CREATE INDEX idx_item_nm_id ON item (nm_id);
+
+CREATE INDEX idx_item_status_history_order_id ON item_status_history (order_id, changed_at);